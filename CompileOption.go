@@ -0,0 +1,336 @@
+package govaluate
+
+import (
+	"errors"
+	"fmt"
+)
+
+/*
+	Configures how `Compile` parses an expression, and how the resulting expression evaluates.
+	Options are applied in the order given, so later options override earlier ones where they conflict.
+*/
+type Option func(*compileOptions)
+
+/*
+	Represents a user-defined implementation of an operator, given to `WithOperator`.
+	For prefix operators (such as negation), [left] is always nil.
+*/
+type OperatorFunction func(left interface{}, right interface{}) (interface{}, error)
+
+/*
+	Every setting that can be given to `Compile`.
+*/
+type compileOptions struct {
+
+	// every function that the lexer should recognize, including stand-ins for context functions.
+	functions        map[string]ExpressionFunction
+	contextFunctions map[string]ContextExpressionFunction
+
+	numericMode     NumericMode
+	decimalRounding *DecimalRounding
+	timeFormats     []string
+	operators       map[OperatorSymbol]OperatorFunction
+
+	limits          ParseLimits
+	budget          EvaluationBudget
+	checksTypes     bool
+	queryDateFormat string
+	diagnostics     bool
+	engine          EvaluationEngine
+	simplifies      bool
+
+	// the first invalid option given, if any.
+	err error
+}
+
+/*
+	Returns the default options, with each of the given [options] applied in order.
+*/
+func newCompileOptions(options []Option) *compileOptions {
+
+	ret := &compileOptions{
+		functions:       make(map[string]ExpressionFunction),
+		timeFormats:     defaultTimeFormats,
+		checksTypes:     true,
+		queryDateFormat: isoDateFormat,
+	}
+
+	for _, option := range options {
+		option(ret)
+	}
+	return ret
+}
+
+/*
+	Makes the given [functions] available to the expression.
+	May be given more than once, in which case every given function is available.
+*/
+func WithFunctions(functions map[string]ExpressionFunction) Option {
+
+	return func(options *compileOptions) {
+
+		for name, function := range functions {
+			options.functions[name] = function
+			delete(options.contextFunctions, name)
+		}
+	}
+}
+
+/*
+	Makes the given [functions] available to the expression. Each is passed the context that the expression is evaluated with; see `EvalContext`.
+	May be given more than once, in which case every given function is available.
+*/
+func WithContextFunctions(functions map[string]ContextExpressionFunction) Option {
+
+	return func(options *compileOptions) {
+
+		if options.contextFunctions == nil {
+			options.contextFunctions = make(map[string]ContextExpressionFunction, len(functions))
+		}
+
+		for name, function := range functions {
+			options.contextFunctions[name] = function
+			delete(options.functions, name)
+		}
+	}
+}
+
+/*
+	Represents numeric literals and parameters according to the given [mode]. See NumericMode.
+*/
+func WithNumericMode(mode NumericMode) Option {
+
+	return func(options *compileOptions) {
+
+		if mode.String() == "UNKNOWN" {
+			if options.err == nil {
+				options.err = errors.New(fmt.Sprintf("Unknown numeric mode %d", int(mode)))
+			}
+			return
+		}
+		options.numericMode = mode
+	}
+}
+
+/*
+	Uses DECIMAL_NUMERICS, and rounds the result of every division according to the given [rounding].
+*/
+func WithDecimalRounding(rounding DecimalRounding) Option {
+
+	return func(options *compileOptions) {
+		options.numericMode = DECIMAL_NUMERICS
+		options.decimalRounding = &rounding
+	}
+}
+
+/*
+	Sets the formats (as understood by `time.Parse`) which string literals are checked against.
+	Any string literal which matches one of them becomes a time instead of a string.
+	If no formats are given, string literals are never parsed as times.
+*/
+func WithTimeFormats(formats ...string) Option {
+
+	return func(options *compileOptions) {
+		options.timeFormats = append([]string{}, formats...)
+	}
+}
+
+/*
+	Rejects any expression which exceeds the given [limits]. See ParseLimits.
+*/
+func WithLimits(limits ParseLimits) Option {
+
+	return func(options *compileOptions) {
+		options.limits = limits
+	}
+}
+
+/*
+	Limits how much work each evaluation of the expression may do. See EvaluationBudget.
+*/
+func WithBudget(budget EvaluationBudget) Option {
+
+	return func(options *compileOptions) {
+		options.budget = budget
+	}
+}
+
+/*
+	Replaces the implementation of the operator with the given [symbol] with the given [operator].
+	The operator's usual type checks are not performed; [operator] is responsible for returning an error for any values it can't handle.
+	Since operators between literals are computed while parsing, [operator] should not have side effects.
+
+	Only comparators, modifiers, and prefix operators can be replaced. Regex comparators, logical, ternary, and coalescing operators,
+	and the separator, cannot.
+*/
+func WithOperator(symbol OperatorSymbol, operator OperatorFunction) Option {
+
+	return func(options *compileOptions) {
+
+		if !isReplaceableOperator(symbol) {
+			if options.err == nil {
+				options.err = errors.New(fmt.Sprintf("Operator '%v' cannot be replaced", symbol))
+			}
+			return
+		}
+
+		if options.operators == nil {
+			options.operators = make(map[OperatorSymbol]OperatorFunction)
+		}
+		options.operators[symbol] = operator
+	}
+}
+
+/*
+	Sets whether types are checked before each operator is evaluated. Defaults to true.
+	See `EvaluableExpression.ChecksTypes`.
+*/
+func WithTypeChecks(checksTypes bool) Option {
+
+	return func(options *compileOptions) {
+		options.checksTypes = checksTypes
+	}
+}
+
+/*
+	Sets the format used to output dates in queries. See `EvaluableExpression.QueryDateFormat`.
+*/
+func WithQueryDateFormat(format string) Option {
+
+	return func(options *compileOptions) {
+		options.queryDateFormat = format
+	}
+}
+
+/*
+	Evaluates the expression with the given [engine]. Defaults to STAGE_ENGINE. See EvaluationEngine.
+*/
+func WithEngine(engine EvaluationEngine) Option {
+
+	return func(options *compileOptions) {
+
+		if engine.String() == "UNKNOWN" {
+			if options.err == nil {
+				options.err = errors.New(fmt.Sprintf("Unknown evaluation engine %d", int(engine)))
+			}
+			return
+		}
+		options.engine = engine
+	}
+}
+
+/*
+	Simplifies the expression after parsing it, by applying algebraic and boolean identities. For instance,
+	"x && true" becomes "x", "x || true" becomes "true", "!!x" becomes "x", "!(a && b)" becomes "!a || !b", "x * 1" and "x - 0" become "x",
+	"false ? x : y" becomes "y", and a term which appears more than once in a chain of "&&" or "||" is only kept the first time.
+	Since strings can be added to numbers, "x + 0" only becomes "x" if x is sure to be a number. Likewise, "true ? x : y" gives y when x is nil,
+	so it becomes "x ?? y", or just "x" if x can't be nil.
+	The simplified expression is what `Tokens()` and `Format()` give, and what is evaluated.
+
+	Simplification assumes that evaluating the expression succeeds. With any parameters for which the original expression gives a result,
+	the simplified one gives the same result, but it may give a result where the original would have failed
+	(for instance, "x && true" gives x even if x isn't a bool, and "x || true" is true even if x is missing).
+	Identities are not applied to operators replaced with `WithOperator`, and terms which call functions or methods are never removed as duplicates.
+*/
+func WithSimplification() Option {
+
+	return func(options *compileOptions) {
+		options.simplifies = true
+	}
+}
+
+/*
+	Keeps parsing after the first problem found, so that every problem in the expression is reported at once.
+	If there are any, the error returned by `Compile` is a ParseErrors. See `NewEvaluableExpressionWithDiagnostics`.
+*/
+func WithDiagnostics() Option {
+
+	return func(options *compileOptions) {
+		options.diagnostics = true
+	}
+}
+
+/*
+	Returns true if the operator with the given [symbol] can be replaced with `WithOperator`.
+*/
+func isReplaceableOperator(symbol OperatorSymbol) bool {
+
+	switch symbol {
+	case EQ:
+		fallthrough
+	case NEQ:
+		fallthrough
+	case GT:
+		fallthrough
+	case LT:
+		fallthrough
+	case GTE:
+		fallthrough
+	case LTE:
+		fallthrough
+	case IN:
+		fallthrough
+	case PLUS:
+		fallthrough
+	case MINUS:
+		fallthrough
+	case BITWISE_AND:
+		fallthrough
+	case BITWISE_OR:
+		fallthrough
+	case BITWISE_XOR:
+		fallthrough
+	case BITWISE_LSHIFT:
+		fallthrough
+	case BITWISE_RSHIFT:
+		fallthrough
+	case MULTIPLY:
+		fallthrough
+	case DIVIDE:
+		fallthrough
+	case MODULUS:
+		fallthrough
+	case EXPONENT:
+		fallthrough
+	case NEGATE:
+		fallthrough
+	case INVERT:
+		fallthrough
+	case BITWISE_NOT:
+		return true
+	}
+	return false
+}
+
+/*
+	Replaces the operators of every stage in the tree under [root] which has a user-defined replacement in [operators].
+*/
+func applyCustomOperators(root *evaluationStage, operators map[OperatorSymbol]OperatorFunction) {
+
+	var operator OperatorFunction
+	var found bool
+
+	if root == nil || len(operators) == 0 {
+		return
+	}
+
+	applyCustomOperators(root.leftStage, operators)
+	applyCustomOperators(root.rightStage, operators)
+
+	operator, found = operators[root.symbol]
+	if !found {
+		return
+	}
+
+	root.operator = makeCustomOperatorStage(operator)
+	root.leftTypeCheck = nil
+	root.rightTypeCheck = nil
+	root.typeCheck = nil
+}
+
+func makeCustomOperatorStage(operator OperatorFunction) evaluationOperator {
+
+	return func(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
+		return operator(left, right)
+	}
+}
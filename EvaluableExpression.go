@@ -1,6 +1,7 @@
 package govaluate
 
 import (
+	"context"
 	"errors"
 	"fmt"
 )
@@ -32,9 +33,21 @@ type EvaluableExpression struct {
 	*/
 	ChecksTypes bool
 
+	/*
+		Limits on how much work each evaluation of this expression may do.
+		If any limit is exceeded, evaluation stops and returns an ErrBudgetExceeded.
+		Defaults to no limits.
+	*/
+	Budget EvaluationBudget
+
 	tokens           []ExpressionToken
 	evaluationStages *evaluationStage
+	closure          stageClosure
+	program          *bytecodeProgram
 	inputExpression  string
+	numericMode      NumericMode
+	decimalRounding  *DecimalRounding
+	operators        map[OperatorSymbol]OperatorFunction
 }
 
 /*
@@ -42,9 +55,7 @@ type EvaluableExpression struct {
 	Returns an error if the given expression has invalid syntax.
 */
 func NewEvaluableExpression(expression string) (*EvaluableExpression, error) {
-
-	functions := make(map[string]ExpressionFunction)
-	return NewEvaluableExpressionWithFunctions(expression, functions)
+	return Compile(expression)
 }
 
 /*
@@ -54,10 +65,11 @@ func NewEvaluableExpression(expression string) (*EvaluableExpression, error) {
 func NewEvaluableExpressionFromTokens(tokens []ExpressionToken) (*EvaluableExpression, error) {
 
 	var ret *EvaluableExpression
+	var options *compileOptions
 	var err error
 
-	ret = new(EvaluableExpression)
-	ret.QueryDateFormat = isoDateFormat
+	options = newCompileOptions(nil)
+	ret = newEvaluableExpression("", options)
 
 	err = checkBalance(tokens)
 	if err != nil {
@@ -74,12 +86,11 @@ func NewEvaluableExpressionFromTokens(tokens []ExpressionToken) (*EvaluableExpre
 		return nil, err
 	}
 
-	ret.evaluationStages, err = planStages(ret.tokens)
+	ret.evaluationStages, err = planStages(ret.tokens, options)
 	if err != nil {
 		return nil, err
 	}
 
-	ret.ChecksTypes = true
 	return ret, nil
 }
 
@@ -88,40 +99,273 @@ func NewEvaluableExpressionFromTokens(tokens []ExpressionToken) (*EvaluableExpre
 	Functions passed into this will be available to the expression.
 */
 func NewEvaluableExpressionWithFunctions(expression string, functions map[string]ExpressionFunction) (*EvaluableExpression, error) {
+	return Compile(expression, WithFunctions(functions))
+}
+
+/*
+	Similar to [NewEvaluableExpressionWithFunctions], except that numeric literals and parameters are represented according to the given [mode],
+	instead of always being float64.
+
+	Deprecated: use `Compile(expression, WithFunctions(functions), WithNumericMode(mode))` instead.
+*/
+func NewEvaluableExpressionWithNumericMode(expression string, functions map[string]ExpressionFunction, mode NumericMode) (*EvaluableExpression, error) {
+	return Compile(expression, WithFunctions(functions), WithNumericMode(mode))
+}
+
+/*
+	Similar to [NewEvaluableExpressionWithNumericMode] using DECIMAL_NUMERICS,
+	except that the result of every division is rounded according to the given [rounding].
+
+	Deprecated: use `Compile(expression, WithFunctions(functions), WithDecimalRounding(rounding))` instead.
+*/
+func NewEvaluableExpressionWithDecimalRounding(expression string, functions map[string]ExpressionFunction, rounding DecimalRounding) (*EvaluableExpression, error) {
+	return Compile(expression, WithFunctions(functions), WithDecimalRounding(rounding))
+}
+
+/*
+	Similar to [NewEvaluableExpressionWithFunctions], except that the given [functions] are also passed the context that the expression is evaluated with.
+	See `EvalContext`.
+
+	Deprecated: use `Compile(expression, WithContextFunctions(functions))` instead.
+*/
+func NewEvaluableExpressionWithContextFunctions(expression string, functions map[string]ContextExpressionFunction) (*EvaluableExpression, error) {
+	return Compile(expression, WithContextFunctions(functions))
+}
+
+/*
+	Similar to [NewEvaluableExpressionWithFunctions], except that the given [limits] are enforced on the size and complexity of the expression.
+	If the expression exceeds any of them, it is rejected with an error.
+
+	Deprecated: use `Compile(expression, WithFunctions(functions), WithLimits(limits))` instead.
+*/
+func NewEvaluableExpressionWithLimits(expression string, functions map[string]ExpressionFunction, limits ParseLimits) (*EvaluableExpression, error) {
+	return Compile(expression, WithFunctions(functions), WithLimits(limits))
+}
+
+/*
+	Similar to [NewEvaluableExpressionWithFunctions], except that parsing does not stop at the first problem found.
+	Instead, each invalid token is skipped (up to the next separator, logical operator, or parenthesis) and checking continues,
+	so that every problem in the expression can be reported at once.
+
+	If any problems are found, the returned expression is nil, and the returned error is a ParseErrors
+	holding every problem, in the order they appear in the expression.
+*/
+func NewEvaluableExpressionWithDiagnostics(expression string, functions map[string]ExpressionFunction) (*EvaluableExpression, error) {
+	return Compile(expression, WithFunctions(functions), WithDiagnostics())
+}
+
+/*
+	Parses a new EvaluableExpression from the given [expression] string, configured by the given [options].
+	Returns an error if the given expression has invalid syntax, or if any of the options are invalid.
+
+	With no options, this is the same as [NewEvaluableExpression]. Each of the other constructors is the same as this with some options given;
+	for instance, `NewEvaluableExpressionWithFunctions(expression, functions)` is the same as `Compile(expression, WithFunctions(functions))`.
+*/
+func Compile(expression string, options ...Option) (*EvaluableExpression, error) {
+
+	var compiled *compileOptions
+	var ret *EvaluableExpression
+	var errs ParseErrors
+
+	compiled = newCompileOptions(options)
+	if compiled.err != nil {
+		return nil, compiled.err
+	}
+
+	if !compiled.diagnostics {
+		return compile(expression, compiled)
+	}
+
+	// a nil ParseErrors must not be returned as a non-nil error.
+	ret, errs = compileWithDiagnostics(expression, compiled)
+	if errs != nil {
+		return nil, errs
+	}
+	return ret, nil
+}
+
+/*
+	Creates a new EvaluableExpression from the syntax tree under the given [root], configured by the given [options].
+	The tree may come from `AST()`, be built with the functions in NodeBuilder.go (such as `And(Gt(Var("x"), Num(3)), ...)`), or be built by hand.
+	Numeric literals are converted according to the numeric mode in effect, and functions are called through the implementations on each FunctionNode.
+
+	Returns an error if the tree can't be represented as an expression, such as when an operand is missing,
+	or if the resulting expression is rejected in the same way a parsed one would be.
+*/
+func CompileAST(root Node, options ...Option) (*EvaluableExpression, error) {
+
+	var compiled *compileOptions
+
+	compiled = newCompileOptions(options)
+	if compiled.err != nil {
+		return nil, compiled.err
+	}
+	return compileNode(root, compiled)
+}
+
+func compileNode(root Node, options *compileOptions) (*EvaluableExpression, error) {
 
 	var ret *EvaluableExpression
 	var err error
 
-	ret = new(EvaluableExpression)
-	ret.QueryDateFormat = isoDateFormat
-	ret.inputExpression = expression
+	ret = newEvaluableExpression("", options)
 
-	ret.tokens, err = parseTokens(expression, functions)
+	ret.tokens, err = nodeTokens(root, options.numericMode)
 	if err != nil {
 		return nil, err
 	}
 
-	err = checkBalance(ret.tokens)
+	err = compileTokens(ret, options)
 	if err != nil {
 		return nil, err
 	}
+	return ret, nil
+}
+
+/*
+	Creates an expression for the given [expression] string, with every setting from [options] which doesn't require parsing.
+*/
+func newEvaluableExpression(expression string, options *compileOptions) *EvaluableExpression {
+
+	var ret *EvaluableExpression
 
-	err = checkExpressionSyntax(ret.tokens)
+	ret = new(EvaluableExpression)
+	ret.QueryDateFormat = options.queryDateFormat
+	ret.ChecksTypes = options.checksTypes
+	ret.Budget = options.budget
+	ret.inputExpression = expression
+	ret.numericMode = options.numericMode
+	ret.decimalRounding = options.decimalRounding
+	ret.operators = options.operators
+	return ret
+}
+
+func compile(expression string, options *compileOptions) (*EvaluableExpression, error) {
+
+	var ret *EvaluableExpression
+	var err error
+
+	err = options.limits.checkLength(expression)
 	if err != nil {
 		return nil, err
 	}
 
-	ret.tokens, err = optimizeTokens(ret.tokens)
+	ret = newEvaluableExpression(expression, options)
+
+	ret.tokens, err = parseTokens(expression, options)
 	if err != nil {
 		return nil, err
 	}
-
-	ret.evaluationStages, err = planStages(ret.tokens)
+	err = compileTokens(ret, options)
 	if err != nil {
 		return nil, err
 	}
+	return ret, nil
+}
+
+/*
+	Checks the tokens of the given [expression], then plans its stages.
+*/
+func compileTokens(expression *EvaluableExpression, options *compileOptions) error {
+
+	var err error
+
+	err = options.limits.checkTokens(expression.tokens)
+	if err != nil {
+		return err
+	}
+
+	err = checkBalance(expression.tokens)
+	if err != nil {
+		return err
+	}
+
+	err = checkExpressionSyntax(expression.tokens)
+	if err != nil {
+		return err
+	}
+
+	expression.tokens, err = optimizeTokens(expression.tokens)
+	if err != nil {
+		return err
+	}
+
+	expression.evaluationStages, err = planStages(expression.tokens, options)
+	if err != nil {
+		return err
+	}
+
+	if options.simplifies {
+		err = simplifyExpression(expression, options)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = options.limits.checkStageDepth(expression.evaluationStages)
+	if err != nil {
+		return err
+	}
+
+	switch options.engine {
+	case CLOSURE_ENGINE:
+		expression.closure = compileStageClosure(expression.evaluationStages, options)
+	case BYTECODE_ENGINE:
+		expression.program = compileBytecode(expression.evaluationStages, options)
+	}
+	return nil
+}
+
+/*
+	The same as `compile`, except that parsing does not stop at the first problem found. See [NewEvaluableExpressionWithDiagnostics].
+*/
+func compileWithDiagnostics(expression string, options *compileOptions) (*EvaluableExpression, ParseErrors) {
+
+	var ret *EvaluableExpression
+	var errs, found ParseErrors
+	var err error
+
+	err = options.limits.checkLength(expression)
+	if err != nil {
+		return nil, ParseErrors{asParseError(err)}
+	}
+
+	ret = newEvaluableExpression(expression, options)
+
+	ret.tokens, errs = lexTokens(expression, options, true)
+	err = options.limits.checkTokens(ret.tokens)
+	if err != nil {
+		errs = append(errs, asParseError(err))
+	}
+
+	errs = append(errs, findUnbalancedTokens(ret.tokens)...)
+	errs = append(errs, findSyntaxErrors(ret.tokens, true)...)
+
+	ret.tokens, found = compileConstantPatterns(ret.tokens, true)
+	errs = append(errs, found...)
+
+	if len(errs) > 0 {
+		errs.sort()
+		return nil, errs
+	}
+
+	ret.evaluationStages, err = planStages(ret.tokens, options)
+	if err != nil {
+		return nil, ParseErrors{asParseError(err)}
+	}
+
+	if options.simplifies {
+		err = simplifyExpression(ret, options)
+		if err != nil {
+			return nil, ParseErrors{asParseError(err)}
+		}
+	}
+
+	err = options.limits.checkStageDepth(ret.evaluationStages)
+	if err != nil {
+		return nil, ParseErrors{asParseError(err)}
+	}
 
-	ret.ChecksTypes = true
 	return ret, nil
 }
 
@@ -149,25 +393,68 @@ func (this EvaluableExpression) Evaluate(parameters map[string]interface{}) (int
 	e.g., if the expression is "foo + 1" and parameters contains "foo" = 2, this will return 3.0
 */
 func (this EvaluableExpression) Eval(parameters Parameters) (interface{}, error) {
+	return this.EvalContext(context.Background(), parameters)
+}
+
+/*
+	Same as `Eval`, but stops evaluating (and returns the context's error) as soon as the given [ctx] is cancelled or its deadline passes.
+	Cancellation is checked before each stage of the expression is evaluated,
+	and [ctx] is passed to any ContextExpressionFunction that the expression calls.
+*/
+func (this EvaluableExpression) EvalContext(ctx context.Context, parameters Parameters) (interface{}, error) {
+
+	var sanitized *sanitizedParameters
 
 	if this.evaluationStages == nil {
 		return nil, nil
 	}
 
-	if parameters != nil {
-		parameters = &sanitizedParameters{parameters}
-	} else {
+	if parameters == nil {
 		parameters = DUMMY_PARAMETERS
 	}
 
-	return this.evaluateStage(this.evaluationStages, parameters)
+	// programs keep their own parameters, so that they can be reused between evaluations.
+	if this.program != nil {
+		return copyDecimalResult(this.program.run(ctx, parameters, this.ChecksTypes, this.Budget))
+	}
+
+	sanitized = &sanitizedParameters{
+		orig:        parameters,
+		numericMode: this.numericMode,
+		ctx:         ctx,
+		done:        ctx.Done(),
+		checksTypes: this.ChecksTypes,
+	}
+
+	// the budget is copied, rather than pointing into this expression, so that the expression needn't escape to the heap.
+	if this.Budget.isLimited() {
+		budget := this.Budget
+		sanitized.budget = &budget
+	}
+
+	if this.closure != nil {
+		return copyDecimalResult(this.closure(sanitized))
+	}
+	return copyDecimalResult(this.evaluateStage(this.evaluationStages, sanitized))
 }
 
-func (this EvaluableExpression) evaluateStage(stage *evaluationStage, parameters Parameters) (interface{}, error) {
+func (this EvaluableExpression) evaluateStage(stage *evaluationStage, parameters *sanitizedParameters) (interface{}, error) {
 
-	var left, right interface{}
+	var left, right, result interface{}
 	var err error
 
+	err = parameters.cancelled()
+	if err != nil {
+		return nil, err
+	}
+
+	if parameters.budget != nil {
+		err = parameters.budget.spend(stage, &parameters.usage)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	if stage.leftStage != nil {
 		left, err = this.evaluateStage(stage.leftStage, parameters)
 		if err != nil {
@@ -229,7 +516,16 @@ func (this EvaluableExpression) evaluateStage(stage *evaluationStage, parameters
 		}
 	}
 
-	return stage.operator(left, right, parameters)
+	result, err = stage.operator(left, right, parameters)
+	if err != nil || parameters.budget == nil {
+		return result, err
+	}
+
+	err = parameters.budget.checkResult(stage.symbol, result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
 }
 
 func typeCheck(check stageTypeCheck, value interface{}, symbol OperatorSymbol, format string) error {
@@ -256,10 +552,22 @@ func (this EvaluableExpression) Tokens() []ExpressionToken {
 
 /*
 	Returns the original expression used to create this EvaluableExpression.
+	If it wasn't created from a string (such as with `CompileAST`), returns the expression as given by `Format()` instead.
 */
 func (this EvaluableExpression) String() string {
 
-	return this.inputExpression
+	var ret string
+	var err error
+
+	if this.inputExpression != "" || this.evaluationStages == nil {
+		return this.inputExpression
+	}
+
+	ret, err = this.Format()
+	if err != nil {
+		return ""
+	}
+	return ret
 }
 
 /*
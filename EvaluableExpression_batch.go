@@ -0,0 +1,209 @@
+package govaluate
+
+import (
+	"context"
+	"sync"
+)
+
+/*
+	The number of parameters which `EvalIterator` takes from its iterator before evaluating them.
+*/
+const iteratorChunkSize = 1024
+
+/*
+	Gives a sequence of Parameters, one at a time, to `EvalIterator`.
+*/
+type ParametersIterator interface {
+
+	/*
+		Returns the next parameters in the sequence and true, or false if there are none left.
+	*/
+	Next() (Parameters, bool)
+}
+
+/*
+	The state which one goroutine reuses while evaluating part of a batch.
+*/
+type batchWorker struct {
+	expression *EvaluableExpression
+	parameters sanitizedParameters
+	budget     EvaluationBudget
+	machine    *bytecodeMachine
+}
+
+/*
+	Evaluates this expression once for each of the given [batch] of parameters.
+	Returns the result of each evaluation, and the error (or nil) of each evaluation, in the same order as [batch].
+	An error in one evaluation doesn't stop the others.
+
+	The state needed to evaluate is made once per batch rather than once per evaluation, so this is faster than calling `Eval` in a loop.
+	If [workers] is more than one, the batch is split between that many goroutines, which evaluate their parts at the same time.
+	Otherwise, every evaluation happens on the calling goroutine.
+*/
+func (this EvaluableExpression) EvalBatch(batch []Parameters, workers int) ([]interface{}, []error) {
+	return this.EvalBatchContext(context.Background(), batch, workers)
+}
+
+/*
+	Same as `EvalBatch`, except that every evaluation uses the given [ctx], as with `EvalContext`.
+	Once [ctx] is cancelled, every evaluation which hasn't finished returns the context's error.
+*/
+func (this EvaluableExpression) EvalBatchContext(ctx context.Context, batch []Parameters, workers int) ([]interface{}, []error) {
+
+	var results []interface{}
+	var errs []error
+	var waitGroup sync.WaitGroup
+	var size int
+
+	results = make([]interface{}, len(batch))
+	errs = make([]error, len(batch))
+
+	if this.evaluationStages == nil || len(batch) == 0 {
+		return results, errs
+	}
+
+	if workers <= 1 {
+		this.evaluateBatch(ctx, batch, results, errs)
+		return results, errs
+	}
+
+	// each worker evaluates one contiguous part of the batch, so that none of them need to share anything.
+	size = (len(batch) + workers - 1) / workers
+
+	for start := 0; start < len(batch); start += size {
+
+		end := start + size
+		if end > len(batch) {
+			end = len(batch)
+		}
+
+		waitGroup.Add(1)
+		go func(start int, end int) {
+			defer waitGroup.Done()
+			this.evaluateBatch(ctx, batch[start:end], results[start:end], errs[start:end])
+		}(start, end)
+	}
+
+	waitGroup.Wait()
+	return results, errs
+}
+
+/*
+	Same as `EvalBatch`, except that the parameters are taken from the given [iterator] until it has none left.
+	Each result and error is in the same order that the iterator gave its parameters.
+
+	Parameters are taken and evaluated `iteratorChunkSize` at a time, so at most that many of them are held at once,
+	however many the iterator gives.
+*/
+func (this EvaluableExpression) EvalIterator(iterator ParametersIterator, workers int) ([]interface{}, []error) {
+	return this.EvalIteratorContext(context.Background(), iterator, workers)
+}
+
+/*
+	Same as `EvalIterator`, except that every evaluation uses the given [ctx], as with `EvalBatchContext`.
+	Once [ctx] is done, no more parameters are taken from the iterator,
+	so there are only results (and errors) for the parameters which had already been taken.
+*/
+func (this EvaluableExpression) EvalIteratorContext(ctx context.Context, iterator ParametersIterator, workers int) ([]interface{}, []error) {
+
+	var chunk []Parameters
+	var parameters Parameters
+	var results, chunkResults []interface{}
+	var errs, chunkErrs []error
+	var found bool
+
+	chunk = make([]Parameters, 0, iteratorChunkSize)
+	results = []interface{}{}
+	errs = []error{}
+
+	for {
+
+		if ctx.Err() != nil {
+			return results, errs
+		}
+
+		chunk = chunk[:0]
+		for len(chunk) < iteratorChunkSize {
+
+			parameters, found = iterator.Next()
+			if !found {
+				break
+			}
+			chunk = append(chunk, parameters)
+		}
+
+		chunkResults, chunkErrs = this.EvalBatchContext(ctx, chunk, workers)
+		results = append(results, chunkResults...)
+		errs = append(errs, chunkErrs...)
+
+		if !found {
+			return results, errs
+		}
+	}
+}
+
+/*
+	Evaluates this expression with each of the given [batch] of parameters on the calling goroutine,
+	storing each outcome at the same index of [results] and [errs].
+*/
+func (this *EvaluableExpression) evaluateBatch(ctx context.Context, batch []Parameters, results []interface{}, errs []error) {
+
+	var worker *batchWorker
+
+	worker = this.newBatchWorker(ctx)
+
+	for i, parameters := range batch {
+		results[i], errs[i] = worker.evaluate(parameters)
+	}
+
+	if worker.machine != nil {
+		this.program.release(worker.machine)
+	}
+}
+
+func (this *EvaluableExpression) newBatchWorker(ctx context.Context) *batchWorker {
+
+	var ret *batchWorker
+
+	ret = &batchWorker{
+		expression: this,
+		parameters: sanitizedParameters{
+			numericMode: this.numericMode,
+			ctx:         ctx,
+			done:        ctx.Done(),
+			checksTypes: this.ChecksTypes,
+		},
+	}
+
+	if this.Budget.isLimited() {
+		ret.budget = this.Budget
+		ret.parameters.budget = &ret.budget
+	}
+
+	if this.program != nil {
+		ret.machine = this.program.acquire()
+	}
+	return ret
+}
+
+/*
+	Evaluates this worker's expression with the given [parameters], in the same way as `EvalContext`.
+*/
+func (this *batchWorker) evaluate(parameters Parameters) (interface{}, error) {
+
+	if parameters == nil {
+		parameters = DUMMY_PARAMETERS
+	}
+
+	// each evaluation has a budget of its own.
+	this.parameters.orig = parameters
+	this.parameters.usage = budgetUsage{}
+
+	if this.machine != nil {
+		return copyDecimalResult(this.machine.execute(this.expression.program.instructions, &this.parameters))
+	}
+	if this.expression.closure != nil {
+		return copyDecimalResult(this.expression.closure(&this.parameters))
+	}
+	return copyDecimalResult(this.expression.evaluateStage(this.expression.evaluationStages, &this.parameters))
+}
@@ -0,0 +1,830 @@
+package govaluate
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+/*
+	The outcome of evaluating an expression with `EvalColumns`.
+*/
+type ColumnResult struct {
+
+	/*
+		The result of each row. This is a []float64, []string, or []bool if every row which succeeded gave that type,
+		or []interface{} otherwise. Rows which failed hold the zero value.
+	*/
+	Values interface{}
+
+	/*
+		The error of each row which failed, or nil for each row which succeeded.
+		Nil if every row succeeded.
+	*/
+	Errors []error
+}
+
+/*
+	Which of a columnVector's slices holds its values.
+*/
+type columnKind int
+
+const (
+	floatColumn columnKind = iota
+	stringColumn
+	boolColumn
+	valueColumn
+)
+
+/*
+	The value of a stage for every row, kept in a typed slice where possible so that operators can work on whole columns at once.
+*/
+type columnVector struct {
+	kind    columnKind
+	floats  []float64
+	strings []string
+	bools   []bool
+	values  []interface{}
+}
+
+/*
+	The state of a single call to `EvalColumns`.
+*/
+type columnEvaluator struct {
+	options *compileOptions
+	rows    int
+
+	// the first error of each row, once a row has failed. Failed rows aren't evaluated any further.
+	errs   []error
+	failed bool
+
+	// parameters for operators which need them (such as accessors), which are moved to each row in turn.
+	row        columnParameters
+	parameters sanitizedParameters
+}
+
+/*
+	Parameters which are the values of one row of a set of columns.
+*/
+type columnParameters struct {
+	columns map[string]interface{}
+	row     int
+}
+
+/*
+	Evaluates this expression once for every row of the given [columns], where each column is the values of one parameter,
+	given as a []float64, []string, []bool, or []interface{} (for columns with mixed types, or nils). Every column must have the same length.
+
+	Under float numerics, each stage of the expression is evaluated for every row at once, rather than evaluating every stage once per row,
+	so operators between numbers, strings and bools work directly on columns without boxing each value.
+	Other operators (such as function calls and accessors) are still evaluated for one row at a time, and only for rows
+	which haven't failed or been short-circuited, so each row gives the same result and error as evaluating it alone.
+	However, functions are called for every row before the next stage is evaluated for any row.
+
+	If the expression has a Budget, or uses any other numeric mode, each row is evaluated on its own instead, as with `EvalBatch`.
+
+	Returns an error only if the columns can't be evaluated at all, such as when they have different lengths.
+*/
+func (this EvaluableExpression) EvalColumns(columns map[string]interface{}) (ColumnResult, error) {
+
+	var evaluator *columnEvaluator
+	var vector columnVector
+	var rows int
+	var err error
+
+	rows, err = countColumnRows(columns)
+	if err != nil {
+		return ColumnResult{}, err
+	}
+
+	if this.evaluationStages == nil {
+		return ColumnResult{Values: make([]interface{}, rows)}, nil
+	}
+
+	if this.numericMode != FLOAT_NUMERICS || this.Budget.isLimited() {
+		return this.evaluateColumnRows(columns, rows), nil
+	}
+
+	evaluator = &columnEvaluator{
+		options: &compileOptions{numericMode: this.numericMode, operators: this.operators},
+		rows:    rows,
+		errs:    make([]error, rows),
+		row:     columnParameters{columns: columns},
+	}
+
+	evaluator.parameters = sanitizedParameters{
+		orig:        &evaluator.row,
+		numericMode: this.numericMode,
+		checksTypes: this.ChecksTypes,
+	}
+
+	vector = evaluator.evaluate(this.evaluationStages, nil)
+	return evaluator.finish(vector), nil
+}
+
+/*
+	Returns the number of rows in the given [columns], or an error if they aren't all valid columns of the same length.
+*/
+func countColumnRows(columns map[string]interface{}) (int, error) {
+
+	var names []string
+	var rows, length int
+
+	for name := range columns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for i, name := range names {
+
+		switch columns[name].(type) {
+		case []float64:
+			length = len(columns[name].([]float64))
+		case []string:
+			length = len(columns[name].([]string))
+		case []bool:
+			length = len(columns[name].([]bool))
+		case []interface{}:
+			length = len(columns[name].([]interface{}))
+		default:
+			return 0, errors.New(fmt.Sprintf("Column '%s' is a %T; columns must be []float64, []string, []bool, or []interface{}", name, columns[name]))
+		}
+
+		if i == 0 {
+			rows = length
+			continue
+		}
+
+		if length != rows {
+			return 0, errors.New(fmt.Sprintf("Column '%s' has %d rows, but column '%s' has %d", name, length, names[0], rows))
+		}
+	}
+	return rows, nil
+}
+
+/*
+	Evaluates this expression for each row of the given [columns] on its own, in the same way as `EvalBatch`.
+*/
+func (this *EvaluableExpression) evaluateColumnRows(columns map[string]interface{}, rows int) ColumnResult {
+
+	var worker *batchWorker
+	var row *columnParameters
+	var values []interface{}
+	var errs []error
+	var failed bool
+
+	worker = this.newBatchWorker(contextOf(nil))
+	row = &columnParameters{columns: columns}
+	values = make([]interface{}, rows)
+	errs = make([]error, rows)
+
+	for i := 0; i < rows; i++ {
+
+		row.row = i
+		values[i], errs[i] = worker.evaluate(row)
+		failed = failed || errs[i] != nil
+	}
+
+	if worker.machine != nil {
+		this.program.release(worker.machine)
+	}
+
+	if !failed {
+		errs = nil
+	}
+	return ColumnResult{Values: packColumn(values, errs), Errors: errs}
+}
+
+func (this *columnParameters) Get(name string) (interface{}, error) {
+
+	var column interface{}
+	var found bool
+
+	column, found = this.columns[name]
+	if !found {
+		return nil, errors.New("No parameter '" + name + "' found.")
+	}
+
+	switch column.(type) {
+	case []float64:
+		return column.([]float64)[this.row], nil
+	case []string:
+		return column.([]string)[this.row], nil
+	case []bool:
+		return column.([]bool)[this.row], nil
+	}
+	return column.([]interface{})[this.row], nil
+}
+
+/*
+	Returns the value of the given [vector] at the given [row], as an interface{}.
+*/
+func (this *columnVector) at(row int) interface{} {
+
+	switch this.kind {
+	case floatColumn:
+		return this.floats[row]
+	case stringColumn:
+		return this.strings[row]
+	case boolColumn:
+		return boolIface(this.bools[row])
+	}
+	return this.values[row]
+}
+
+func (this *columnVector) isFalseAt(row int) bool {
+
+	switch this.kind {
+	case boolColumn:
+		return !this.bools[row]
+	case valueColumn:
+		return this.values[row] == false
+	}
+	return false
+}
+
+func (this *columnVector) isTrueAt(row int) bool {
+
+	switch this.kind {
+	case boolColumn:
+		return this.bools[row]
+	case valueColumn:
+		return this.values[row] == true
+	}
+	return false
+}
+
+func (this *columnVector) isNilAt(row int) bool {
+	return this.kind == valueColumn && this.values[row] == nil
+}
+
+/*
+	Returns true if the given [row] is still being evaluated; that is, it's [active], and hasn't failed.
+	A nil [active] means that every row is active.
+*/
+func (this *columnEvaluator) isLive(active []bool, row int) bool {
+	return (active == nil || active[row]) && this.errs[row] == nil
+}
+
+func (this *columnEvaluator) fail(row int, err error) {
+
+	this.errs[row] = err
+	this.failed = true
+}
+
+/*
+	Returns the value of [stage] for every row which is [active].
+	The values of other rows are unspecified, and must not be used.
+*/
+func (this *columnEvaluator) evaluate(stage *evaluationStage, active []bool) columnVector {
+
+	var left, right columnVector
+	var rightActive []bool
+	var ret columnVector
+	var ok bool
+
+	switch stage.symbol {
+
+	case LITERAL:
+
+		value, err := stage.operator(nil, nil, nil)
+		if err == nil {
+			return this.broadcast(value)
+		}
+
+	case VALUE:
+		if stage.name != "" {
+			return this.load(stage.name, active)
+		}
+
+	case NOOP:
+		if stage.rightStage != nil {
+			return this.evaluate(stage.rightStage, active)
+		}
+	}
+
+	if stage.leftStage != nil {
+		left = this.evaluate(stage.leftStage, active)
+	}
+
+	rightActive = active
+	if stage.isShortCircuitable() {
+		rightActive = this.findUnshortedRows(stage, &left, active)
+	}
+
+	if stage.rightStage != nil {
+		right = this.evaluate(stage.rightStage, rightActive)
+	}
+
+	if _, found := this.options.operators[stage.symbol]; !found {
+
+		ret, ok = this.applyVectors(stage, &left, &right)
+		if ok {
+			return ret
+		}
+	}
+	return this.applyRows(stage, &left, &right, active)
+}
+
+/*
+	Returns which of the [active] rows need the right side of the short-circuiting [stage], given the [left] side.
+*/
+func (this *columnEvaluator) findUnshortedRows(stage *evaluationStage, left *columnVector, active []bool) []bool {
+
+	var ret []bool
+
+	ret = make([]bool, this.rows)
+
+	for i := range ret {
+
+		if !this.isLive(active, i) {
+			continue
+		}
+
+		switch stage.symbol {
+		case AND:
+			fallthrough
+		case TERNARY_TRUE:
+			ret[i] = !left.isFalseAt(i)
+		case OR:
+			ret[i] = !left.isTrueAt(i)
+		case COALESCE:
+			fallthrough
+		case TERNARY_FALSE:
+			ret[i] = left.isNilAt(i)
+		}
+	}
+	return ret
+}
+
+/*
+	Returns a column which holds the given [value] in every row.
+*/
+func (this *columnEvaluator) broadcast(value interface{}) columnVector {
+
+	var ret columnVector
+
+	switch value.(type) {
+
+	case float64:
+		ret = columnVector{kind: floatColumn, floats: make([]float64, this.rows)}
+		for i := range ret.floats {
+			ret.floats[i] = value.(float64)
+		}
+
+	case string:
+		ret = columnVector{kind: stringColumn, strings: make([]string, this.rows)}
+		for i := range ret.strings {
+			ret.strings[i] = value.(string)
+		}
+
+	case bool:
+		ret = columnVector{kind: boolColumn, bools: make([]bool, this.rows)}
+		for i := range ret.bools {
+			ret.bools[i] = value.(bool)
+		}
+
+	default:
+		ret = columnVector{kind: valueColumn, values: make([]interface{}, this.rows)}
+		for i := range ret.values {
+			ret.values[i] = value
+		}
+	}
+	return ret
+}
+
+/*
+	Returns the column of the parameter with the given [name], failing every [active] row if there's no such column.
+*/
+func (this *columnEvaluator) load(name string, active []bool) columnVector {
+
+	var ret columnVector
+	var column interface{}
+	var found bool
+
+	column, found = this.row.columns[name]
+	if !found {
+
+		for i := 0; i < this.rows; i++ {
+			if this.isLive(active, i) {
+				this.fail(i, errors.New("No parameter '"+name+"' found."))
+			}
+		}
+		return columnVector{kind: valueColumn, values: make([]interface{}, this.rows)}
+	}
+
+	switch column.(type) {
+	case []float64:
+		return columnVector{kind: floatColumn, floats: column.([]float64)}
+	case []string:
+		return columnVector{kind: stringColumn, strings: column.([]string)}
+	case []bool:
+		return columnVector{kind: boolColumn, bools: column.([]bool)}
+	}
+
+	ret = columnVector{kind: valueColumn, values: make([]interface{}, this.rows)}
+	for i, value := range column.([]interface{}) {
+		ret.values[i] = castToFloat64(value)
+	}
+	return ret
+}
+
+/*
+	Applies the operator of [stage] to the whole of each side at once, if both sides have types which it can do this for.
+	Every row is computed, whether active or not, since none of these operations can fail.
+*/
+func (this *columnEvaluator) applyVectors(stage *evaluationStage, left *columnVector, right *columnVector) (columnVector, bool) {
+
+	if stage.leftStage == nil && stage.rightStage != nil {
+		return applyPrefixVector(stage.symbol, right)
+	}
+
+	if stage.leftStage == nil || stage.rightStage == nil || left.kind != right.kind {
+		return columnVector{}, false
+	}
+
+	switch left.kind {
+	case floatColumn:
+		return applyFloatVectors(stage.symbol, left.floats, right.floats)
+	case stringColumn:
+		return applyStringVectors(stage.symbol, left.strings, right.strings)
+	case boolColumn:
+		return applyBoolVectors(stage.symbol, left.bools, right.bools)
+	}
+	return columnVector{}, false
+}
+
+func applyPrefixVector(symbol OperatorSymbol, right *columnVector) (columnVector, bool) {
+
+	switch {
+
+	case symbol == NEGATE && right.kind == floatColumn:
+
+		ret := make([]float64, len(right.floats))
+		for i, value := range right.floats {
+			ret[i] = -value
+		}
+		return columnVector{kind: floatColumn, floats: ret}, true
+
+	case symbol == INVERT && right.kind == boolColumn:
+
+		ret := make([]bool, len(right.bools))
+		for i, value := range right.bools {
+			ret[i] = !value
+		}
+		return columnVector{kind: boolColumn, bools: ret}, true
+	}
+	return columnVector{}, false
+}
+
+func applyFloatVectors(symbol OperatorSymbol, left []float64, right []float64) (columnVector, bool) {
+
+	var floats []float64
+	var bools []bool
+
+	switch symbol {
+	case PLUS:
+		fallthrough
+	case MINUS:
+		fallthrough
+	case MULTIPLY:
+		fallthrough
+	case DIVIDE:
+		fallthrough
+	case MODULUS:
+		fallthrough
+	case EXPONENT:
+		floats = make([]float64, len(left))
+	case EQ:
+		fallthrough
+	case NEQ:
+		fallthrough
+	case GT:
+		fallthrough
+	case GTE:
+		fallthrough
+	case LT:
+		fallthrough
+	case LTE:
+		bools = make([]bool, len(left))
+	default:
+		return columnVector{}, false
+	}
+
+	switch symbol {
+	case PLUS:
+		for i := range floats {
+			floats[i] = left[i] + right[i]
+		}
+	case MINUS:
+		for i := range floats {
+			floats[i] = left[i] - right[i]
+		}
+	case MULTIPLY:
+		for i := range floats {
+			floats[i] = left[i] * right[i]
+		}
+	case DIVIDE:
+		for i := range floats {
+			floats[i] = left[i] / right[i]
+		}
+	case MODULUS:
+		for i := range floats {
+			floats[i] = math.Mod(left[i], right[i])
+		}
+	case EXPONENT:
+		for i := range floats {
+			floats[i] = math.Pow(left[i], right[i])
+		}
+	case EQ:
+		for i := range bools {
+			bools[i] = left[i] == right[i]
+		}
+	case NEQ:
+		for i := range bools {
+			bools[i] = left[i] != right[i]
+		}
+	case GT:
+		for i := range bools {
+			bools[i] = left[i] > right[i]
+		}
+	case GTE:
+		for i := range bools {
+			bools[i] = left[i] >= right[i]
+		}
+	case LT:
+		for i := range bools {
+			bools[i] = left[i] < right[i]
+		}
+	case LTE:
+		for i := range bools {
+			bools[i] = left[i] <= right[i]
+		}
+	}
+
+	if floats != nil {
+		return columnVector{kind: floatColumn, floats: floats}, true
+	}
+	return columnVector{kind: boolColumn, bools: bools}, true
+}
+
+func applyStringVectors(symbol OperatorSymbol, left []string, right []string) (columnVector, bool) {
+
+	var strings []string
+	var bools []bool
+
+	if symbol == PLUS {
+
+		strings = make([]string, len(left))
+		for i := range strings {
+			strings[i] = left[i] + right[i]
+		}
+		return columnVector{kind: stringColumn, strings: strings}, true
+	}
+
+	bools = make([]bool, len(left))
+
+	switch symbol {
+	case EQ:
+		for i := range bools {
+			bools[i] = left[i] == right[i]
+		}
+	case NEQ:
+		for i := range bools {
+			bools[i] = left[i] != right[i]
+		}
+	case GT:
+		for i := range bools {
+			bools[i] = left[i] > right[i]
+		}
+	case GTE:
+		for i := range bools {
+			bools[i] = left[i] >= right[i]
+		}
+	case LT:
+		for i := range bools {
+			bools[i] = left[i] < right[i]
+		}
+	case LTE:
+		for i := range bools {
+			bools[i] = left[i] <= right[i]
+		}
+	default:
+		return columnVector{}, false
+	}
+	return columnVector{kind: boolColumn, bools: bools}, true
+}
+
+/*
+	Applies a logical or equality operator to two columns of bools.
+	Rows which short-circuited have the right result, whatever the unused right side holds.
+*/
+func applyBoolVectors(symbol OperatorSymbol, left []bool, right []bool) (columnVector, bool) {
+
+	var bools []bool
+
+	bools = make([]bool, len(left))
+
+	switch symbol {
+	case AND:
+		for i := range bools {
+			bools[i] = left[i] && right[i]
+		}
+	case OR:
+		for i := range bools {
+			bools[i] = left[i] || right[i]
+		}
+	case EQ:
+		for i := range bools {
+			bools[i] = left[i] == right[i]
+		}
+	case NEQ:
+		for i := range bools {
+			bools[i] = left[i] != right[i]
+		}
+	default:
+		return columnVector{}, false
+	}
+	return columnVector{kind: boolColumn, bools: bools}, true
+}
+
+/*
+	Applies the operator of [stage] to each [active] row on its own, in the same way as `evaluateStage`.
+*/
+func (this *columnEvaluator) applyRows(stage *evaluationStage, left *columnVector, right *columnVector, active []bool) columnVector {
+
+	var checks typeChecks
+	var values []interface{}
+	var leftValue, rightValue, result interface{}
+	var err error
+
+	checks = hoistTypeChecks(stage, this.options)
+	values = make([]interface{}, this.rows)
+
+	for i := 0; i < this.rows; i++ {
+
+		if !this.isLive(active, i) {
+			continue
+		}
+
+		leftValue, rightValue = nil, nil
+
+		if stage.leftStage != nil {
+			leftValue = left.at(i)
+		}
+
+		switch stage.symbol {
+		case AND:
+			if leftValue == false {
+				values[i] = false
+				continue
+			}
+		case OR:
+			if leftValue == true {
+				values[i] = true
+				continue
+			}
+		case COALESCE:
+			if leftValue != nil {
+				values[i] = leftValue
+				continue
+			}
+		case TERNARY_TRUE:
+			if leftValue == false {
+				rightValue = shortCircuitHolder
+			}
+		case TERNARY_FALSE:
+			if leftValue != nil {
+				rightValue = shortCircuitHolder
+			}
+		}
+
+		if rightValue != shortCircuitHolder && stage.rightStage != nil {
+			rightValue = right.at(i)
+		}
+
+		this.row.row = i
+
+		result, err = exitCompiledStage(stage, checks, leftValue, rightValue, &this.parameters)
+		if err != nil {
+			this.fail(i, err)
+			continue
+		}
+		values[i] = result
+	}
+
+	return columnVector{kind: valueColumn, values: values}
+}
+
+/*
+	Returns the result of an evaluation whose root stage gave the given [vector].
+*/
+func (this *columnEvaluator) finish(vector columnVector) ColumnResult {
+
+	var ret ColumnResult
+
+	if this.failed {
+		ret.Errors = this.errs
+	}
+
+	switch vector.kind {
+
+	// results are always copied, since they may be one of the columns that were given.
+	case floatColumn:
+
+		floats := make([]float64, this.rows)
+		for i, value := range vector.floats {
+			if this.errs[i] == nil {
+				floats[i] = value
+			}
+		}
+		ret.Values = floats
+
+	case stringColumn:
+
+		strings := make([]string, this.rows)
+		for i, value := range vector.strings {
+			if this.errs[i] == nil {
+				strings[i] = value
+			}
+		}
+		ret.Values = strings
+
+	case boolColumn:
+
+		bools := make([]bool, this.rows)
+		for i, value := range vector.bools {
+			if this.errs[i] == nil {
+				bools[i] = value
+			}
+		}
+		ret.Values = bools
+
+	default:
+		ret.Values = packColumn(vector.values, ret.Errors)
+	}
+	return ret
+}
+
+/*
+	Returns the given [values] as a []float64, []string, or []bool if every value whose row has no error is of that type,
+	or as they are otherwise.
+*/
+func packColumn(values []interface{}, errs []error) interface{} {
+
+	var floats []float64
+	var strings []string
+	var bools []bool
+	var kind interface{}
+
+	for i, value := range values {
+
+		if errs != nil && errs[i] != nil {
+			continue
+		}
+
+		if kind == nil {
+			kind = value
+		}
+
+		switch kind.(type) {
+		case float64:
+			if _, ok := value.(float64); !ok {
+				return values
+			}
+		case string:
+			if _, ok := value.(string); !ok {
+				return values
+			}
+		case bool:
+			if _, ok := value.(bool); !ok {
+				return values
+			}
+		default:
+			return values
+		}
+	}
+
+	switch kind.(type) {
+	case float64:
+		floats = make([]float64, len(values))
+		for i, value := range values {
+			floats[i], _ = value.(float64)
+		}
+		return floats
+	case string:
+		strings = make([]string, len(values))
+		for i, value := range values {
+			strings[i], _ = value.(string)
+		}
+		return strings
+	case bool:
+		bools = make([]bool, len(values))
+		for i, value := range values {
+			bools[i], _ = value.(bool)
+		}
+		return bools
+	}
+	return values
+}
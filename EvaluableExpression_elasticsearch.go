@@ -0,0 +1,279 @@
+package govaluate
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+/*
+	Returns an Elasticsearch query (in the query DSL, as used by the `query` of a search) which matches the documents
+	for which this expression is true. Parameters are fields of the document, and accessors such as `user.Age` are dotted paths to object fields.
+	The query is made of maps and slices, so can be given straight to `json.Marshal`.
+
+	`&&`, `||` and `!` become `bool` queries which `must`, `should` and `must_not` match their operands.
+	`==` becomes a `term` query, `>`, `>=`, `<` and `<=` become `range` queries, `in` becomes a `terms` query, and `=~` becomes a `regexp` query.
+	`!=` and `!~` are `must_not` matches of `term` and `regexp` queries.
+	Null coalescence such as `(foo ?? 0) > 1` is written using `exists`, treating a missing field as nil.
+
+	Note that Elasticsearch regular expressions are always anchored, and don't support every feature of Go's regular expressions.
+	Patterns are given as they're written in the expression.
+
+	Returns an error for anything the query DSL can't express, such as arithmetic on fields, comparisons between two fields,
+	ternaries, and function or method calls.
+*/
+func (this EvaluableExpression) ToElasticsearchQuery() (map[string]interface{}, error) {
+
+	if this.evaluationStages == nil {
+		return map[string]interface{}{"match_all": map[string]interface{}{}}, nil
+	}
+	return writeElasticsearchQuery(this.evaluationStages)
+}
+
+/*
+	Returns the query which matches documents for which the tree of stages under [stage] is true.
+*/
+func writeElasticsearchQuery(stage *evaluationStage) (map[string]interface{}, error) {
+
+	var query map[string]interface{}
+	var queries []interface{}
+	var err error
+
+	stage = skipNoopStages(stage)
+	if stage == nil {
+		return nil, errors.New("Unable to write an empty expression as an Elasticsearch query")
+	}
+
+	switch stage.symbol {
+
+	case AND:
+		fallthrough
+	case OR:
+
+		for _, operand := range flattenLogicalStages(stage, stage.symbol) {
+
+			query, err = writeElasticsearchQuery(operand)
+			if err != nil {
+				return nil, err
+			}
+			queries = append(queries, query)
+		}
+
+		if stage.symbol == AND {
+			return map[string]interface{}{"bool": map[string]interface{}{"must": queries}}, nil
+		}
+		return map[string]interface{}{"bool": map[string]interface{}{"should": queries, "minimum_should_match": 1}}, nil
+
+	case INVERT:
+
+		query, err = writeElasticsearchQuery(stage.rightStage)
+		if err != nil {
+			return nil, err
+		}
+		return invertElasticsearchQuery(query), nil
+
+	case EQ:
+		fallthrough
+	case NEQ:
+		fallthrough
+	case GT:
+		fallthrough
+	case GTE:
+		fallthrough
+	case LT:
+		fallthrough
+	case LTE:
+		fallthrough
+	case REQ:
+		fallthrough
+	case NREQ:
+		fallthrough
+	case IN:
+		return writeElasticsearchComparison(stage)
+
+	case VALUE:
+		fallthrough
+	case ACCESS:
+		fallthrough
+	case COALESCE:
+
+		// a boolean on its own is the same as comparing it to true.
+		if isFilterField(stage) {
+			return writeElasticsearchCondition(&evaluationStage{symbol: EQ, operator: equalStage}, stage, true, false)
+		}
+
+	case LITERAL:
+
+		if stage.value == true {
+			return map[string]interface{}{"match_all": map[string]interface{}{}}, nil
+		}
+		if stage.value == false {
+			return map[string]interface{}{"match_none": map[string]interface{}{}}, nil
+		}
+		return nil, errors.New(fmt.Sprintf("Unable to write literal '%v' as an Elasticsearch query", stage.value))
+
+	case FUNCTIONAL:
+		return nil, errors.New(fmt.Sprintf("Unable to write function '%s' as an Elasticsearch query", stage.name))
+	}
+
+	if stage.symbol == ACCESS {
+		return nil, errors.New(fmt.Sprintf("Unable to write method call '%s' as an Elasticsearch query", strings.Join(stage.path, ".")))
+	}
+
+	if isArithmeticStage(stage) {
+		return nil, errors.New(fmt.Sprintf("Unable to write arithmetic '%v' as an Elasticsearch query", stage.symbol))
+	}
+	return nil, errors.New(fmt.Sprintf("Unable to write operator '%v' as an Elasticsearch query", stage.symbol))
+}
+
+/*
+	Writes a comparison between a field and a literal, given the comparator [stage].
+*/
+func writeElasticsearchComparison(stage *evaluationStage) (map[string]interface{}, error) {
+
+	var field, literal *evaluationStage
+	var value interface{}
+	var flipped bool
+	var err error
+
+	// the query DSL can only compare stored values, so a more specific error is given for computed ones.
+	for _, operand := range []*evaluationStage{skipNoopStages(stage.leftStage), skipNoopStages(stage.rightStage)} {
+		if isArithmeticStage(operand) {
+			return nil, errors.New(fmt.Sprintf("Unable to write arithmetic '%v' as an Elasticsearch query", operand.symbol))
+		}
+	}
+
+	field, literal, flipped, err = findFilterComparison(stage, "an Elasticsearch query")
+	if err != nil {
+		return nil, err
+	}
+
+	if stage.symbol == IN {
+		value, err = findFilterArray(stage.rightStage, "an Elasticsearch query")
+	} else {
+		value, err = findFilterLiteral(literal, "an Elasticsearch query")
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return writeElasticsearchCondition(stage, field, value, flipped)
+}
+
+/*
+	Writes the condition that the [field] compares to [value] according to the comparator [stage].
+	If [flipped] is true, the comparison was written with the value on the left.
+*/
+func writeElasticsearchCondition(stage *evaluationStage, field *evaluationStage, value interface{}, flipped bool) (map[string]interface{}, error) {
+
+	var query, exists map[string]interface{}
+	var path string
+	var matchesMissing bool
+	var err error
+
+	if field.symbol != COALESCE {
+		return findElasticsearchQuery(stage.symbol, flipped, findFilterPath(field), value), nil
+	}
+
+	// a coalesced field compares its fallback when it's missing, which is either always or never true.
+	path = findFilterPath(skipNoopStages(field.leftStage))
+	query = findElasticsearchQuery(stage.symbol, flipped, path, value)
+	exists = map[string]interface{}{"exists": map[string]interface{}{"field": path}}
+
+	matchesMissing, err = matchesFilterFallback(stage, field, value, flipped, "an Elasticsearch query")
+	if err != nil {
+		return nil, err
+	}
+
+	if matchesMissing {
+		return map[string]interface{}{
+			"bool": map[string]interface{}{
+				"should":               []interface{}{query, invertElasticsearchQuery(exists)},
+				"minimum_should_match": 1,
+			},
+		}, nil
+	}
+
+	// most queries never match a missing field, but those which are inverted do.
+	if stage.symbol == NEQ || stage.symbol == NREQ {
+		return map[string]interface{}{"bool": map[string]interface{}{"must": []interface{}{exists, query}}}, nil
+	}
+	return query, nil
+}
+
+/*
+	Returns the query for the field at [path] compared to [value] with the given [symbol], such as {"range": {"age": {"gt": 5}}}.
+	If [flipped] is true, the comparison was written with the value on the left, so ordering comparators are reversed.
+*/
+func findElasticsearchQuery(symbol OperatorSymbol, flipped bool, path string, value interface{}) map[string]interface{} {
+
+	if flipped {
+		symbol = flipComparator(symbol)
+	}
+
+	switch symbol {
+	case NEQ:
+		return invertElasticsearchQuery(findElasticsearchQuery(EQ, false, path, value))
+	case GT:
+		return map[string]interface{}{"range": map[string]interface{}{path: map[string]interface{}{"gt": value}}}
+	case GTE:
+		return map[string]interface{}{"range": map[string]interface{}{path: map[string]interface{}{"gte": value}}}
+	case LT:
+		return map[string]interface{}{"range": map[string]interface{}{path: map[string]interface{}{"lt": value}}}
+	case LTE:
+		return map[string]interface{}{"range": map[string]interface{}{path: map[string]interface{}{"lte": value}}}
+	case REQ:
+		return map[string]interface{}{"regexp": map[string]interface{}{path: value}}
+	case NREQ:
+		return invertElasticsearchQuery(findElasticsearchQuery(REQ, false, path, value))
+	case IN:
+		return map[string]interface{}{"terms": map[string]interface{}{path: value}}
+	}
+	return map[string]interface{}{"term": map[string]interface{}{path: value}}
+}
+
+/*
+	Returns a query which matches every document that the given [query] does not.
+	Inverting a query which is itself only an inversion gives back the original query.
+*/
+func invertElasticsearchQuery(query map[string]interface{}) map[string]interface{} {
+
+	var clauses map[string]interface{}
+	var inverted []interface{}
+	var ok bool
+
+	clauses, ok = query["bool"].(map[string]interface{})
+	if ok && len(query) == 1 && len(clauses) == 1 {
+
+		inverted, ok = clauses["must_not"].([]interface{})
+		if ok && len(inverted) == 1 {
+			return inverted[0].(map[string]interface{})
+		}
+	}
+
+	return map[string]interface{}{"bool": map[string]interface{}{"must_not": []interface{}{query}}}
+}
+
+/*
+	Returns true if the given [stage] computes a value with arithmetic or bitwise operators.
+*/
+func isArithmeticStage(stage *evaluationStage) bool {
+
+	if stage == nil {
+		return false
+	}
+
+	switch findOperatorPrecedenceForSymbol(stage.symbol) {
+	case exponentialPrecedence:
+		fallthrough
+	case additivePrecedence:
+		fallthrough
+	case bitwisePrecedence:
+		fallthrough
+	case bitwiseShiftPrecedence:
+		fallthrough
+	case multiplicativePrecedence:
+		return true
+	}
+	return stage.symbol == NEGATE || stage.symbol == BITWISE_NOT
+}
@@ -0,0 +1,232 @@
+package govaluate
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+/*
+	Returns this expression written out in canonical form, such that any two expressions with the same syntax tree are formatted identically.
+	Operators are separated by single spaces, strings are single-quoted, parameters are only bracketed when their names require it,
+	and parentheses are only used where operator precedence requires them.
+
+	The result is formatted from the syntax tree given by `AST()`, so operators between literals are formatted as their computed result.
+	Parsing the result (with the same functions and numeric mode) gives an expression with the same syntax tree.
+	Negative numbers are written with a leading "-", which is parsed back into the same negative literal.
+	Infinities and NaN are written as divisions by zero, such as "(1 / 0)"; since NaN never equals itself, a tree holding one
+	is only the same in that it holds NaN in the same place.
+*/
+func (this EvaluableExpression) Format() (string, error) {
+	return FormatNode(this.AST())
+}
+
+/*
+	Parses the given [expression] with the given [options], and returns it in canonical form. See `EvaluableExpression.Format()`.
+	This is useful for normalizing expressions before storing or comparing them.
+*/
+func Format(expression string, options ...Option) (string, error) {
+
+	var compiled *EvaluableExpression
+	var err error
+
+	compiled, err = Compile(expression, options...)
+	if err != nil {
+		return "", err
+	}
+	return compiled.Format()
+}
+
+/*
+	Returns the tree under the given [root] written out as an expression, in canonical form. See `EvaluableExpression.Format()`.
+	Returns an error if the tree can't be written as an expression, for the same reasons that `CompileAST` would.
+*/
+func FormatNode(root Node) (string, error) {
+
+	var tokens []ExpressionToken
+	var err error
+
+	// every kind of number is accepted as a decimal, which is also how they're formatted.
+	tokens, err = nodeTokens(root, DECIMAL_NUMERICS)
+	if err != nil {
+		return "", err
+	}
+	return formatTokens(tokens)
+}
+
+/*
+	Writes out the given [tokens] as an expression.
+*/
+func formatTokens(tokens []ExpressionToken) (string, error) {
+
+	var buffer bytes.Buffer
+	var functions map[string]bool
+	var text string
+	var err error
+
+	// a parameter with the same name as a function must be bracketed, or it would be parsed as the function.
+	functions = make(map[string]bool)
+	for _, token := range tokens {
+		if token.Kind == FUNCTION {
+			functions[token.Text] = true
+		}
+	}
+
+	for _, token := range tokens {
+
+		switch token.Kind {
+
+		case NUMERIC:
+			text, err = formatNumber(token.Value)
+			if err != nil {
+				return "", err
+			}
+		case BOOLEAN:
+			text = strconv.FormatBool(token.Value.(bool))
+		case STRING:
+			text = quoteString(token.Value.(string))
+		case PATTERN:
+			text = quoteString(token.Value.(*regexp.Regexp).String())
+		case TIME:
+			text = quoteString(token.Value.(time.Time).Format(isoDateFormat))
+		case VARIABLE:
+			text = formatVariable(token.Value.(string), functions)
+		case ACCESSOR:
+			text = strings.Join(token.Value.([]string), ".")
+		case FUNCTION:
+
+			// functions are only known by name when they're parsed, or given with one.
+			if token.Text == "" {
+				return "", errors.New("Unable to format a function with no name")
+			}
+			text = token.Text
+		case CLAUSE:
+			text = "("
+		case CLAUSE_CLOSE:
+			text = ")"
+		case SEPARATOR:
+			text = ", "
+		case PREFIX:
+			text = token.Value.(string)
+		case COMPARATOR:
+			fallthrough
+		case LOGICALOP:
+			fallthrough
+		case MODIFIER:
+			fallthrough
+		case TERNARY:
+			text = " " + token.Value.(string) + " "
+		default:
+			return "", errors.New(fmt.Sprintf("Unable to format token of kind %v", token.Kind))
+		}
+
+		buffer.WriteString(text)
+	}
+
+	return buffer.String(), nil
+}
+
+/*
+	Formats the given number in plain decimal notation, such as "1.25" or "1000000".
+	Infinities and NaN have no notation of their own, so they're written as the divisions by zero which give them,
+	such as "(1 / 0)". These are computed in advance when parsed, so they're parsed as the same literals.
+*/
+func formatNumber(value interface{}) (string, error) {
+
+	var rat = toRat(value)
+	var float, isFloat = value.(float64)
+
+	switch {
+	case isFloat && math.IsInf(float, 1):
+		return "(1 / 0)", nil
+	case isFloat && math.IsInf(float, -1):
+		return "(-1 / 0)", nil
+	case isFloat && math.IsNaN(float):
+		return "(0 / 0)", nil
+	}
+
+	if rat == nil {
+		return "", errors.New(fmt.Sprintf("Unable to format number '%v'", value))
+	}
+	return formatDecimal(rat), nil
+}
+
+/*
+	Single-quotes the given string, escaping any quotes or backslashes within it.
+*/
+func quoteString(value string) string {
+
+	var buffer bytes.Buffer
+
+	buffer.WriteRune('\'')
+	for _, character := range value {
+
+		if character == '\'' || character == '"' || character == '\\' {
+			buffer.WriteRune('\\')
+		}
+		buffer.WriteRune(character)
+	}
+	buffer.WriteRune('\'')
+
+	return buffer.String()
+}
+
+/*
+	Returns the given parameter [name] as it must be written in an expression, bracketing and escaping it if needed.
+*/
+func formatVariable(name string, functions map[string]bool) string {
+
+	var buffer bytes.Buffer
+
+	if isPlainVariableName(name) && !functions[name] {
+		return name
+	}
+
+	buffer.WriteRune('[')
+	for _, character := range name {
+
+		if character == ']' || character == '\\' {
+			buffer.WriteRune('\\')
+		}
+		buffer.WriteRune(character)
+	}
+	buffer.WriteRune(']')
+
+	return buffer.String()
+}
+
+/*
+	Returns true if the given parameter [name] is parsed as a parameter without being bracketed.
+*/
+func isPlainVariableName(name string) bool {
+
+	switch name {
+	case "":
+		fallthrough
+	case "true":
+		fallthrough
+	case "false":
+		fallthrough
+	case "in":
+		fallthrough
+	case "IN":
+		return false
+	}
+
+	if !unicode.IsLetter(getFirstRune(name)) {
+		return false
+	}
+
+	for _, character := range name {
+		if character == '.' || !isVariableName(character) {
+			return false
+		}
+	}
+	return true
+}
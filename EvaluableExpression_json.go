@@ -0,0 +1,477 @@
+package govaluate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+/*
+	The version of the format written by `MarshalJSON`. Expressions written in any other version can't be read.
+*/
+const serializedExpressionVersion int = 1
+
+/*
+	The form in which `MarshalJSON` writes an expression.
+*/
+type serializedExpression struct {
+	Version         int              `json:"version"`
+	Expression      string           `json:"expression,omitempty"`
+	NumericMode     string           `json:"numericMode"`
+	DecimalRounding *DecimalRounding `json:"decimalRounding,omitempty"`
+	QueryDateFormat string           `json:"queryDateFormat"`
+	ChecksTypes     bool             `json:"checksTypes"`
+	Budget          EvaluationBudget `json:"budget"`
+	Root            *serializedNode  `json:"root"`
+}
+
+/*
+	The form in which `MarshalJSON` writes each Node of an expression's syntax tree.
+	[Kind] says which Node it is, and [Operands] are its children, in the order given by `Children()`.
+*/
+type serializedNode struct {
+	Kind     string            `json:"kind"`
+	Type     string            `json:"type,omitempty"`
+	Value    json.RawMessage   `json:"value,omitempty"`
+	Name     string            `json:"name,omitempty"`
+	Path     []string          `json:"path,omitempty"`
+	Method   bool              `json:"method,omitempty"`
+	Operator string            `json:"operator,omitempty"`
+	Operands []*serializedNode `json:"operands,omitempty"`
+}
+
+/*
+	Writes this expression as JSON, so that it can be stored and read back later with `UnmarshalExpression`
+	without being parsed again. The syntax tree of the expression is written along with its settings
+	(numeric mode, decimal rounding, QueryDateFormat, ChecksTypes, and Budget), and its original text, if it has one.
+
+	Functions can't be written, so each call refers to its function by name. Operators given with `WithOperator` aren't written either;
+	both need to be given again when the expression is read.
+*/
+func (this EvaluableExpression) MarshalJSON() ([]byte, error) {
+
+	var serialized serializedExpression
+	var err error
+
+	serialized = serializedExpression{
+		Version:         serializedExpressionVersion,
+		Expression:      this.inputExpression,
+		NumericMode:     this.numericMode.String(),
+		DecimalRounding: this.decimalRounding,
+		QueryDateFormat: this.QueryDateFormat,
+		ChecksTypes:     this.ChecksTypes,
+		Budget:          this.Budget,
+	}
+
+	serialized.Root, err = serializeNode(this.AST())
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(serialized)
+}
+
+/*
+	Reads an expression written by `MarshalJSON` into this one. Since no functions can be given,
+	this fails for expressions which call any; use `UnmarshalExpression` to read those.
+*/
+func (this *EvaluableExpression) UnmarshalJSON(data []byte) error {
+
+	var ret *EvaluableExpression
+	var err error
+
+	ret, err = UnmarshalExpression(data)
+	if err != nil {
+		return err
+	}
+
+	*this = *ret
+	return nil
+}
+
+/*
+	Reads an expression written by `MarshalJSON`, configured by the given [options].
+	Each function called by the expression is found by name among the functions given with `WithFunctions` or `WithContextFunctions`,
+	and an error is returned if any are missing.
+
+	The expression's stored settings are applied before [options], so options such as `WithBudget` replace what was stored.
+*/
+func UnmarshalExpression(data []byte, options ...Option) (*EvaluableExpression, error) {
+
+	var serialized serializedExpression
+	var compiled *compileOptions
+	var ret *EvaluableExpression
+	var root Node
+	var err error
+
+	err = json.Unmarshal(data, &serialized)
+	if err != nil {
+		return nil, err
+	}
+
+	if serialized.Version != serializedExpressionVersion {
+		return nil, errors.New(fmt.Sprintf("Unable to read an expression written in version %d", serialized.Version))
+	}
+
+	compiled, err = deserializeOptions(serialized, options)
+	if err != nil {
+		return nil, err
+	}
+
+	root, err = deserializeNode(serialized.Root, compiled)
+	if err != nil {
+		return nil, err
+	}
+
+	ret, err = compileNode(root, compiled)
+	if err != nil {
+		return nil, err
+	}
+
+	ret.inputExpression = serialized.Expression
+	return ret, nil
+}
+
+/*
+	Returns the compile options for an expression with the given stored settings, followed by the given [options].
+*/
+func deserializeOptions(serialized serializedExpression, options []Option) (*compileOptions, error) {
+
+	var ret *compileOptions
+	var stored []Option
+	var mode NumericMode
+	var found bool
+
+	for mode = FLOAT_NUMERICS; mode.String() != "UNKNOWN"; mode++ {
+		if mode.String() == serialized.NumericMode {
+			found = true
+			break
+		}
+	}
+
+	if !found {
+		return nil, errors.New(fmt.Sprintf("Unknown numeric mode '%s'", serialized.NumericMode))
+	}
+
+	stored = []Option{
+		WithNumericMode(mode),
+		WithQueryDateFormat(serialized.QueryDateFormat),
+		WithTypeChecks(serialized.ChecksTypes),
+		WithBudget(serialized.Budget),
+	}
+
+	if serialized.DecimalRounding != nil {
+		stored = append(stored, WithDecimalRounding(*serialized.DecimalRounding))
+	}
+
+	ret = newCompileOptions(append(stored, options...))
+	if ret.err != nil {
+		return nil, ret.err
+	}
+	return ret, nil
+}
+
+/*
+	Returns the serialized form of the tree under the given [node].
+*/
+func serializeNode(node Node) (*serializedNode, error) {
+
+	var ret *serializedNode
+	var operand *serializedNode
+	var err error
+
+	switch node.(type) {
+
+	case nil:
+		return nil, nil
+
+	case *LiteralNode:
+		return serializeLiteral(node.(*LiteralNode).Value)
+
+	case *VariableNode:
+		return &serializedNode{Kind: "variable", Name: node.(*VariableNode).Name}, nil
+
+	case *AccessorNode:
+		ret = &serializedNode{Kind: "accessor", Path: node.(*AccessorNode).Path, Method: node.(*AccessorNode).IsMethodCall}
+
+	case *FunctionNode:
+		ret = &serializedNode{Kind: "function", Name: node.(*FunctionNode).Name}
+
+	case *UnaryNode:
+
+		text, found := findSymbolText(prefixSymbols, node.(*UnaryNode).Operator)
+		if !found {
+			return nil, errors.New(fmt.Sprintf("'%v' is not a prefix operator", node.(*UnaryNode).Operator))
+		}
+		ret = &serializedNode{Kind: "unary", Operator: text}
+
+	case *BinaryNode:
+
+		token, err := operatorToken(node.(*BinaryNode).Operator)
+		if err != nil {
+			return nil, err
+		}
+		ret = &serializedNode{Kind: "binary", Operator: token.Value.(string)}
+
+	case *TernaryNode:
+		ret = &serializedNode{Kind: "ternary"}
+
+	case *ArrayNode:
+		ret = &serializedNode{Kind: "array"}
+
+	default:
+		return nil, errors.New(fmt.Sprintf("Unable to serialize %T", node))
+	}
+
+	for _, child := range node.Children() {
+
+		if child == nil {
+			return nil, errors.New("Missing operand")
+		}
+
+		operand, err = serializeNode(child)
+		if err != nil {
+			return nil, err
+		}
+		ret.Operands = append(ret.Operands, operand)
+	}
+	return ret, nil
+}
+
+func serializeLiteral(value interface{}) (*serializedNode, error) {
+
+	var ret *serializedNode
+	var err error
+
+	ret = &serializedNode{Kind: "literal"}
+
+	switch value.(type) {
+
+	case float64:
+
+		ret.Type = "float"
+
+		// JSON has no numbers for infinities or NaN, so these are written as the strings "+Inf", "-Inf" and "NaN".
+		if math.IsInf(value.(float64), 0) || math.IsNaN(value.(float64)) {
+			value = strconv.FormatFloat(value.(float64), 'g', -1, 64)
+		}
+
+	case int64:
+		ret.Type = "integer"
+	case uint64:
+		ret.Type = "unsigned"
+	case string:
+		ret.Type = "string"
+	case bool:
+		ret.Type = "boolean"
+	case time.Time:
+		ret.Type = "time"
+
+	// decimals are written as fractions, since not every decimal has a finite number of digits.
+	case *big.Rat:
+		ret.Type = "decimal"
+		value = value.(*big.Rat).RatString()
+
+	case *regexp.Regexp:
+		ret.Type = "pattern"
+		value = value.(*regexp.Regexp).String()
+
+	default:
+		return nil, errors.New(fmt.Sprintf("Unable to serialize literal of type %T", value))
+	}
+
+	ret.Value, err = json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+/*
+	Returns the tree for the given [serialized] node, finding each function by name in the given [options].
+*/
+func deserializeNode(serialized *serializedNode, options *compileOptions) (Node, error) {
+
+	var operands []Node
+	var operand Node
+	var err error
+
+	if serialized == nil {
+		return nil, errors.New("Missing operand")
+	}
+
+	if serialized.Kind == "literal" {
+		return deserializeLiteral(serialized)
+	}
+
+	for _, child := range serialized.Operands {
+
+		operand, err = deserializeNode(child, options)
+		if err != nil {
+			return nil, err
+		}
+		operands = append(operands, operand)
+	}
+
+	switch serialized.Kind {
+
+	case "variable":
+		return &VariableNode{Name: serialized.Name}, nil
+
+	case "accessor":
+		return &AccessorNode{Path: serialized.Path, Arguments: operands, IsMethodCall: serialized.Method}, nil
+
+	case "function":
+
+		if function, found := options.contextFunctions[serialized.Name]; found {
+			return &FunctionNode{Name: serialized.Name, ContextFunction: function, Arguments: operands}, nil
+		}
+		if function, found := options.functions[serialized.Name]; found {
+			return &FunctionNode{Name: serialized.Name, Function: function, Arguments: operands}, nil
+		}
+		return nil, errors.New(fmt.Sprintf("The expression calls function '%s', which was not given", serialized.Name))
+
+	case "unary":
+
+		symbol, found := prefixSymbols[serialized.Operator]
+		if !found || len(operands) != 1 {
+			return nil, errors.New(fmt.Sprintf("Invalid prefix operator '%s'", serialized.Operator))
+		}
+		return &UnaryNode{Operator: symbol, Operand: operands[0]}, nil
+
+	case "binary":
+
+		symbol, found := findBinarySymbol(serialized.Operator)
+		if !found || len(operands) != 2 {
+			return nil, errors.New(fmt.Sprintf("Invalid binary operator '%s'", serialized.Operator))
+		}
+		return &BinaryNode{Operator: symbol, Left: operands[0], Right: operands[1]}, nil
+
+	case "ternary":
+
+		switch len(operands) {
+		case 2:
+			return &TernaryNode{Condition: operands[0], Then: operands[1]}, nil
+		case 3:
+			return &TernaryNode{Condition: operands[0], Then: operands[1], Else: operands[2]}, nil
+		}
+		return nil, errors.New(fmt.Sprintf("A ternary must have 2 or 3 operands, but has %d", len(operands)))
+
+	case "array":
+		return &ArrayNode{Elements: operands}, nil
+	}
+
+	return nil, errors.New(fmt.Sprintf("Unknown kind of node '%s'", serialized.Kind))
+}
+
+func deserializeLiteral(serialized *serializedNode) (Node, error) {
+
+	var text string
+	var err error
+
+	switch serialized.Type {
+
+	case "float":
+
+		var value float64
+
+		if len(serialized.Value) > 0 && serialized.Value[0] == '"' {
+			return deserializeNonFinite(serialized.Value)
+		}
+		err = json.Unmarshal(serialized.Value, &value)
+		return Lit(value), err
+
+	case "integer":
+		var value int64
+		err = json.Unmarshal(serialized.Value, &value)
+		return Lit(value), err
+
+	case "unsigned":
+		var value uint64
+		err = json.Unmarshal(serialized.Value, &value)
+		return Lit(value), err
+
+	case "string":
+		var value string
+		err = json.Unmarshal(serialized.Value, &value)
+		return Lit(value), err
+
+	case "boolean":
+		var value bool
+		err = json.Unmarshal(serialized.Value, &value)
+		return Lit(value), err
+
+	case "time":
+		var value time.Time
+		err = json.Unmarshal(serialized.Value, &value)
+		return Lit(value), err
+	}
+
+	err = json.Unmarshal(serialized.Value, &text)
+	if err != nil {
+		return nil, err
+	}
+
+	switch serialized.Type {
+
+	case "decimal":
+
+		value, found := new(big.Rat).SetString(text)
+		if !found {
+			return nil, errors.New(fmt.Sprintf("Invalid decimal '%s'", text))
+		}
+		return Lit(value), nil
+
+	case "pattern":
+
+		value, err := regexp.Compile(text)
+		if err != nil {
+			return nil, err
+		}
+		return Lit(value), nil
+	}
+
+	return nil, errors.New(fmt.Sprintf("Unknown type of literal '%s'", serialized.Type))
+}
+
+/*
+	Returns the literal for a float which was written as a string, which must be one of "+Inf", "-Inf" or "NaN".
+*/
+func deserializeNonFinite(serialized json.RawMessage) (Node, error) {
+
+	var text string
+	var err error
+
+	err = json.Unmarshal(serialized, &text)
+	if err != nil {
+		return nil, err
+	}
+
+	switch text {
+	case "+Inf":
+		return Lit(math.Inf(1)), nil
+	case "-Inf":
+		return Lit(math.Inf(-1)), nil
+	case "NaN":
+		return Lit(math.NaN()), nil
+	}
+	return nil, errors.New(fmt.Sprintf("Invalid float '%s'", text))
+}
+
+/*
+	Returns the binary operator written as the given [text] in an expression.
+*/
+func findBinarySymbol(text string) (OperatorSymbol, bool) {
+
+	for _, symbols := range []map[string]OperatorSymbol{comparatorSymbols, logicalSymbols, modifierSymbols, ternarySymbols} {
+
+		if symbol, found := symbols[text]; found {
+			return symbol, true
+		}
+	}
+	return VALUE, false
+}
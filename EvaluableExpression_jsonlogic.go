@@ -0,0 +1,653 @@
+package govaluate
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+	The JsonLogic operation used for each operator.
+	JsonLogic has no equivalent for some operators, which are written as non-standard operations named after their symbol;
+	other JsonLogic implementations need those operations added before they can use rules which contain them.
+*/
+var jsonLogicOperations = map[OperatorSymbol]string{
+
+	EQ:   "==",
+	NEQ:  "!=",
+	GT:   ">",
+	LT:   "<",
+	GTE:  ">=",
+	LTE:  "<=",
+	IN:   "in",
+	AND:  "and",
+	OR:   "or",
+	REQ:  "=~",
+	NREQ: "!~",
+
+	PLUS:           "+",
+	MINUS:          "-",
+	MULTIPLY:       "*",
+	DIVIDE:         "/",
+	MODULUS:        "%",
+	EXPONENT:       "**",
+	BITWISE_AND:    "&",
+	BITWISE_OR:     "|",
+	BITWISE_XOR:    "^",
+	BITWISE_LSHIFT: "<<",
+	BITWISE_RSHIFT: ">>",
+	COALESCE:       "??",
+
+	NEGATE:      "-",
+	INVERT:      "!",
+	BITWISE_NOT: "~",
+}
+
+/*
+	Returns this expression as a JsonLogic rule (see jsonlogic.com), such as `{"and": [{">": [{"var": "x"}, 3]}, ...]}`.
+	Parameters are written as `var`, and accessors as a dotted `var` (such as `{"var": "user.Age"}`).
+	Null coalescence of a parameter with a literal is written as a `var` with a default (such as `{"var": ["x", 0]}`),
+	and ternaries are written as `if`.
+
+	Operators which JsonLogic doesn't have (regexes, `**`, bitwise operators, and any other null coalescence) are written as
+	operations named after their symbol, such as `{"=~": [{"var": "name"}, "^foo"]}`. User-defined functions are written
+	as operations named after the function. Both can be read back by `CompileJsonLogic`.
+
+	Times are written as strings in ISO-8601 format. Returns an error for method calls, which JsonLogic can't express.
+*/
+func (this EvaluableExpression) ToJsonLogic() ([]byte, error) {
+
+	var rule interface{}
+	var buffer bytes.Buffer
+	var encoder *json.Encoder
+	var err error
+
+	rule, err = writeJsonLogic(this.AST())
+	if err != nil {
+		return nil, err
+	}
+
+	// operations such as `<` and `&` are written as they are, rather than escaped for HTML.
+	encoder = json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+
+	err = encoder.Encode(rule)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buffer.Bytes(), "\n"), nil
+}
+
+/*
+	Creates a new EvaluableExpression from the given JsonLogic [rule] (see jsonlogic.com), configured by the given [options].
+	Reads every operation written by `ToJsonLogic`, along with `?:`, `===`, `!==`, `!!`, and `<` or `<=` with three arguments.
+	Any other operation calls the user-defined function of the same name (given by `WithFunctions` or `WithContextFunctions`).
+	Strings are converted to times in the same way as when parsing an expression.
+
+	Unlike JsonLogic, expressions don't treat values as "truthy"; `and`, `or`, `!` and `if` require booleans.
+	`in` checks membership of an array, so can't be used to find a substring.
+*/
+func CompileJsonLogic(rule []byte, options ...Option) (*EvaluableExpression, error) {
+
+	var compiled *compileOptions
+	var decoder *json.Decoder
+	var decoded interface{}
+	var root Node
+	var err error
+
+	compiled = newCompileOptions(options)
+	if compiled.err != nil {
+		return nil, compiled.err
+	}
+
+	// numbers are kept as written, so that integers and decimals aren't rounded through a float.
+	decoder = json.NewDecoder(bytes.NewReader(rule))
+	decoder.UseNumber()
+
+	err = decoder.Decode(&decoded)
+	if err != nil {
+		return nil, err
+	}
+
+	root, err = readJsonLogic(decoded, compiled)
+	if err != nil {
+		return nil, err
+	}
+	return compileNode(root, compiled)
+}
+
+/*
+	Returns the JsonLogic rule for the tree under the given [node], as the maps and slices that it's marshaled from.
+*/
+func writeJsonLogic(node Node) (interface{}, error) {
+
+	var operation string
+	var operands []Node
+	var arguments []interface{}
+	var argument interface{}
+	var err error
+
+	switch node.(type) {
+
+	case nil:
+		return nil, errors.New("Missing operand")
+
+	case *LiteralNode:
+		return writeJsonLogicLiteral(node.(*LiteralNode).Value)
+
+	case *VariableNode, *AccessorNode:
+
+		name, isVariable := findJsonLogicVariable(node)
+		if !isVariable {
+			return nil, errors.New(fmt.Sprintf("Unable to write method call '%s' as JsonLogic", name))
+		}
+		return map[string]interface{}{"var": name}, nil
+
+	case *FunctionNode:
+		operation = node.(*FunctionNode).Name
+		operands = node.(*FunctionNode).Arguments
+
+	case *UnaryNode:
+		operation = jsonLogicOperations[node.(*UnaryNode).Operator]
+		operands = []Node{node.(*UnaryNode).Operand}
+
+	case *BinaryNode:
+
+		binary := node.(*BinaryNode)
+		operation = jsonLogicOperations[binary.Operator]
+
+		switch binary.Operator {
+
+		case AND:
+			fallthrough
+		case OR:
+			operands = flattenLogicalNodes(binary, binary.Operator)
+
+		case COALESCE:
+
+			// a parameter with a literal fallback is a `var` with a default.
+			name, isVariable := findJsonLogicVariable(binary.Left)
+			literal, isLiteral := binary.Right.(*LiteralNode)
+
+			if isVariable && isLiteral {
+
+				argument, err = writeJsonLogicLiteral(literal.Value)
+				if err != nil {
+					return nil, err
+				}
+				return map[string]interface{}{"var": []interface{}{name, argument}}, nil
+			}
+			operands = []Node{binary.Left, binary.Right}
+
+		default:
+			operands = []Node{binary.Left, binary.Right}
+		}
+
+	case *TernaryNode:
+		operation = "if"
+		operands = flattenTernaryNodes(node.(*TernaryNode))
+
+	case *ArrayNode:
+		operands = node.(*ArrayNode).Elements
+
+	default:
+		return nil, errors.New(fmt.Sprintf("Unable to write %T as JsonLogic", node))
+	}
+
+	arguments = []interface{}{}
+	for _, operand := range operands {
+
+		argument, err = writeJsonLogic(operand)
+		if err != nil {
+			return nil, err
+		}
+		arguments = append(arguments, argument)
+	}
+
+	// arrays are written as they are, everything else is an operation on its arguments.
+	if operation == "" {
+		return arguments, nil
+	}
+	return map[string]interface{}{operation: arguments}, nil
+}
+
+func writeJsonLogicLiteral(value interface{}) (interface{}, error) {
+
+	switch value.(type) {
+
+	case string, bool, int64, uint64:
+		return value, nil
+
+	// JSON has no numbers for infinities or NaN, so they can't be written.
+	case float64:
+
+		if math.IsInf(value.(float64), 0) || math.IsNaN(value.(float64)) {
+			return nil, errors.New(fmt.Sprintf("Unable to write %v as JsonLogic, which has no infinite or NaN numbers", value))
+		}
+		return value, nil
+
+	case *regexp.Regexp:
+		return value.(*regexp.Regexp).String(), nil
+	case time.Time:
+		return value.(time.Time).Format(isoDateFormat), nil
+	case *big.Rat:
+		return json.Number(formatDecimal(value.(*big.Rat))), nil
+	}
+
+	return nil, errors.New(fmt.Sprintf("Unable to write literal of type %T as JsonLogic", value))
+}
+
+/*
+	Returns the name that a `var` uses for the given parameter or accessor [node], such as "user.Age".
+	Returns false if [node] is anything else, such as a method call.
+*/
+func findJsonLogicVariable(node Node) (string, bool) {
+
+	switch node.(type) {
+	case *VariableNode:
+		return node.(*VariableNode).Name, true
+	case *AccessorNode:
+		return strings.Join(node.(*AccessorNode).Path, "."), !node.(*AccessorNode).IsMethodCall
+	}
+	return "", false
+}
+
+/*
+	Returns every operand of a chain of the given logical operator [symbol], such as each of `a`, `b` and `c` in `a && b && c`.
+*/
+func flattenLogicalNodes(node Node, symbol OperatorSymbol) []Node {
+
+	binary, isBinary := node.(*BinaryNode)
+	if !isBinary || binary.Operator != symbol {
+		return []Node{node}
+	}
+	return append(flattenLogicalNodes(binary.Left, symbol), flattenLogicalNodes(binary.Right, symbol)...)
+}
+
+/*
+	Returns the arguments of the JsonLogic `if` for the given [ternary].
+	Ternaries whose else part is another ternary are written as a single `if`, such as `[a, 1, b, 2, 3]` for `a ? 1 : b ? 2 : 3`.
+*/
+func flattenTernaryNodes(ternary *TernaryNode) []Node {
+
+	var ret []Node
+
+	for {
+		ret = append(ret, ternary.Condition, ternary.Then)
+
+		next, isTernary := ternary.Else.(*TernaryNode)
+		if !isTernary {
+			break
+		}
+		ternary = next
+	}
+
+	if ternary.Else != nil {
+		ret = append(ret, ternary.Else)
+	}
+	return ret
+}
+
+/*
+	Returns the tree for the given decoded JsonLogic [rule].
+*/
+func readJsonLogic(rule interface{}, options *compileOptions) (Node, error) {
+
+	var arguments []Node
+	var operation string
+	var value interface{}
+	var err error
+
+	switch rule.(type) {
+
+	case map[string]interface{}:
+
+		if len(rule.(map[string]interface{})) != 1 {
+			return nil, errors.New(fmt.Sprintf("A JsonLogic operation must have exactly one key, but found %d", len(rule.(map[string]interface{}))))
+		}
+
+		for key, argument := range rule.(map[string]interface{}) {
+			operation, value = key, argument
+		}
+
+		if operation == "var" {
+			return readJsonLogicVariable(value, options)
+		}
+
+		// a single argument doesn't need to be in an array.
+		if _, isArray := value.([]interface{}); !isArray {
+			value = []interface{}{value}
+		}
+
+		arguments, err = readJsonLogicArguments(value.([]interface{}), options)
+		if err != nil {
+			return nil, err
+		}
+		return readJsonLogicOperation(operation, arguments, options)
+
+	case []interface{}:
+
+		arguments, err = readJsonLogicArguments(rule.([]interface{}), options)
+		if err != nil {
+			return nil, err
+		}
+		return &ArrayNode{Elements: arguments}, nil
+
+	case nil:
+		return nil, errors.New("Unable to read null from JsonLogic")
+	}
+
+	return readJsonLogicLiteral(rule, options)
+}
+
+func readJsonLogicArguments(rules []interface{}, options *compileOptions) ([]Node, error) {
+
+	var ret []Node
+	var argument Node
+	var err error
+
+	for _, rule := range rules {
+
+		argument, err = readJsonLogic(rule, options)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, argument)
+	}
+	return ret, nil
+}
+
+/*
+	Returns the tree for the JsonLogic [operation] on the given [arguments].
+*/
+func readJsonLogicOperation(operation string, arguments []Node, options *compileOptions) (Node, error) {
+
+	var err error
+
+	switch operation {
+
+	case "and":
+		fallthrough
+	case "or":
+
+		if len(arguments) == 0 {
+			return nil, errors.New(fmt.Sprintf("JsonLogic operation '%s' needs at least one argument", operation))
+		}
+
+		if operation == "and" {
+			return And(arguments...), nil
+		}
+		return Or(arguments...), nil
+
+	case "if":
+		fallthrough
+	case "?:":
+		return readJsonLogicCondition(operation, arguments)
+
+	case "!":
+		err = checkJsonLogicArguments(operation, arguments, 1)
+		if err != nil {
+			return nil, err
+		}
+		return Not(arguments[0]), nil
+
+	case "!!":
+		err = checkJsonLogicArguments(operation, arguments, 1)
+		if err != nil {
+			return nil, err
+		}
+		return Not(Not(arguments[0])), nil
+
+	case "-":
+		if len(arguments) == 1 {
+			return Neg(arguments[0]), nil
+		}
+
+	case "~":
+		err = checkJsonLogicArguments(operation, arguments, 1)
+		if err != nil {
+			return nil, err
+		}
+		return &UnaryNode{Operator: BITWISE_NOT, Operand: arguments[0]}, nil
+
+	case "+":
+		fallthrough
+	case "*":
+
+		// addition and multiplication take any number of arguments, and a single argument is just that number.
+		if len(arguments) == 0 {
+			return nil, errors.New(fmt.Sprintf("JsonLogic operation '%s' needs at least one argument", operation))
+		}
+		if operation == "+" {
+			return chainNodes(PLUS, arguments), nil
+		}
+		return chainNodes(MULTIPLY, arguments), nil
+
+	case "<":
+		fallthrough
+	case "<=":
+
+		// `{"<": [a, b, c]}` is true if `b` is between `a` and `c`.
+		if len(arguments) == 3 {
+			if operation == "<" {
+				return And(Lt(arguments[0], arguments[1]), Lt(arguments[1], arguments[2])), nil
+			}
+			return And(Lte(arguments[0], arguments[1]), Lte(arguments[1], arguments[2])), nil
+		}
+
+	case "in":
+		err = checkJsonLogicArguments(operation, arguments, 2)
+		if err != nil {
+			return nil, err
+		}
+		return readJsonLogicMembership(arguments[0], arguments[1]), nil
+
+	case "===":
+		operation = "=="
+	case "!==":
+		operation = "!="
+	}
+
+	// prefix operations were handled above, so any other operation with a symbol is a binary operator.
+	for symbol, symbolOperation := range jsonLogicOperations {
+
+		if symbolOperation == operation && findOperatorPrecedenceForSymbol(symbol) != prefixPrecedence {
+
+			err = checkJsonLogicArguments(operation, arguments, 2)
+			if err != nil {
+				return nil, err
+			}
+			return &BinaryNode{Operator: symbol, Left: arguments[0], Right: arguments[1]}, nil
+		}
+	}
+
+	if function, found := options.contextFunctions[operation]; found {
+		return &FunctionNode{Name: operation, ContextFunction: function, Arguments: arguments}, nil
+	}
+	if function, found := options.functions[operation]; found {
+		return &FunctionNode{Name: operation, Function: function, Arguments: arguments}, nil
+	}
+
+	return nil, errors.New(fmt.Sprintf("Unknown JsonLogic operation '%s'", operation))
+}
+
+/*
+	Returns the tree for a `var`, which is either a name, or a name and the value to use if that parameter is nil.
+	A dotted name (such as "user.Age") is an accessor.
+*/
+func readJsonLogicVariable(value interface{}, options *compileOptions) (Node, error) {
+
+	var name, fallback Node
+	var arguments []interface{}
+	var path string
+	var err error
+
+	arguments, isArray := value.([]interface{})
+	if !isArray {
+		arguments = []interface{}{value}
+	}
+
+	if len(arguments) < 1 || len(arguments) > 2 {
+		return nil, errors.New(fmt.Sprintf("JsonLogic operation 'var' takes 1 or 2 arguments, but was given %d", len(arguments)))
+	}
+
+	switch arguments[0].(type) {
+	case string:
+		path = arguments[0].(string)
+	case json.Number:
+		path = arguments[0].(json.Number).String()
+	default:
+		return nil, errors.New(fmt.Sprintf("Unable to read a 'var' named by %T from JsonLogic", arguments[0]))
+	}
+
+	if path == "" {
+		return nil, errors.New("Unable to read a 'var' of all data from JsonLogic")
+	}
+
+	if strings.Contains(path, ".") {
+		name = Field(strings.Split(path, ".")...)
+	} else {
+		name = Var(path)
+	}
+
+	if len(arguments) == 1 {
+		return name, nil
+	}
+
+	fallback, err = readJsonLogic(arguments[1], options)
+	if err != nil {
+		return nil, err
+	}
+	return Coalesce(name, fallback), nil
+}
+
+/*
+	Returns the tree for an `if` (or `?:`), which may have any number of conditions, each followed by its result,
+	and finally an optional result for when no condition is true.
+*/
+func readJsonLogicCondition(operation string, arguments []Node) (Node, error) {
+
+	var ret *TernaryNode
+	var current *TernaryNode
+
+	if len(arguments) < 2 {
+		return nil, errors.New(fmt.Sprintf("JsonLogic operation '%s' needs at least 2 arguments, but was given %d", operation, len(arguments)))
+	}
+
+	for len(arguments) >= 2 {
+
+		next := &TernaryNode{Condition: arguments[0], Then: arguments[1]}
+		if ret == nil {
+			ret = next
+		} else {
+			current.Else = next
+		}
+
+		current = next
+		arguments = arguments[2:]
+	}
+
+	if len(arguments) == 1 {
+		current.Else = arguments[0]
+	}
+	return ret, nil
+}
+
+/*
+	Returns the tree for an `in`. Expressions need at least two elements to make an array,
+	so membership of an array with fewer is written as a comparison.
+*/
+func readJsonLogicMembership(needle Node, haystack Node) Node {
+
+	array, isArray := haystack.(*ArrayNode)
+	if !isArray {
+		return In(needle, haystack)
+	}
+
+	switch len(array.Elements) {
+	case 0:
+		return Bool(false)
+	case 1:
+		return Eq(needle, array.Elements[0])
+	}
+	return In(needle, haystack)
+}
+
+func readJsonLogicLiteral(value interface{}, options *compileOptions) (Node, error) {
+
+	var number json.Number
+	var integer int64
+	var unsigned uint64
+	var float float64
+	var decimal *big.Rat
+	var found bool
+	var err error
+
+	switch value.(type) {
+
+	case bool:
+		return Bool(value.(bool)), nil
+
+	case string:
+
+		// strings which are times are used as times, the same as they would be if the expression was parsed.
+		moment, found := tryParseTime(value.(string), options.timeFormats)
+		if found {
+			return Lit(moment), nil
+		}
+		return Str(value.(string)), nil
+
+	case json.Number:
+
+		number = value.(json.Number)
+
+		if options.numericMode == DECIMAL_NUMERICS {
+
+			decimal, found = new(big.Rat).SetString(number.String())
+			if !found {
+				return nil, errors.New(fmt.Sprintf("Unable to read number '%s' from JsonLogic", number))
+			}
+			return Lit(decimal), nil
+		}
+
+		integer, err = number.Int64()
+		if err == nil {
+			return Int(integer), nil
+		}
+
+		// integers too large for an int64 are unsigned when they're parsed with INTEGER_NUMERICS, so the same is done here.
+		if options.numericMode == INTEGER_NUMERICS {
+
+			unsigned, err = strconv.ParseUint(number.String(), 10, 64)
+			if err == nil {
+				return Lit(unsigned), nil
+			}
+		}
+
+		float, err = number.Float64()
+		if err != nil {
+			return nil, err
+		}
+		return Num(float), nil
+	}
+
+	return nil, errors.New(fmt.Sprintf("Unable to read %T from JsonLogic", value))
+}
+
+/*
+	Returns an error if the JsonLogic [operation] wasn't given exactly [count] [arguments].
+*/
+func checkJsonLogicArguments(operation string, arguments []Node, count int) error {
+
+	if len(arguments) != count {
+		return errors.New(fmt.Sprintf("JsonLogic operation '%s' takes %d arguments, but was given %d", operation, count, len(arguments)))
+	}
+	return nil
+}
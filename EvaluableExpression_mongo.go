@@ -0,0 +1,258 @@
+package govaluate
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+/*
+	Returns a MongoDB query filter (as used by `Find`) which matches the documents for which this expression is true.
+	Parameters are fields of the document, and accessors such as `user.Age` are dotted paths to embedded fields.
+	The filter is made of maps and slices, so can be used directly as (or converted to) a `bson.M`.
+
+	`&&` and `||` become `$and` and `$or`, `==` becomes a plain match, other comparators become `$ne`, `$gt`, `$gte`, `$lt` and `$lte`,
+	`=~` becomes `$regex`, `in` becomes `$in`, and `!` becomes `$not` (or `$nor`, for anything other than a single comparison).
+	Null coalescence such as `(foo ?? 0) > 1` is written using `$exists`, treating a missing field as nil.
+
+	Returns an error for anything a filter can't express, such as arithmetic on fields, comparisons between two fields,
+	ternaries, and function or method calls.
+*/
+func (this EvaluableExpression) ToMongoQuery() (map[string]interface{}, error) {
+
+	if this.evaluationStages == nil {
+		return map[string]interface{}{}, nil
+	}
+	return writeMongoFilter(this.evaluationStages)
+}
+
+/*
+	Returns the filter which matches documents for which the tree of stages under [stage] is true.
+*/
+func writeMongoFilter(stage *evaluationStage) (map[string]interface{}, error) {
+
+	var filter map[string]interface{}
+	var filters []interface{}
+	var err error
+
+	stage = skipNoopStages(stage)
+	if stage == nil {
+		return nil, errors.New("Unable to write an empty expression as a Mongo query")
+	}
+
+	switch stage.symbol {
+
+	case AND:
+		fallthrough
+	case OR:
+
+		for _, operand := range flattenLogicalStages(stage, stage.symbol) {
+
+			filter, err = writeMongoFilter(operand)
+			if err != nil {
+				return nil, err
+			}
+			filters = append(filters, filter)
+		}
+
+		if stage.symbol == AND {
+			return map[string]interface{}{"$and": filters}, nil
+		}
+		return map[string]interface{}{"$or": filters}, nil
+
+	case INVERT:
+
+		filter, err = writeMongoFilter(stage.rightStage)
+		if err != nil {
+			return nil, err
+		}
+		return invertMongoFilter(filter), nil
+
+	case EQ:
+		fallthrough
+	case NEQ:
+		fallthrough
+	case GT:
+		fallthrough
+	case GTE:
+		fallthrough
+	case LT:
+		fallthrough
+	case LTE:
+		fallthrough
+	case REQ:
+		fallthrough
+	case NREQ:
+		fallthrough
+	case IN:
+		return writeMongoComparison(stage)
+
+	case VALUE:
+		fallthrough
+	case ACCESS:
+		fallthrough
+	case COALESCE:
+
+		// a boolean on its own is the same as comparing it to true.
+		if isFilterField(stage) {
+			return writeMongoCondition(&evaluationStage{symbol: EQ, operator: equalStage}, stage, true, false)
+		}
+
+	case LITERAL:
+
+		if stage.value == true {
+			return map[string]interface{}{}, nil
+		}
+		if stage.value == false {
+			return map[string]interface{}{"$nor": []interface{}{map[string]interface{}{}}}, nil
+		}
+		return nil, errors.New(fmt.Sprintf("Unable to write literal '%v' as a Mongo query", stage.value))
+
+	case FUNCTIONAL:
+		return nil, errors.New(fmt.Sprintf("Unable to write function '%s' as a Mongo query", stage.name))
+	}
+
+	return nil, errors.New(fmt.Sprintf("Unable to write operator '%v' as a Mongo query", stage.symbol))
+}
+
+/*
+	Writes a comparison between a field and a literal, given the comparator [stage].
+*/
+func writeMongoComparison(stage *evaluationStage) (map[string]interface{}, error) {
+
+	var field, literal *evaluationStage
+	var value interface{}
+	var flipped bool
+	var err error
+
+	field, literal, flipped, err = findFilterComparison(stage, "a Mongo query")
+	if err != nil {
+		return nil, err
+	}
+
+	if stage.symbol == IN {
+		value, err = findFilterArray(stage.rightStage, "a Mongo query")
+	} else {
+		value, err = findFilterLiteral(literal, "a Mongo query")
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return writeMongoCondition(stage, field, value, flipped)
+}
+
+/*
+	Writes the condition that the [field] compares to [value] according to the comparator [stage].
+	If [flipped] is true, the comparison was written with the value on the left.
+*/
+func writeMongoCondition(stage *evaluationStage, field *evaluationStage, value interface{}, flipped bool) (map[string]interface{}, error) {
+
+	var condition map[string]interface{}
+	var path string
+	var matchesMissing bool
+	var err error
+
+	condition = findMongoOperator(stage.symbol, flipped, value)
+
+	if field.symbol != COALESCE {
+		return map[string]interface{}{findFilterPath(field): simplifyMongoCondition(condition)}, nil
+	}
+
+	// a coalesced field compares its fallback when it's missing, which is either always or never true.
+	path = findFilterPath(skipNoopStages(field.leftStage))
+
+	matchesMissing, err = matchesFilterFallback(stage, field, value, flipped, "a Mongo query")
+	if err != nil {
+		return nil, err
+	}
+
+	if matchesMissing {
+		return map[string]interface{}{
+			"$or": []interface{}{
+				map[string]interface{}{path: simplifyMongoCondition(condition)},
+				map[string]interface{}{path: map[string]interface{}{"$exists": false}},
+			},
+		}, nil
+	}
+
+	condition["$exists"] = true
+	return map[string]interface{}{path: condition}, nil
+}
+
+/*
+	Returns the operator document for a field compared to [value] with the given [symbol], such as {"$gt": 5}.
+	If [flipped] is true, the comparison was written with the value on the left, so ordering comparators are reversed.
+*/
+func findMongoOperator(symbol OperatorSymbol, flipped bool, value interface{}) map[string]interface{} {
+
+	if flipped {
+		symbol = flipComparator(symbol)
+	}
+
+	switch symbol {
+	case NEQ:
+		return map[string]interface{}{"$ne": value}
+	case GT:
+		return map[string]interface{}{"$gt": value}
+	case GTE:
+		return map[string]interface{}{"$gte": value}
+	case LT:
+		return map[string]interface{}{"$lt": value}
+	case LTE:
+		return map[string]interface{}{"$lte": value}
+	case REQ:
+		return map[string]interface{}{"$regex": value}
+	case NREQ:
+		return map[string]interface{}{"$not": map[string]interface{}{"$regex": value}}
+	case IN:
+		return map[string]interface{}{"$in": value}
+	}
+	return map[string]interface{}{"$eq": value}
+}
+
+/*
+	Returns the given operator document as it would usually be written; equality is written as just the value.
+*/
+func simplifyMongoCondition(condition map[string]interface{}) interface{} {
+
+	var value interface{}
+	var found bool
+
+	value, found = condition["$eq"]
+	if found && len(condition) == 1 {
+		return value
+	}
+	return condition
+}
+
+/*
+	Returns a filter which matches every document that the given [filter] does not.
+	A filter on a single field is inverted with `$not` (or `$ne`, for equality). Any other filter is inverted with `$nor`.
+*/
+func invertMongoFilter(filter map[string]interface{}) map[string]interface{} {
+
+	var condition map[string]interface{}
+	var inverted interface{}
+	var isCondition bool
+
+	for path, value := range filter {
+
+		if len(filter) != 1 || strings.HasPrefix(path, "$") {
+			break
+		}
+
+		condition, isCondition = value.(map[string]interface{})
+		if !isCondition {
+			return map[string]interface{}{path: map[string]interface{}{"$ne": value}}
+		}
+
+		inverted, isCondition = condition["$not"]
+		if isCondition && len(condition) == 1 {
+			return map[string]interface{}{path: inverted}
+		}
+		return map[string]interface{}{path: map[string]interface{}{"$not": condition}}
+	}
+
+	return map[string]interface{}{"$nor": []interface{}{filter}}
+}
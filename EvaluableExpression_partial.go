@@ -0,0 +1,251 @@
+package govaluate
+
+import (
+	"context"
+	"time"
+)
+
+/*
+	The state of a single call to `PartialEval`.
+*/
+type partialEvaluator struct {
+	expression *EvaluableExpression
+	parameters sanitizedParameters
+}
+
+/*
+	Evaluates every part of this expression which depends only on the given [known] parameters, leaving the rest to be evaluated later.
+	A parameter is known if [known] gives a value for it without an error.
+
+	If nothing is left to evaluate, returns a nil expression and the result (or the error) of evaluating this expression.
+	Otherwise, returns a smaller expression, with the same settings as this one, in which each part that could be evaluated
+	has been replaced by its value. Evaluating it with the remaining parameters gives the same result as evaluating this expression
+	with both the known and the remaining parameters.
+
+	Besides parts whose operands are all known, some parts need only one known operand: "a && b" is false if "a" is known to be false,
+	"a || b" is true if "a" is known to be true, and "a ?? b" or "a ? b : c" are replaced by whichever side "a" chooses.
+	However, a part is left as it is if:
+
+	- it calls a function or a method, since these may not give the same result each time they're called. Their arguments may still be evaluated.
+	- it fails, since it might not be evaluated at all once the rest of the expression is known (for instance, if it's on the right of "&&").
+	- its value can't be written as a literal, such as an array, a struct, or a time. A known parameter with such a value is kept as a parameter
+	  wherever the parts around it can't be evaluated, so it must be given again when the remaining expression is evaluated.
+*/
+func (this EvaluableExpression) PartialEval(known Parameters) (*EvaluableExpression, interface{}, error) {
+
+	var evaluator *partialEvaluator
+	var root *evaluationStage
+	var value interface{}
+	var ret *EvaluableExpression
+	var constant bool
+	var err error
+
+	if this.evaluationStages == nil {
+		return nil, nil, nil
+	}
+
+	if known == nil {
+		known = DUMMY_PARAMETERS
+	}
+
+	evaluator = &partialEvaluator{
+		expression: &this,
+		parameters: sanitizedParameters{
+			orig:        known,
+			numericMode: this.numericMode,
+			ctx:         context.Background(),
+			checksTypes: this.ChecksTypes,
+		},
+	}
+
+	root, constant = evaluator.fold(this.evaluationStages)
+	if constant {
+		value, err = this.evaluateStage(root, &evaluator.parameters)
+		return nil, value, err
+	}
+
+	ret, err = compileNode(buildNode(root), this.findCompileOptions())
+	if err != nil {
+		return nil, nil, err
+	}
+	return ret, nil, nil
+}
+
+/*
+	Returns options which compile an expression with the same settings as this one.
+*/
+func (this EvaluableExpression) findCompileOptions() *compileOptions {
+
+	var ret *compileOptions
+
+	ret = newCompileOptions(nil)
+	ret.numericMode = this.numericMode
+	ret.decimalRounding = this.decimalRounding
+	ret.operators = this.operators
+	ret.budget = this.Budget
+	ret.checksTypes = this.ChecksTypes
+	ret.queryDateFormat = this.QueryDateFormat
+
+	if this.closure != nil {
+		ret.engine = CLOSURE_ENGINE
+	}
+	if this.program != nil {
+		ret.engine = BYTECODE_ENGINE
+	}
+	return ret
+}
+
+/*
+	Returns a copy of the tree under [stage] in which every part that can be evaluated has been replaced by a literal,
+	and whether the whole tree depends only on known parameters.
+	A tree which depends only on known parameters can be evaluated as-is, even if it couldn't be replaced by a literal.
+*/
+func (this *partialEvaluator) fold(stage *evaluationStage) (*evaluationStage, bool) {
+
+	var ret *evaluationStage
+	var copied evaluationStage
+	var leftConstant, rightConstant bool
+	var err error
+
+	if stage == nil {
+		return nil, true
+	}
+
+	copied = *stage
+	ret = &copied
+
+	switch stage.symbol {
+
+	case LITERAL:
+		return stage, true
+
+	case VALUE:
+
+		if stage.name == "" {
+			return stage, false
+		}
+
+		_, err = this.parameters.orig.Get(stage.name)
+		if err != nil {
+			return stage, false
+		}
+		return this.replace(stage), true
+
+	case ACCESS:
+
+		// method calls might not give the same result each time, so only their arguments are evaluated.
+		if stage.rightStage != nil {
+			ret.rightStage, _ = this.fold(stage.rightStage)
+			return ret, false
+		}
+
+		_, err = this.parameters.orig.Get(stage.path[0])
+		if err != nil {
+			return stage, false
+		}
+		return this.replace(stage), true
+
+	case FUNCTIONAL:
+		ret.rightStage, _ = this.fold(stage.rightStage)
+		return ret, false
+	}
+
+	ret.leftStage, leftConstant = this.fold(stage.leftStage)
+	ret.rightStage, rightConstant = this.fold(stage.rightStage)
+
+	// "a ? b : c" is only a ternary while its left is still the "a ? b". Otherwise it chooses in the same way as "??".
+	if ret.symbol == TERNARY_FALSE && (ret.leftStage == nil || ret.leftStage.symbol != TERNARY_TRUE) {
+		ret.symbol = COALESCE
+	}
+
+	if leftConstant && rightConstant {
+		return this.replace(ret), true
+	}
+
+	if leftConstant && ret.isShortCircuitable() {
+		return this.shortCircuit(ret)
+	}
+	return ret, false
+}
+
+/*
+	Returns what the given short-circuiting [stage] becomes when only its left side is known,
+	and whether it then depends only on known parameters.
+*/
+func (this *partialEvaluator) shortCircuit(stage *evaluationStage) (*evaluationStage, bool) {
+
+	var left interface{}
+	var err error
+
+	left, err = this.expression.evaluateStage(stage.leftStage, &this.parameters)
+	if err != nil {
+		return stage, false
+	}
+
+	// these are the cases in which evaluation stops before the stage's operator is called.
+	switch stage.symbol {
+	case AND:
+		if left == false {
+			return this.replace(stage), true
+		}
+	case OR:
+		if left == true {
+			return this.replace(stage), true
+		}
+	case TERNARY_TRUE:
+		if left == false {
+			return this.replace(stage), true
+		}
+	case TERNARY_FALSE:
+		fallthrough
+	case COALESCE:
+		if left != nil {
+			return this.replace(stage), true
+		}
+	}
+
+	// otherwise, the result is whatever the right side gives. None of these operators can be replaced, so that can't be changed.
+	switch stage.symbol {
+	case TERNARY_TRUE:
+		if left == true {
+			return stage.rightStage, false
+		}
+	case TERNARY_FALSE:
+		fallthrough
+	case COALESCE:
+		return stage.rightStage, false
+	}
+	return stage, false
+}
+
+/*
+	Returns a literal stage holding the value of the given [stage], which must depend only on known parameters.
+	If it fails, or its value can't be written as a literal, returns the given [stage].
+*/
+func (this *partialEvaluator) replace(stage *evaluationStage) *evaluationStage {
+
+	var value interface{}
+	var err error
+
+	value, err = this.expression.evaluateStage(stage, &this.parameters)
+	if err != nil {
+		return stage
+	}
+
+	// time literals are compared as numbers, which times given as parameters aren't.
+	switch value.(type) {
+	case time.Time:
+		return stage
+	}
+
+	_, err = literalToken(value, this.expression.numericMode)
+	if err != nil {
+		return stage
+	}
+
+	return &evaluationStage{
+		symbol:   LITERAL,
+		operator: makeLiteralStage(value),
+		value:    value,
+	}
+}
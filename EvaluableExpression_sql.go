@@ -3,7 +3,9 @@ package govaluate
 import (
 	"errors"
 	"fmt"
+	"math/big"
 	"regexp"
+	"strings"
 	"time"
 )
 
@@ -11,157 +13,396 @@ import (
 	Returns a string representing this expression as if it were written in SQL.
 	This function assumes that all parameters exist within the same table, and that the table essentially represents
 	a serialized object of some sort (e.g., hibernate).
-	If your data model is more normalized, you may need to consider iterating through each actual token given by `Tokens()`
-	to create your query.
+	If your data model is more normalized, you may need to consider walking the tree given by `AST()` to create your query.
 
 	Boolean values are considered to be "1" for true, "0" for false.
+	Ternaries are written as `CASE WHEN ... THEN ... ELSE ... END`, and null coalescence as `COALESCE(...)`.
+	Functions and accessors can't be written, unless a dialect which translates them is used; see SQLDialect.
 
 	Times are formatted according to this.QueryDateFormat.
 */
 func (this EvaluableExpression) ToSQLQuery() (string, error) {
+	return this.ToSQLQueryWithDialect("default")
+}
 
-	var stream *tokenStream
-	var transactions *expressionOutputStream
-	var transaction string
+/*
+	Similar to [ToSQLQuery], except that the query is written in the SQL dialect registered with the given [name]. See SQLDialect.
+	Returns an error if no dialect is registered with that name.
+*/
+func (this EvaluableExpression) ToSQLQueryWithDialect(name string) (string, error) {
+
+	var ret string
 	var err error
 
-	stream = newTokenStream(this.tokens)
-	transactions = new(expressionOutputStream)
+	ret, _, err = this.writeSQLQuery(name, false)
+	return ret, err
+}
 
-	for stream.hasNext() {
+/*
+	Similar to [ToSQLQueryWithDialect], except that string, regex and time literals are not written into the query.
+	Instead, each is written as a placeholder (such as `?` or `$1`, depending on the dialect), and its value is returned as a bind argument,
+	in the same order as the placeholders. The query and arguments can be given directly to `database/sql`:
 
-		transaction, err = this.findNextSQLString(stream, transactions)
-		if err != nil {
-			return "", err
-		}
+		query, arguments, err := expression.ToSQLQueryWithArguments("postgres")
+		rows, err := db.Query("SELECT * FROM users WHERE "+query, arguments...)
+
+	Times are given as time.Time, and regexes as the string of their pattern.
+*/
+func (this EvaluableExpression) ToSQLQueryWithArguments(name string) (string, []interface{}, error) {
+	return this.writeSQLQuery(name, true)
+}
+
+/*
+	The state of a query being written by `writeSQLStage`.
+*/
+type sqlQuery struct {
+	dialect         SQLDialect
+	queryDateFormat string
+
+	// if true, literals are written as placeholders, and their values are collected into [arguments].
+	parameterized bool
+	arguments     []interface{}
+}
 
-		transactions.add(transaction)
+/*
+	Returns the SQL for a literal [value], which is [inline] unless this query is parameterized.
+*/
+func (this *sqlQuery) writeLiteral(value interface{}, inline string) string {
+
+	if !this.parameterized {
+		return inline
 	}
 
-	return transactions.createString(" "), nil
+	this.arguments = append(this.arguments, value)
+	return this.dialect.placeholder(len(this.arguments))
 }
 
-func (this EvaluableExpression) findNextSQLString(stream *tokenStream, transactions *expressionOutputStream) (string, error) {
+func (this EvaluableExpression) writeSQLQuery(name string, parameterized bool) (string, []interface{}, error) {
 
-	var token ExpressionToken
+	var query *sqlQuery
+	var root *evaluationStage
 	var ret string
+	var err error
 
-	token = stream.next()
+	query = &sqlQuery{
+		parameterized:   parameterized,
+		queryDateFormat: this.QueryDateFormat,
+	}
+
+	query.dialect, err = FindSQLDialect(name)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// the planned stages have their literals computed in advance, so the query is written from freshly-planned ones instead.
+	root, err = planStructure(this.tokens, 0)
+	if err != nil {
+		return "", nil, err
+	}
+
+	ret, err = query.writeSQLStage(root)
+	if err != nil {
+		return "", nil, err
+	}
+	return ret, query.arguments, nil
+}
+
+/*
+	Returns the SQL for the tree of stages under [stage].
+*/
+func (this *sqlQuery) writeSQLStage(stage *evaluationStage) (string, error) {
+
+	var left, right, operator string
+	var err error
 
-	switch token.Kind {
+	if stage == nil {
+		return "", errors.New("Unable to write an empty expression as SQL")
+	}
 
-	case STRING:
-		ret = fmt.Sprintf("'%v'", token.Value)
-	case PATTERN:
-		ret = fmt.Sprintf("'%s'", token.Value.(*regexp.Regexp).String())
-	case TIME:
-		ret = fmt.Sprintf("'%s'", token.Value.(time.Time).Format(this.QueryDateFormat))
+	switch stage.symbol {
 
-	case LOGICALOP:
-		switch logicalSymbols[token.Value.(string)] {
+	case VALUE:
+		return this.writeSQLColumn([]string{stage.name}, false)
+	case ACCESS:
+		return this.writeSQLColumn(stage.path, stage.rightStage != nil)
+	case FUNCTIONAL:
+		return this.writeSQLFunction(stage)
+	case LITERAL:
+		return this.writeSQLLiteral(stage.value)
 
-		case AND:
-			ret = "AND"
-		case OR:
-			ret = "OR"
+	case NOOP:
+		right, err = this.writeSQLStage(stage.rightStage)
+		if err != nil {
+			return "", err
 		}
+		return fmt.Sprintf("( %s )", right), nil
 
-	case BOOLEAN:
-		if token.Value.(bool) {
-			ret = "1"
-		} else {
-			ret = "0"
+	case INVERT:
+		right, err = this.writeSQLStage(stage.rightStage)
+		if err != nil {
+			return "", err
 		}
+		return fmt.Sprintf("NOT %s", right), nil
 
-	case VARIABLE:
-		ret = fmt.Sprintf("[%s]", token.Value.(string))
-
-	case NUMERIC:
-		ret = fmt.Sprintf("%g", token.Value.(float64))
-
-	case COMPARATOR:
-		switch comparatorSymbols[token.Value.(string)] {
-
-		case EQ:
-			ret = "="
-		case NEQ:
-			ret = "<>"
-		case REQ:
-			ret = "RLIKE"
-		case NREQ:
-			ret = "NOT RLIKE"
-		default:
-			ret = fmt.Sprintf("%s", token.Value.(string))
+	case NEGATE:
+		fallthrough
+	case BITWISE_NOT:
+		right, err = this.writeSQLStage(stage.rightStage)
+		if err != nil {
+			return "", err
 		}
+		return fmt.Sprintf("%s%s", stage.symbol.String(), right), nil
 
-	case TERNARY:
+	case IN:
+		return this.writeSQLMembership(stage)
 
-		switch ternarySymbols[token.Value.(string)] {
+	case TERNARY_TRUE:
+		fallthrough
+	case TERNARY_FALSE:
+		return this.writeSQLTernary(stage)
 
-		case COALESCE:
+	case SEPARATE:
+		return "", errors.New("Arrays can only be written as SQL on the right side of 'in'")
+	}
 
-			left := transactions.rollback()
-			right, err := this.findNextSQLString(stream, transactions)
-			if err != nil {
-				return "", err
-			}
+	left, err = this.writeSQLStage(stage.leftStage)
+	if err != nil {
+		return "", err
+	}
+
+	right, err = this.writeSQLStage(stage.rightStage)
+	if err != nil {
+		return "", err
+	}
 
-			ret = fmt.Sprintf("COALESCE(%v, %v)", left, right)
-		case TERNARY_TRUE:
-			fallthrough
-		case TERNARY_FALSE:
-			return "", errors.New("Ternary operators are unsupported in SQL output")
+	switch stage.symbol {
+
+	case EXPONENT:
+		return fmt.Sprintf(this.dialect.PowerFormat, left, right), nil
+	case MODULUS:
+		return fmt.Sprintf(this.dialect.ModulusFormat, left, right), nil
+	case COALESCE:
+		return fmt.Sprintf("COALESCE(%s, %s)", left, right), nil
+
+	case AND:
+		operator = "AND"
+	case OR:
+		operator = "OR"
+	case EQ:
+		operator = "="
+	case NEQ:
+		operator = "<>"
+	case REQ:
+		operator = this.dialect.RegexOperator
+	case NREQ:
+		operator = this.dialect.NotRegexOperator
+	default:
+		operator = stage.symbol.String()
+	}
+
+	return fmt.Sprintf("%s %s %s", left, operator, right), nil
+}
+
+func (this *sqlQuery) writeSQLLiteral(value interface{}) (string, error) {
+
+	var format string
+
+	switch value.(type) {
+
+	case string:
+		return this.writeLiteral(value, quoteSQLString(value.(string))), nil
+
+	case *regexp.Regexp:
+		pattern := value.(*regexp.Regexp).String()
+		return this.writeLiteral(pattern, quoteSQLString(pattern)), nil
+
+	case time.Time:
+		format = this.dialect.DateFormat
+		if format == "" {
+			format = this.queryDateFormat
 		}
-	case PREFIX:
-		switch prefixSymbols[token.Value.(string)] {
+		return this.writeLiteral(value, quoteSQLString(value.(time.Time).Format(format))), nil
 
-		case INVERT:
-			ret = fmt.Sprintf("NOT")
-		default:
+	case bool:
+		if value.(bool) {
+			return this.dialect.True, nil
+		}
+		return this.dialect.False, nil
 
-			right, err := this.findNextSQLString(stream, transactions)
-			if err != nil {
-				return "", err
-			}
+	case float64:
+		return fmt.Sprintf("%g", value.(float64)), nil
+	case *big.Rat:
+		return formatDecimal(value.(*big.Rat)), nil
+	}
+
+	return fmt.Sprintf("%v", value), nil
+}
+
+/*
+	Writes the parameter or accessor with the given [path], using the dialect's column mapping if it has one.
+	Method calls can't be written as SQL.
+*/
+func (this *sqlQuery) writeSQLColumn(path []string, isMethodCall bool) (string, error) {
+
+	var ret string
+	var err error
+
+	if isMethodCall {
+		return "", errors.New(fmt.Sprintf("Unable to write method call '%s' as SQL", strings.Join(path, ".")))
+	}
+
+	if this.dialect.Columns != nil {
 
-			ret = fmt.Sprintf("%s%s", token.Value.(string), right)
+		ret, err = this.dialect.Columns(path)
+		if err != nil || ret != "" {
+			return ret, err
 		}
-	case MODIFIER:
+	}
+
+	if len(path) > 1 {
+		return "", errors.New(fmt.Sprintf("Unable to write accessor '%s' as SQL", strings.Join(path, ".")))
+	}
+	return this.dialect.quoteIdentifier(path[0]), nil
+}
+
+/*
+	Writes a call to a function using the dialect's translation of it, given the FUNCTIONAL [stage].
+*/
+func (this *sqlQuery) writeSQLFunction(stage *evaluationStage) (string, error) {
+
+	var function SQLFunction
+	var arguments []string
+	var argument string
+	var parameters *evaluationStage
+	var found bool
+	var err error
+
+	function, found = this.dialect.Functions[stage.name]
+	if !found {
+		return "", errors.New(fmt.Sprintf("Unable to write function '%s' as SQL", stage.name))
+	}
+
+	// arguments are always planned within parenthesis, which may be empty.
+	parameters = stage.rightStage
+	if parameters != nil && parameters.symbol == NOOP {
+		parameters = parameters.rightStage
+	}
 
-		switch modifierSymbols[token.Value.(string)] {
+	arguments = []string{}
+	if parameters != nil {
 
-		case EXPONENT:
+		for _, parameter := range flattenSeparatedStages(parameters) {
 
-			left := transactions.rollback()
-			right, err := this.findNextSQLString(stream, transactions)
+			argument, err = this.writeSQLStage(parameter)
 			if err != nil {
 				return "", err
 			}
+			arguments = append(arguments, argument)
+		}
+	}
+
+	return function(arguments)
+}
+
+/*
+	Writes `x IN (a, b, c)`, given the IN [stage].
+*/
+func (this *sqlQuery) writeSQLMembership(stage *evaluationStage) (string, error) {
+
+	var elements []string
+	var left, element string
+	var list *evaluationStage
+	var err error
+
+	left, err = this.writeSQLStage(stage.leftStage)
+	if err != nil {
+		return "", err
+	}
+
+	list = stage.rightStage
+	if list != nil && list.symbol == NOOP {
+		list = list.rightStage
+	}
+
+	if list == nil {
+		return "", errors.New("Unable to write an empty array as SQL")
+	}
+
+	for _, member := range flattenSeparatedStages(list) {
+
+		element, err = this.writeSQLStage(member)
+		if err != nil {
+			return "", err
+		}
+		elements = append(elements, element)
+	}
+
+	return fmt.Sprintf("%s IN (%s)", left, strings.Join(elements, ", ")), nil
+}
+
+/*
+	Writes a ternary as `CASE WHEN ... THEN ... ELSE ... END`.
+	Without an else part, the result is NULL when the condition is false, the same as the ternary's result of nil.
+	A false-ternary without a true-ternary on its left (such as `a : b`) gives its left side unless that's nil, and so is a COALESCE.
+*/
+func (this *sqlQuery) writeSQLTernary(stage *evaluationStage) (string, error) {
 
-			ret = fmt.Sprintf("POW(%s, %s)", left, right)
-		case MODULUS:
+	var condition, then, otherwise string
+	var err error
+
+	if stage.symbol == TERNARY_FALSE {
 
-			left := transactions.rollback()
-			right, err := this.findNextSQLString(stream, transactions)
+		otherwise, err = this.writeSQLStage(stage.rightStage)
+		if err != nil {
+			return "", err
+		}
+
+		if stage.leftStage == nil || stage.leftStage.symbol != TERNARY_TRUE {
+
+			condition, err = this.writeSQLStage(stage.leftStage)
 			if err != nil {
 				return "", err
 			}
-
-			ret = fmt.Sprintf("MOD(%s, %s)", left, right)
-		default:
-			ret = fmt.Sprintf("%s", token.Value.(string))
+			return fmt.Sprintf("COALESCE(%s, %s)", condition, otherwise), nil
 		}
-	case CLAUSE:
-		ret = "("
-	case CLAUSE_CLOSE:
-		ret = ")"
-	case SEPARATOR:
-		ret = ","
 
-	default:
-		errorMsg := fmt.Sprintf("Unrecognized query token '%s' of kind '%s'", token.Value, token.Kind)
-		return "", errors.New(errorMsg)
+		stage = stage.leftStage
 	}
 
-	return ret, nil
+	condition, err = this.writeSQLStage(stage.leftStage)
+	if err != nil {
+		return "", err
+	}
+
+	then, err = this.writeSQLStage(stage.rightStage)
+	if err != nil {
+		return "", err
+	}
+
+	if otherwise == "" {
+		return fmt.Sprintf("CASE WHEN %s THEN %s END", condition, then), nil
+	}
+	return fmt.Sprintf("CASE WHEN %s THEN %s ELSE %s END", condition, then, otherwise), nil
+}
+
+/*
+	Returns every element of the array built by the given [stage], in order. If it isn't a SEPARATE, it's the only element.
+*/
+func flattenSeparatedStages(stage *evaluationStage) []*evaluationStage {
+
+	if stage.symbol != SEPARATE {
+		return []*evaluationStage{stage}
+	}
+
+	if stage.leftStage != nil && stage.leftStage.symbol == SEPARATE {
+		return append(flattenSeparatedStages(stage.leftStage), stage.rightStage)
+	}
+	return []*evaluationStage{stage.leftStage, stage.rightStage}
+}
+
+/*
+	Single-quotes the given string for SQL, escaping any quotes within it by doubling them.
+*/
+func quoteSQLString(value string) string {
+	return "'" + strings.Replace(value, "'", "''", -1) + "'"
 }
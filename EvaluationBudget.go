@@ -0,0 +1,161 @@
+package govaluate
+
+import (
+	"fmt"
+)
+
+/*
+	Represents limits on how much work a single evaluation of an expression may do.
+	Useful when expressions come from untrusted sources, and a single evaluation must not be able to use unbounded time or memory.
+
+	A limit of zero (or less) means that there is no limit. The zero value of this struct places no limits at all.
+*/
+type EvaluationBudget struct {
+
+	/*
+		The maximum number of stages which may be evaluated.
+		Every operator, literal, parameter, accessor, and function call is one stage.
+	*/
+	MaxStages int
+
+	/*
+		The maximum number of calls to user-defined functions.
+	*/
+	MaxFunctionCalls int
+
+	/*
+		The maximum length (in bytes) of any string produced by concatenation with `+`.
+	*/
+	MaxStringLength int
+
+	/*
+		The maximum number of elements in any array produced by the separator `,`.
+	*/
+	MaxArrayLength int
+}
+
+/*
+	Represents one of the limits in an EvaluationBudget.
+*/
+type BudgetLimit int
+
+const (
+	STAGE_BUDGET BudgetLimit = iota
+	FUNCTION_CALL_BUDGET
+	STRING_LENGTH_BUDGET
+	ARRAY_LENGTH_BUDGET
+)
+
+/*
+	Returned by an evaluation which exceeded one of the limits in its expression's EvaluationBudget.
+*/
+type ErrBudgetExceeded struct {
+
+	// which limit was exceeded.
+	Limit BudgetLimit
+
+	// the value of that limit in the EvaluationBudget.
+	Max int
+}
+
+/*
+	Counts how much of an EvaluationBudget has been used by a single evaluation.
+*/
+type budgetUsage struct {
+	stages        int
+	functionCalls int
+}
+
+/*
+	Returns a string that describes this BudgetLimit.
+*/
+func (this BudgetLimit) String() string {
+
+	switch this {
+	case STAGE_BUDGET:
+		return "STAGE_BUDGET"
+	case FUNCTION_CALL_BUDGET:
+		return "FUNCTION_CALL_BUDGET"
+	case STRING_LENGTH_BUDGET:
+		return "STRING_LENGTH_BUDGET"
+	case ARRAY_LENGTH_BUDGET:
+		return "ARRAY_LENGTH_BUDGET"
+	}
+
+	return "UNKNOWN"
+}
+
+func (this ErrBudgetExceeded) Error() string {
+
+	var description string
+
+	switch this.Limit {
+	case STAGE_BUDGET:
+		description = "number of evaluated stages"
+	case FUNCTION_CALL_BUDGET:
+		description = "number of function calls"
+	case STRING_LENGTH_BUDGET:
+		description = "length of a concatenated string"
+	case ARRAY_LENGTH_BUDGET:
+		description = "length of an array"
+	default:
+		description = this.Limit.String()
+	}
+
+	return fmt.Sprintf("Evaluation budget exceeded: %s is limited to %d", description, this.Max)
+}
+
+/*
+	Returns true if this budget has any limits at all.
+*/
+func (this EvaluationBudget) isLimited() bool {
+	return this.MaxStages > 0 ||
+		this.MaxFunctionCalls > 0 ||
+		this.MaxStringLength > 0 ||
+		this.MaxArrayLength > 0
+}
+
+/*
+	Records that the given [stage] is about to be evaluated,
+	and returns an error if doing so would exceed this budget.
+*/
+func (this EvaluationBudget) spend(stage *evaluationStage, usage *budgetUsage) error {
+
+	usage.stages++
+	if this.MaxStages > 0 && usage.stages > this.MaxStages {
+		return ErrBudgetExceeded{STAGE_BUDGET, this.MaxStages}
+	}
+
+	if stage.symbol != FUNCTIONAL {
+		return nil
+	}
+
+	usage.functionCalls++
+	if this.MaxFunctionCalls > 0 && usage.functionCalls > this.MaxFunctionCalls {
+		return ErrBudgetExceeded{FUNCTION_CALL_BUDGET, this.MaxFunctionCalls}
+	}
+	return nil
+}
+
+/*
+	Returns an error if the given [result] of evaluating a stage with the given [symbol] is larger than this budget allows.
+*/
+func (this EvaluationBudget) checkResult(symbol OperatorSymbol, result interface{}) error {
+
+	switch symbol {
+	case PLUS:
+
+		value, ok := result.(string)
+		if ok && this.MaxStringLength > 0 && len(value) > this.MaxStringLength {
+			return ErrBudgetExceeded{STRING_LENGTH_BUDGET, this.MaxStringLength}
+		}
+
+	case SEPARATE:
+
+		value, ok := result.([]interface{})
+		if ok && this.MaxArrayLength > 0 && len(value) > this.MaxArrayLength {
+			return ErrBudgetExceeded{ARRAY_LENGTH_BUDGET, this.MaxArrayLength}
+		}
+	}
+	return nil
+}
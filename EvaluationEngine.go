@@ -0,0 +1,48 @@
+package govaluate
+
+/*
+	Represents how an expression is evaluated. Every engine gives the same results and errors for the same expression;
+	they differ only in how much work is done ahead of time (when the expression is compiled) to make each evaluation faster.
+*/
+type EvaluationEngine int
+
+const (
+
+	/*
+		The default engine. Each evaluation walks the planned tree of stages, checking the types of each stage's operands as it goes.
+	*/
+	STAGE_ENGINE EvaluationEngine = iota
+
+	/*
+		The planned tree of stages is compiled into a tree of Go closures, each specialized to its operator.
+		Type checks which can be shown to always pass (such as checking that the result of a comparison is a bool) are removed,
+		and common operations on numbers, strings and bools are done without going through the general operators.
+		Compiling takes longer, but is usually worth it for expressions which are evaluated many times.
+	*/
+	CLOSURE_ENGINE
+
+	/*
+		The planned tree of stages is compiled into a flat sequence of instructions, which are run by a small stack machine.
+		`&&`, `||`, `??` and ternaries are compiled into jumps, which skip the instructions for whichever side isn't needed.
+		Type checks are removed and operations are done directly in the same cases as CLOSURE_ENGINE,
+		and each expression reuses its stacks between evaluations, so evaluation itself allocates nothing beyond the values it produces.
+	*/
+	BYTECODE_ENGINE
+)
+
+/*
+	Returns a string that describes this EvaluationEngine.
+*/
+func (this EvaluationEngine) String() string {
+
+	switch this {
+	case STAGE_ENGINE:
+		return "STAGE_ENGINE"
+	case CLOSURE_ENGINE:
+		return "CLOSURE_ENGINE"
+	case BYTECODE_ENGINE:
+		return "BYTECODE_ENGINE"
+	}
+
+	return "UNKNOWN"
+}
@@ -6,4 +6,35 @@ package govaluate
 type ExpressionToken struct {
 	Kind  TokenKind
 	Value interface{}
+
+	/*
+		Where this token starts, and where it ends (exclusive), in the expression it was parsed from.
+		Tokens which were not parsed from a string (such as those given to [NewEvaluableExpressionFromTokens]) have zero-valued positions.
+	*/
+	Start Position
+	End   Position
+
+	/*
+		The exact source text this token was read from, such as "[foo bar]" for the VARIABLE "foo bar".
+		Empty for tokens which were not parsed from a string.
+	*/
+	Text string
+}
+
+/*
+	Represents a single location within the source of an expression.
+	[Offset] is a zero-based byte offset, [Line] and [Column] are one-based, and [Column] is counted in characters (runes).
+	A zero-valued Position (where Line is 0) means the location is unknown.
+*/
+type Position struct {
+	Offset int
+	Line   int
+	Column int
+}
+
+/*
+	Returns whether or not this position refers to an actual location in an expression.
+*/
+func (this Position) IsValid() bool {
+	return this.Line > 0
 }
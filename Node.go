@@ -0,0 +1,181 @@
+package govaluate
+
+/*
+	Represents one node of the syntax tree of a parsed expression. See `EvaluableExpression.AST()`.
+	Every Node is one of *LiteralNode, *VariableNode, *AccessorNode, *FunctionNode, *UnaryNode, *BinaryNode, *TernaryNode, or *ArrayNode.
+*/
+type Node interface {
+
+	/*
+		Returns the nodes directly beneath this one, in the order they appear in the expression.
+	*/
+	Children() []Node
+
+	isNode()
+}
+
+/*
+	A literal value, such as a number, string, boolean, or time.
+	Strings which are used as regex patterns may also be a precompiled *regexp.Regexp.
+	Operators whose operands were all literals are computed while parsing, and so appear as a single literal of their result.
+*/
+type LiteralNode struct {
+	Value interface{}
+}
+
+/*
+	A parameter, such as `foo` or `[foo bar]`.
+*/
+type VariableNode struct {
+	Name string
+}
+
+/*
+	A field or method of a parameter, such as `foo.Bar` or `foo.Bar(1)`.
+	[Path] is every name in the accessor, starting with the parameter's name.
+	If [IsMethodCall] is true, the method is called with the given [Arguments].
+*/
+type AccessorNode struct {
+	Path         []string
+	Arguments    []Node
+	IsMethodCall bool
+}
+
+/*
+	A call to a user-defined function, such as `foo(1, 2)`.
+	Exactly one of [Function] or [ContextFunction] is set, depending on which kind of function was given to the expression.
+	[Name] is the name the function was called by, which is empty if it was given as a token without any Text.
+*/
+type FunctionNode struct {
+	Name            string
+	Function        ExpressionFunction
+	ContextFunction ContextExpressionFunction
+	Arguments       []Node
+}
+
+/*
+	A prefix operator, such as `-foo`, `!foo`, or `~foo`. [Operator] is one of NEGATE, INVERT, or BITWISE_NOT.
+*/
+type UnaryNode struct {
+	Operator OperatorSymbol
+	Operand  Node
+}
+
+/*
+	An operator between two operands, such as `foo + bar`, `foo && bar`, or `foo ?? bar`.
+*/
+type BinaryNode struct {
+	Operator OperatorSymbol
+	Left     Node
+	Right    Node
+}
+
+/*
+	A ternary, such as `foo ? bar : baz`. [Else] is nil if there is no `:` part, in which case the ternary is nil when [Condition] is false.
+*/
+type TernaryNode struct {
+	Condition Node
+	Then      Node
+	Else      Node
+}
+
+/*
+	An array built with separators, such as `(1, 2, 3)`.
+*/
+type ArrayNode struct {
+	Elements []Node
+}
+
+func (this *LiteralNode) Children() []Node {
+	return nil
+}
+
+func (this *VariableNode) Children() []Node {
+	return nil
+}
+
+func (this *AccessorNode) Children() []Node {
+	return this.Arguments
+}
+
+func (this *FunctionNode) Children() []Node {
+	return this.Arguments
+}
+
+func (this *UnaryNode) Children() []Node {
+	return []Node{this.Operand}
+}
+
+func (this *BinaryNode) Children() []Node {
+	return []Node{this.Left, this.Right}
+}
+
+func (this *TernaryNode) Children() []Node {
+
+	if this.Else == nil {
+		return []Node{this.Condition, this.Then}
+	}
+	return []Node{this.Condition, this.Then, this.Else}
+}
+
+func (this *ArrayNode) Children() []Node {
+	return this.Elements
+}
+
+func (this *LiteralNode) isNode()  {}
+func (this *VariableNode) isNode() {}
+func (this *AccessorNode) isNode() {}
+func (this *FunctionNode) isNode() {}
+func (this *UnaryNode) isNode()    {}
+func (this *BinaryNode) isNode()   {}
+func (this *TernaryNode) isNode()  {}
+func (this *ArrayNode) isNode()    {}
+
+/*
+	Visits nodes during `Walk`.
+	`Visit` is called for each node. If it returns a non-nil Visitor, that Visitor is used to visit each of the node's children,
+	and then has `Visit(nil)` called on it once all of them are visited.
+*/
+type Visitor interface {
+	Visit(node Node) Visitor
+}
+
+/*
+	Traverses the tree under the given [node] depth-first, in the order that nodes appear in the expression,
+	starting by calling `visitor.Visit(node)`.
+*/
+func Walk(visitor Visitor, node Node) {
+
+	if node == nil {
+		return
+	}
+
+	visitor = visitor.Visit(node)
+	if visitor == nil {
+		return
+	}
+
+	for _, child := range node.Children() {
+		Walk(visitor, child)
+	}
+
+	visitor.Visit(nil)
+}
+
+/*
+	Traverses the tree under the given [node] depth-first, calling [inspector] for each node.
+	If [inspector] returns false, the children of that node are not visited.
+*/
+func Inspect(node Node, inspector func(Node) bool) {
+	Walk(inspectorVisitor(inspector), node)
+}
+
+type inspectorVisitor func(Node) bool
+
+func (this inspectorVisitor) Visit(node Node) Visitor {
+
+	if node != nil && this(node) {
+		return this
+	}
+	return nil
+}
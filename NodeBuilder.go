@@ -0,0 +1,191 @@
+package govaluate
+
+/*
+	Functions which build syntax trees, to be given to `CompileAST`. For instance,
+
+		CompileAST(And(Gt(Var("x"), Num(3)), Eq(Var("name"), Str("foo"))))
+
+	is the same as parsing `x > 3 && name == 'foo'`.
+	Any operator without a function here can be built directly, such as `&BinaryNode{Operator: BITWISE_XOR, Left: a, Right: b}`.
+*/
+
+/*
+	A literal of any value that an expression can contain. Numbers are converted according to the numeric mode given to `CompileAST`.
+*/
+func Lit(value interface{}) Node {
+	return &LiteralNode{Value: value}
+}
+
+func Num(value float64) Node {
+	return &LiteralNode{Value: value}
+}
+
+func Int(value int64) Node {
+	return &LiteralNode{Value: value}
+}
+
+func Str(value string) Node {
+	return &LiteralNode{Value: value}
+}
+
+func Bool(value bool) Node {
+	return &LiteralNode{Value: value}
+}
+
+/*
+	A parameter with the given [name].
+*/
+func Var(name string) Node {
+	return &VariableNode{Name: name}
+}
+
+/*
+	A field of a parameter, such as `Field("foo", "Bar")` for `foo.Bar`.
+*/
+func Field(path ...string) Node {
+	return &AccessorNode{Path: path}
+}
+
+/*
+	A method call on a parameter, such as `Method([]string{"foo", "Bar"}, Num(1))` for `foo.Bar(1)`.
+*/
+func Method(path []string, arguments ...Node) Node {
+	return &AccessorNode{Path: path, Arguments: arguments, IsMethodCall: true}
+}
+
+/*
+	A call to the given [function], which is shown with the given [name].
+*/
+func Call(name string, function ExpressionFunction, arguments ...Node) Node {
+	return &FunctionNode{Name: name, Function: function, Arguments: arguments}
+}
+
+/*
+	An array of the given [elements], such as the right side of `in`. Must have at least two elements.
+*/
+func Array(elements ...Node) Node {
+	return &ArrayNode{Elements: elements}
+}
+
+/*
+	Joins all of the given [operands] with `&&`. With one operand, returns it as-is.
+*/
+func And(operands ...Node) Node {
+	return chainNodes(AND, operands)
+}
+
+/*
+	Joins all of the given [operands] with `||`. With one operand, returns it as-is.
+*/
+func Or(operands ...Node) Node {
+	return chainNodes(OR, operands)
+}
+
+func Not(operand Node) Node {
+	return &UnaryNode{Operator: INVERT, Operand: operand}
+}
+
+func Neg(operand Node) Node {
+	return &UnaryNode{Operator: NEGATE, Operand: operand}
+}
+
+func Eq(left Node, right Node) Node {
+	return &BinaryNode{Operator: EQ, Left: left, Right: right}
+}
+
+func Neq(left Node, right Node) Node {
+	return &BinaryNode{Operator: NEQ, Left: left, Right: right}
+}
+
+func Gt(left Node, right Node) Node {
+	return &BinaryNode{Operator: GT, Left: left, Right: right}
+}
+
+func Gte(left Node, right Node) Node {
+	return &BinaryNode{Operator: GTE, Left: left, Right: right}
+}
+
+func Lt(left Node, right Node) Node {
+	return &BinaryNode{Operator: LT, Left: left, Right: right}
+}
+
+func Lte(left Node, right Node) Node {
+	return &BinaryNode{Operator: LTE, Left: left, Right: right}
+}
+
+/*
+	`left =~ right`, where [right] is a regex pattern.
+*/
+func Match(left Node, right Node) Node {
+	return &BinaryNode{Operator: REQ, Left: left, Right: right}
+}
+
+/*
+	`left !~ right`, where [right] is a regex pattern.
+*/
+func NotMatch(left Node, right Node) Node {
+	return &BinaryNode{Operator: NREQ, Left: left, Right: right}
+}
+
+/*
+	`left in right`, where [right] is usually an `Array`.
+*/
+func In(left Node, right Node) Node {
+	return &BinaryNode{Operator: IN, Left: left, Right: right}
+}
+
+func Add(left Node, right Node) Node {
+	return &BinaryNode{Operator: PLUS, Left: left, Right: right}
+}
+
+func Sub(left Node, right Node) Node {
+	return &BinaryNode{Operator: MINUS, Left: left, Right: right}
+}
+
+func Mul(left Node, right Node) Node {
+	return &BinaryNode{Operator: MULTIPLY, Left: left, Right: right}
+}
+
+func Div(left Node, right Node) Node {
+	return &BinaryNode{Operator: DIVIDE, Left: left, Right: right}
+}
+
+func Mod(left Node, right Node) Node {
+	return &BinaryNode{Operator: MODULUS, Left: left, Right: right}
+}
+
+func Pow(left Node, right Node) Node {
+	return &BinaryNode{Operator: EXPONENT, Left: left, Right: right}
+}
+
+/*
+	`left ?? right`.
+*/
+func Coalesce(left Node, right Node) Node {
+	return &BinaryNode{Operator: COALESCE, Left: left, Right: right}
+}
+
+/*
+	`condition ? then : otherwise`. If [otherwise] is nil, there is no `:` part.
+*/
+func Ternary(condition Node, then Node, otherwise Node) Node {
+	return &TernaryNode{Condition: condition, Then: then, Else: otherwise}
+}
+
+/*
+	Joins the given [operands] into a left-associative chain of the given operator [symbol], the same as parsing would.
+*/
+func chainNodes(symbol OperatorSymbol, operands []Node) Node {
+
+	var ret Node
+
+	if len(operands) == 0 {
+		return nil
+	}
+
+	ret = operands[0]
+	for _, operand := range operands[1:] {
+		ret = &BinaryNode{Operator: symbol, Left: ret, Right: operand}
+	}
+	return ret
+}
@@ -0,0 +1,47 @@
+package govaluate
+
+/*
+	Represents how numbers (both literals and parameters) are represented when an expression is evaluated.
+*/
+type NumericMode int
+
+const (
+
+	/*
+		The default mode. All numbers are converted to float64 before use, and all numeric results are float64.
+	*/
+	FLOAT_NUMERICS NumericMode = iota
+
+	/*
+		Integer literals (those without a radix point) are int64, and all signed integer parameters are int64,
+		and all unsigned integer parameters are uint64. Literals too large for an int64 are uint64.
+		Arithmetic between integers produces integers (division truncates, and division by zero is an error),
+		and only promotes to float64 when one side is a float, or when the result would overflow (rather than wrapping).
+	*/
+	INTEGER_NUMERICS
+
+	/*
+		All numeric literals and parameters are exact decimals, represented as *big.Rat.
+		Arithmetic and comparisons are exact, so (for instance) "0.1 + 0.2 == 0.3" is true.
+		Division is exact unless a DecimalRounding is given, and division by zero is an error.
+		Bitwise operators truncate their operands to integers.
+	*/
+	DECIMAL_NUMERICS
+)
+
+/*
+	Returns a string that describes this NumericMode.
+*/
+func (this NumericMode) String() string {
+
+	switch this {
+	case FLOAT_NUMERICS:
+		return "FLOAT_NUMERICS"
+	case INTEGER_NUMERICS:
+		return "INTEGER_NUMERICS"
+	case DECIMAL_NUMERICS:
+		return "DECIMAL_NUMERICS"
+	}
+
+	return "UNKNOWN"
+}
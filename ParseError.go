@@ -0,0 +1,126 @@
+package govaluate
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+)
+
+/*
+	Represents a problem found while parsing an expression, and where in that expression it was found.
+	All errors returned while creating an EvaluableExpression are of this type, so that callers (such as editors)
+	can point out exactly which part of an expression is wrong.
+*/
+type ParseError struct {
+
+	// A human-readable description of the problem.
+	Message string
+
+	/*
+		Where the problem starts and ends (exclusive) in the expression.
+		These are zero-valued if the location is unknown, such as when the expression was created from tokens.
+	*/
+	Start Position
+	End   Position
+
+	// The token which caused the problem, or nil if the problem isn't attributable to a single token (such as an unexpected end of expression).
+	Token *ExpressionToken
+
+	// The kinds of token which would have been valid where the problem was found, if known.
+	Expected []TokenKind
+}
+
+func (this *ParseError) Error() string {
+
+	if !this.Start.IsValid() {
+		return this.Message
+	}
+	return fmt.Sprintf("%s (line %d, column %d)", this.Message, this.Start.Line, this.Start.Column)
+}
+
+/*
+	A list of every problem found in an expression, as returned by [NewEvaluableExpressionWithDiagnostics].
+	Errors are ordered by where they occur in the expression.
+*/
+type ParseErrors []*ParseError
+
+func (this ParseErrors) Error() string {
+
+	var buffer bytes.Buffer
+
+	for i, err := range this {
+
+		if i > 0 {
+			buffer.WriteString("; ")
+		}
+		buffer.WriteString(err.Error())
+	}
+	return buffer.String()
+}
+
+/*
+	Orders the errors by where they start in the expression.
+	Errors at the same location keep the order in which they were found.
+*/
+func (this ParseErrors) sort() {
+
+	sort.SliceStable(this, func(i, j int) bool {
+		return this[i].Start.Offset < this[j].Start.Offset
+	})
+}
+
+/*
+	Returns the given [err] as a ParseError, wrapping it (without any position) if it isn't one already.
+*/
+func asParseError(err error) *ParseError {
+
+	parseError, ok := err.(*ParseError)
+	if ok {
+		return parseError
+	}
+
+	return &ParseError{
+		Message: err.Error(),
+	}
+}
+
+/*
+	Creates a ParseError which points at the given [token].
+*/
+func newTokenParseError(token ExpressionToken, expected []TokenKind, format string, arguments ...interface{}) *ParseError {
+
+	return &ParseError{
+		Message:  fmt.Sprintf(format, arguments...),
+		Start:    token.Start,
+		End:      token.End,
+		Token:    &token,
+		Expected: copyTokenKinds(expected),
+	}
+}
+
+/*
+	Creates a ParseError which points at a single location, but not any specific token.
+*/
+func newPositionParseError(position Position, expected []TokenKind, format string, arguments ...interface{}) *ParseError {
+
+	return &ParseError{
+		Message:  fmt.Sprintf(format, arguments...),
+		Start:    position,
+		End:      position,
+		Expected: copyTokenKinds(expected),
+	}
+}
+
+/*
+	Lexer states share their lists of valid kinds, so errors are given their own copy to avoid callers modifying them.
+*/
+func copyTokenKinds(kinds []TokenKind) []TokenKind {
+
+	if len(kinds) == 0 {
+		return nil
+	}
+
+	ret := make([]TokenKind, len(kinds))
+	copy(ret, kinds)
+	return ret
+}
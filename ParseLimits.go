@@ -0,0 +1,114 @@
+package govaluate
+
+/*
+	Represents limits on the size and complexity of an expression, which are checked while the expression is parsed.
+	Useful when expressions come from untrusted sources, since parsing and evaluation both recurse once for each level of nesting,
+	and a pathological expression could otherwise exhaust the stack.
+
+	A limit of zero (or less) means that there is no limit. The zero value of this struct places no limits at all.
+*/
+type ParseLimits struct {
+
+	/*
+		The maximum length of the expression, in bytes.
+	*/
+	MaxLength int
+
+	/*
+		The maximum number of tokens in the expression.
+	*/
+	MaxTokens int
+
+	/*
+		The maximum depth that parentheses (including those of function calls) may be nested.
+	*/
+	MaxNestingDepth int
+
+	/*
+		The maximum depth of the planned tree of evaluation stages.
+		This is the longest chain of operators (and their operands) which one evaluation may need to recurse through.
+		It's checked while the expression is planned, before any literals are computed in advance, and again once they have been.
+	*/
+	MaxStageDepth int
+}
+
+/*
+	Returns an error if the given [expression] is longer than these limits allow.
+*/
+func (this ParseLimits) checkLength(expression string) error {
+
+	if this.MaxLength > 0 && len(expression) > this.MaxLength {
+		return newPositionParseError(Position{}, nil, "Expression is %d bytes long, which exceeds the maximum of %d", len(expression), this.MaxLength)
+	}
+	return nil
+}
+
+/*
+	Returns an error if the given [tokens] are too many, or too deeply nested, for these limits.
+*/
+func (this ParseLimits) checkTokens(tokens []ExpressionToken) error {
+
+	var depth int
+
+	if this.MaxTokens > 0 && len(tokens) > this.MaxTokens {
+		return newTokenParseError(tokens[this.MaxTokens], nil, "Expression has %d tokens, which exceeds the maximum of %d", len(tokens), this.MaxTokens)
+	}
+
+	if this.MaxNestingDepth <= 0 {
+		return nil
+	}
+
+	for _, token := range tokens {
+
+		switch token.Kind {
+		case CLAUSE:
+
+			depth++
+			if depth > this.MaxNestingDepth {
+				return newTokenParseError(token, nil, "Parentheses are nested more than %d deep", this.MaxNestingDepth)
+			}
+
+		case CLAUSE_CLOSE:
+			depth--
+		}
+	}
+	return nil
+}
+
+/*
+	Returns an error if the tree of stages under [root] is deeper than these limits allow.
+*/
+func (this ParseLimits) checkStageDepth(root *evaluationStage) error {
+
+	var depth int
+
+	if this.MaxStageDepth <= 0 {
+		return nil
+	}
+
+	depth = findStageDepth(root, this.MaxStageDepth+1)
+	if depth > this.MaxStageDepth {
+		return newPositionParseError(Position{}, nil, "Expression is nested more than %d stages deep", this.MaxStageDepth)
+	}
+	return nil
+}
+
+/*
+	Returns the depth of the tree of stages under [root], or [max] if it's at least that deep.
+*/
+func findStageDepth(root *evaluationStage, max int) int {
+
+	var left, right int
+
+	if root == nil || max <= 0 {
+		return 0
+	}
+
+	left = findStageDepth(root.leftStage, max-1)
+	right = findStageDepth(root.rightStage, max-1)
+
+	if left > right {
+		return left + 1
+	}
+	return right + 1
+}
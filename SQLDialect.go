@@ -0,0 +1,233 @@
+package govaluate
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+/*
+	Describes the flavor of SQL written by `ToSQLQueryWithDialect`.
+	Dialects are registered by name with `RegisterSQLDialect`. The built-in dialects are:
+
+		"default"   the flavor written by `ToSQLQuery`: [bracketed] columns, RLIKE, booleans as 1 and 0, ? placeholders.
+		"postgres"  "quoted" columns, ~ and !~, TRUE and FALSE, POWER and MOD, $1 placeholders.
+		"mysql"     `quoted` columns, REGEXP and NOT REGEXP, TRUE and FALSE, POW and MOD, ? placeholders.
+		"sqlite"    "quoted" columns, REGEXP and NOT REGEXP, booleans as 1 and 0, POWER and the % operator, ? placeholders.
+*/
+type SQLDialect struct {
+
+	/*
+		The strings which open and close a quoted column name, such as `"` and `"`.
+		Any closing string within a column name is escaped by doubling it.
+	*/
+	IdentifierOpen  string
+	IdentifierClose string
+
+	/*
+		The operators used for `=~` and `!~`.
+	*/
+	RegexOperator    string
+	NotRegexOperator string
+
+	/*
+		The literals used for `true` and `false`.
+	*/
+	True  string
+	False string
+
+	/*
+		The format used to output times. If empty, the expression's QueryDateFormat is used.
+	*/
+	DateFormat string
+
+	/*
+		Formats used to output `**` and `%`, given the SQL for the left and right operands (in that order), such as "POWER(%s, %s)".
+	*/
+	PowerFormat   string
+	ModulusFormat string
+
+	/*
+		How bind arguments are written by `ToSQLQueryWithArguments`.
+	*/
+	Placeholders PlaceholderStyle
+
+	/*
+		Translations of user-defined functions, by the name they're called with in expressions.
+		Calls to any other function can't be written as SQL.
+	*/
+	Functions map[string]SQLFunction
+
+	/*
+		Maps parameters and accessors to the SQL which refers to them. If nil, or if it returns an empty string,
+		parameters are written as quoted column names, and accessors can't be written as SQL.
+	*/
+	Columns SQLColumnMapper
+}
+
+/*
+	Writes a call to a function as SQL, given the SQL of each of its [arguments].
+*/
+type SQLFunction func(arguments []string) (string, error)
+
+/*
+	Returns the SQL which refers to the parameter or accessor with the given [path].
+	For a parameter, [path] is just its name; for an accessor such as `user.Age`, it's every name in the accessor, such as ["user", "Age"].
+*/
+type SQLColumnMapper func(path []string) (string, error)
+
+/*
+	Returns a translation which writes a call to the SQL function with the given [name], with the same arguments.
+	For instance, `SQLFunctionCall("LENGTH")` writes `strlen(foo)` as `LENGTH("foo")`.
+*/
+func SQLFunctionCall(name string) SQLFunction {
+
+	return func(arguments []string) (string, error) {
+		return fmt.Sprintf("%s(%s)", name, strings.Join(arguments, ", ")), nil
+	}
+}
+
+/*
+	Represents how a SQL dialect writes the placeholders for bind arguments.
+*/
+type PlaceholderStyle int
+
+const (
+
+	/*
+		Every placeholder is `?`, as used by MySQL and SQLite.
+	*/
+	QUESTION_PLACEHOLDERS PlaceholderStyle = iota
+
+	/*
+		Placeholders are numbered `$1`, `$2`, and so on, as used by PostgreSQL.
+	*/
+	DOLLAR_PLACEHOLDERS
+
+	/*
+		Placeholders are named `@p1`, `@p2`, and so on, as used by SQL Server.
+	*/
+	AT_PLACEHOLDERS
+)
+
+/*
+	Returns a string that describes this PlaceholderStyle.
+*/
+func (this PlaceholderStyle) String() string {
+
+	switch this {
+	case QUESTION_PLACEHOLDERS:
+		return "QUESTION_PLACEHOLDERS"
+	case DOLLAR_PLACEHOLDERS:
+		return "DOLLAR_PLACEHOLDERS"
+	case AT_PLACEHOLDERS:
+		return "AT_PLACEHOLDERS"
+	}
+
+	return "UNKNOWN"
+}
+
+var sqlDialects = map[string]SQLDialect{
+
+	"default": SQLDialect{
+		IdentifierOpen:   "[",
+		IdentifierClose:  "]",
+		RegexOperator:    "RLIKE",
+		NotRegexOperator: "NOT RLIKE",
+		True:             "1",
+		False:            "0",
+		PowerFormat:      "POW(%s, %s)",
+		ModulusFormat:    "MOD(%s, %s)",
+	},
+	"postgres": SQLDialect{
+		IdentifierOpen:   "\"",
+		IdentifierClose:  "\"",
+		RegexOperator:    "~",
+		NotRegexOperator: "!~",
+		True:             "TRUE",
+		False:            "FALSE",
+		PowerFormat:      "POWER(%s, %s)",
+		ModulusFormat:    "MOD(%s, %s)",
+		Placeholders:     DOLLAR_PLACEHOLDERS,
+	},
+	"mysql": SQLDialect{
+		IdentifierOpen:   "`",
+		IdentifierClose:  "`",
+		RegexOperator:    "REGEXP",
+		NotRegexOperator: "NOT REGEXP",
+		True:             "TRUE",
+		False:            "FALSE",
+		DateFormat:       "2006-01-02 15:04:05.999999",
+		PowerFormat:      "POW(%s, %s)",
+		ModulusFormat:    "MOD(%s, %s)",
+	},
+	"sqlite": SQLDialect{
+		IdentifierOpen:   "\"",
+		IdentifierClose:  "\"",
+		RegexOperator:    "REGEXP",
+		NotRegexOperator: "NOT REGEXP",
+		True:             "1",
+		False:            "0",
+		PowerFormat:      "POWER(%s, %s)",
+		ModulusFormat:    "%s %% %s",
+	},
+}
+
+var sqlDialectsLock sync.RWMutex
+
+/*
+	Makes the given [dialect] available to `ToSQLQueryWithDialect` under the given [name].
+	If a dialect is already registered with that name (including a built-in one), it is replaced.
+*/
+func RegisterSQLDialect(name string, dialect SQLDialect) {
+
+	sqlDialectsLock.Lock()
+	defer sqlDialectsLock.Unlock()
+
+	sqlDialects[name] = dialect
+}
+
+/*
+	Returns the dialect registered with the given [name], or an error if there isn't one.
+	This is useful for registering a dialect based on another, such as one of the built-in dialects with added Functions.
+*/
+func FindSQLDialect(name string) (SQLDialect, error) {
+
+	var ret SQLDialect
+	var found bool
+
+	sqlDialectsLock.RLock()
+	defer sqlDialectsLock.RUnlock()
+
+	ret, found = sqlDialects[name]
+	if !found {
+		return ret, errors.New(fmt.Sprintf("Unknown SQL dialect '%s'", name))
+	}
+	return ret, nil
+}
+
+/*
+	Returns the given column [name], quoted.
+*/
+func (this SQLDialect) quoteIdentifier(name string) string {
+
+	if this.IdentifierClose != "" {
+		name = strings.Replace(name, this.IdentifierClose, this.IdentifierClose+this.IdentifierClose, -1)
+	}
+	return this.IdentifierOpen + name + this.IdentifierClose
+}
+
+/*
+	Returns the placeholder for the bind argument at the given one-based [position].
+*/
+func (this SQLDialect) placeholder(position int) string {
+
+	switch this.Placeholders {
+	case DOLLAR_PLACEHOLDERS:
+		return fmt.Sprintf("$%d", position)
+	case AT_PLACEHOLDERS:
+		return fmt.Sprintf("@p%d", position)
+	}
+	return "?"
+}
@@ -249,3 +249,247 @@ func BenchmarkNestedAccessors(bench *testing.B) {
 		expression.Evaluate(fooFailureParameters)
 	}
 }
+
+/*
+  Benchmarks evaluation of the given expression with the given engine.
+  Each of the benchmarks below is the same as one of the above, so the engines can be compared directly.
+*/
+func benchmarkEngine(bench *testing.B, engine EvaluationEngine, expressionString string, parameters map[string]interface{}) {
+
+	expression, _ := Compile(expressionString, WithEngine(engine))
+
+	bench.ResetTimer()
+	for i := 0; i < bench.N; i++ {
+		expression.Evaluate(parameters)
+	}
+}
+
+func BenchmarkClosureEvaluationSingle(bench *testing.B) {
+	benchmarkEngine(bench, CLOSURE_ENGINE, "1", nil)
+}
+
+func BenchmarkClosureEvaluationNumericLiteral(bench *testing.B) {
+	benchmarkEngine(bench, CLOSURE_ENGINE, "(2) > (1)", nil)
+}
+
+func BenchmarkClosureEvaluationLiteralModifiers(bench *testing.B) {
+	benchmarkEngine(bench, CLOSURE_ENGINE, "(2) + (2) == (4)", nil)
+}
+
+func BenchmarkClosureEvaluationParameter(bench *testing.B) {
+
+	benchmarkEngine(bench, CLOSURE_ENGINE, "requests_made", map[string]interface{}{
+		"requests_made": 99.0,
+	})
+}
+
+func BenchmarkClosureEvaluationParameters(bench *testing.B) {
+
+	benchmarkEngine(bench, CLOSURE_ENGINE, "requests_made > requests_succeeded", map[string]interface{}{
+		"requests_made":      99.0,
+		"requests_succeeded": 90.0,
+	})
+}
+
+func BenchmarkClosureEvaluationParametersModifiers(bench *testing.B) {
+
+	benchmarkEngine(bench, CLOSURE_ENGINE, "(requests_made * requests_succeeded / 100) >= 90", map[string]interface{}{
+		"requests_made":      99.0,
+		"requests_succeeded": 90.0,
+	})
+}
+
+func BenchmarkClosureComplexExpression(bench *testing.B) {
+
+	var expressionString string
+
+	expressionString = "2 > 1 &&" +
+		"'something' != 'nothing' || " +
+		"'2014-01-20' < 'Wed Jul  8 23:07:35 MDT 2015' && " +
+		"[escapedVariable name with spaces] <= unescaped\\-variableName &&" +
+		"modifierTest + 1000 / 2 > (80 * 100 % 2)"
+
+	benchmarkEngine(bench, CLOSURE_ENGINE, expressionString, map[string]interface{}{
+		"escapedVariable name with spaces": 99.0,
+		"unescaped\\-variableName":         90.0,
+		"modifierTest":                     5.0,
+	})
+}
+
+func BenchmarkClosureRegexExpression(bench *testing.B) {
+
+	benchmarkEngine(bench, CLOSURE_ENGINE, "(foo !~ bar) && (foobar =~ oba)", map[string]interface{}{
+		"foo": "foo",
+		"bar": "bar",
+		"baz": "baz",
+		"oba": ".*oba.*",
+	})
+}
+
+func BenchmarkClosureConstantRegexExpression(bench *testing.B) {
+
+	benchmarkEngine(bench, CLOSURE_ENGINE, "(foo !~ '[bB]az') && (bar =~ '[bB]ar')", map[string]interface{}{
+		"foo": "foo",
+		"bar": "bar",
+	})
+}
+
+func BenchmarkClosureAccessors(bench *testing.B) {
+	benchmarkEngine(bench, CLOSURE_ENGINE, "foo.Int", fooFailureParameters)
+}
+
+func BenchmarkClosureNestedAccessors(bench *testing.B) {
+	benchmarkEngine(bench, CLOSURE_ENGINE, "foo.Nested.Funk", fooFailureParameters)
+}
+
+func BenchmarkBytecodeEvaluationSingle(bench *testing.B) {
+	benchmarkEngine(bench, BYTECODE_ENGINE, "1", nil)
+}
+
+func BenchmarkBytecodeEvaluationNumericLiteral(bench *testing.B) {
+	benchmarkEngine(bench, BYTECODE_ENGINE, "(2) > (1)", nil)
+}
+
+func BenchmarkBytecodeEvaluationLiteralModifiers(bench *testing.B) {
+	benchmarkEngine(bench, BYTECODE_ENGINE, "(2) + (2) == (4)", nil)
+}
+
+func BenchmarkBytecodeEvaluationParameter(bench *testing.B) {
+
+	benchmarkEngine(bench, BYTECODE_ENGINE, "requests_made", map[string]interface{}{
+		"requests_made": 99.0,
+	})
+}
+
+func BenchmarkBytecodeEvaluationParameters(bench *testing.B) {
+
+	benchmarkEngine(bench, BYTECODE_ENGINE, "requests_made > requests_succeeded", map[string]interface{}{
+		"requests_made":      99.0,
+		"requests_succeeded": 90.0,
+	})
+}
+
+func BenchmarkBytecodeEvaluationParametersModifiers(bench *testing.B) {
+
+	benchmarkEngine(bench, BYTECODE_ENGINE, "(requests_made * requests_succeeded / 100) >= 90", map[string]interface{}{
+		"requests_made":      99.0,
+		"requests_succeeded": 90.0,
+	})
+}
+
+func BenchmarkBytecodeComplexExpression(bench *testing.B) {
+
+	var expressionString string
+
+	expressionString = "2 > 1 &&" +
+		"'something' != 'nothing' || " +
+		"'2014-01-20' < 'Wed Jul  8 23:07:35 MDT 2015' && " +
+		"[escapedVariable name with spaces] <= unescaped\\-variableName &&" +
+		"modifierTest + 1000 / 2 > (80 * 100 % 2)"
+
+	benchmarkEngine(bench, BYTECODE_ENGINE, expressionString, map[string]interface{}{
+		"escapedVariable name with spaces": 99.0,
+		"unescaped\\-variableName":         90.0,
+		"modifierTest":                     5.0,
+	})
+}
+
+func BenchmarkBytecodeRegexExpression(bench *testing.B) {
+
+	benchmarkEngine(bench, BYTECODE_ENGINE, "(foo !~ bar) && (foobar =~ oba)", map[string]interface{}{
+		"foo": "foo",
+		"bar": "bar",
+		"baz": "baz",
+		"oba": ".*oba.*",
+	})
+}
+
+func BenchmarkBytecodeConstantRegexExpression(bench *testing.B) {
+
+	benchmarkEngine(bench, BYTECODE_ENGINE, "(foo !~ '[bB]az') && (bar =~ '[bB]ar')", map[string]interface{}{
+		"foo": "foo",
+		"bar": "bar",
+	})
+}
+
+func BenchmarkBytecodeAccessors(bench *testing.B) {
+	benchmarkEngine(bench, BYTECODE_ENGINE, "foo.Int", fooFailureParameters)
+}
+
+func BenchmarkBytecodeNestedAccessors(bench *testing.B) {
+	benchmarkEngine(bench, BYTECODE_ENGINE, "foo.Nested.Funk", fooFailureParameters)
+}
+
+/*
+  Returns many sets of parameters, to benchmark evaluating one expression against each of them,
+  either by calling `Eval` for each, or with `EvalBatch`.
+*/
+func makeBenchmarkBatch() []Parameters {
+
+	var ret []Parameters
+
+	for i := 0; i < 1000; i++ {
+		ret = append(ret, MapParameters{
+			"requests_made":      float64(i),
+			"requests_succeeded": float64(i / 2),
+		})
+	}
+	return ret
+}
+
+func BenchmarkEvaluationLoop(bench *testing.B) {
+
+	expression, _ := NewEvaluableExpression("(requests_made * requests_succeeded / 100) >= 90")
+	batch := makeBenchmarkBatch()
+
+	bench.ResetTimer()
+	for i := 0; i < bench.N; i++ {
+		for _, parameters := range batch {
+			expression.Eval(parameters)
+		}
+	}
+}
+
+func BenchmarkEvaluationBatch(bench *testing.B) {
+
+	expression, _ := NewEvaluableExpression("(requests_made * requests_succeeded / 100) >= 90")
+	batch := makeBenchmarkBatch()
+
+	bench.ResetTimer()
+	for i := 0; i < bench.N; i++ {
+		expression.EvalBatch(batch, 1)
+	}
+}
+
+func BenchmarkEvaluationBatchWorkers(bench *testing.B) {
+
+	expression, _ := NewEvaluableExpression("(requests_made * requests_succeeded / 100) >= 90")
+	batch := makeBenchmarkBatch()
+
+	bench.ResetTimer()
+	for i := 0; i < bench.N; i++ {
+		expression.EvalBatch(batch, 4)
+	}
+}
+
+func BenchmarkEvaluationColumns(bench *testing.B) {
+
+	expression, _ := NewEvaluableExpression("(requests_made * requests_succeeded / 100) >= 90")
+	made := make([]float64, 1000)
+	succeeded := make([]float64, 1000)
+
+	for i := range made {
+		made[i] = float64(i)
+		succeeded[i] = float64(i / 2)
+	}
+
+	columns := map[string]interface{}{
+		"requests_made":      made,
+		"requests_succeeded": succeeded,
+	}
+
+	bench.ResetTimer()
+	for i := 0; i < bench.N; i++ {
+		expression.EvalColumns(columns)
+	}
+}
@@ -0,0 +1,301 @@
+package govaluate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+/*
+	Represents a test of an expression compiled with the given [Options], and evaluated with the given [Parameters].
+*/
+type CompileTest struct {
+	Name       string
+	Input      string
+	Options    []Option
+	Parameters map[string]interface{}
+	Expected   interface{}
+}
+
+/*
+	Represents a test of an expression which is expected to fail to compile (or, if it compiles, to evaluate)
+	with an error that contains [Expected].
+*/
+type CompileFailureTest struct {
+	Name     string
+	Input    string
+	Options  []Option
+	Expected string
+}
+
+type compileTestVector struct {
+	X, Y float64
+}
+
+func TestCompile(test *testing.T) {
+
+	addVectors := func(left interface{}, right interface{}) (interface{}, error) {
+
+		l, lok := left.(compileTestVector)
+		r, rok := right.(compileTestVector)
+		if !lok || !rok {
+			return nil, errors.New("Can only add vectors")
+		}
+		return compileTestVector{l.X + r.X, l.Y + r.Y}, nil
+	}
+
+	compileTests := []CompileTest{
+
+		CompileTest{
+
+			Name:     "No options",
+			Input:    "1 + 1",
+			Expected: 2.0,
+		},
+		CompileTest{
+
+			Name:  "Functions from several options",
+			Input: "one() + two()",
+			Options: []Option{
+				WithFunctions(map[string]ExpressionFunction{
+					"one": func(arguments ...interface{}) (interface{}, error) {
+						return 1.0, nil
+					},
+				}),
+				WithFunctions(map[string]ExpressionFunction{
+					"two": func(arguments ...interface{}) (interface{}, error) {
+						return 2.0, nil
+					},
+				}),
+			},
+			Expected: 3.0,
+		},
+		CompileTest{
+
+			Name:  "Context functions",
+			Input: "background()",
+			Options: []Option{
+				WithContextFunctions(map[string]ContextExpressionFunction{
+					"background": func(ctx context.Context, arguments ...interface{}) (interface{}, error) {
+						return ctx == context.Background(), nil
+					},
+				}),
+			},
+			Expected: true,
+		},
+		CompileTest{
+
+			Name:     "Numeric mode",
+			Input:    "7 / 2",
+			Options:  []Option{WithNumericMode(INTEGER_NUMERICS)},
+			Expected: int64(3),
+		},
+		CompileTest{
+
+			Name:     "Decimal rounding",
+			Input:    "2 / 3 == 0.67",
+			Options:  []Option{WithDecimalRounding(DecimalRounding{Scale: 2})},
+			Expected: true,
+		},
+		CompileTest{
+
+			Name:     "Default time formats",
+			Input:    "'2014-01-02' > '2014-01-01'",
+			Expected: true,
+		},
+		CompileTest{
+
+			Name:     "Custom time formats",
+			Input:    "'02/01/2014' > '03/12/2013'",
+			Options:  []Option{WithTimeFormats("02/01/2006")},
+			Expected: true,
+		},
+		CompileTest{
+
+			Name:     "No time formats",
+			Input:    "'2014-01-02' + ''",
+			Options:  []Option{WithTimeFormats()},
+			Expected: "2014-01-02",
+		},
+		CompileTest{
+
+			Name:     "Within limits",
+			Input:    "1 + 1",
+			Options:  []Option{WithLimits(ParseLimits{MaxTokens: 3})},
+			Expected: 2.0,
+		},
+		CompileTest{
+
+			Name:    "Custom operator",
+			Input:   "a + b",
+			Options: []Option{WithOperator(PLUS, addVectors)},
+			Parameters: map[string]interface{}{
+				"a": compileTestVector{1, 2},
+				"b": compileTestVector{3, 4},
+			},
+			Expected: compileTestVector{4, 6},
+		},
+		CompileTest{
+
+			Name:  "Custom prefix operator",
+			Input: "-a",
+			Options: []Option{
+				WithOperator(NEGATE, func(left interface{}, right interface{}) (interface{}, error) {
+					vector := right.(compileTestVector)
+					return compileTestVector{-vector.X, -vector.Y}, nil
+				}),
+			},
+			Parameters: map[string]interface{}{
+				"a": compileTestVector{1, 2},
+			},
+			Expected: compileTestVector{-1, -2},
+		},
+		CompileTest{
+
+			Name:     "Diagnostics without problems",
+			Input:    "1 + 1",
+			Options:  []Option{WithDiagnostics()},
+			Expected: 2.0,
+		},
+		CompileTest{
+
+			Name:     "Simplification",
+			Input:    "!(!a) || true",
+			Options:  []Option{WithSimplification()},
+			Expected: true,
+		},
+	}
+
+	fmt.Printf("Running %d compile test cases...\n", len(compileTests))
+
+	for _, compileTest := range compileTests {
+
+		expression, err := Compile(compileTest.Input, compileTest.Options...)
+		if err != nil {
+
+			test.Logf("Test '%s' failed to compile: '%s'", compileTest.Name, err)
+			test.Fail()
+			continue
+		}
+
+		result, err := expression.Evaluate(compileTest.Parameters)
+		if err != nil {
+
+			test.Logf("Test '%s' failed", compileTest.Name)
+			test.Logf("Encountered error: %s", err.Error())
+			test.Fail()
+			continue
+		}
+
+		if result != compileTest.Expected {
+
+			test.Logf("Test '%s' failed", compileTest.Name)
+			test.Logf("Evaluation result '%v' (%T) does not match expected: '%v' (%T)", result, result, compileTest.Expected, compileTest.Expected)
+			test.Fail()
+		}
+	}
+}
+
+func TestCompileFailure(test *testing.T) {
+
+	compileTests := []CompileFailureTest{
+
+		CompileFailureTest{
+
+			Name:     "Exceeds limits",
+			Input:    "1 + 1 + 1",
+			Options:  []Option{WithLimits(ParseLimits{MaxTokens: 3})},
+			Expected: "exceeds the maximum of 3",
+		},
+		CompileFailureTest{
+
+			Name:     "Exceeds budget",
+			Input:    "1 + number",
+			Options:  []Option{WithBudget(EvaluationBudget{MaxStages: 2})},
+			Expected: "Evaluation budget exceeded",
+		},
+		CompileFailureTest{
+
+			Name:     "Type checks",
+			Input:    "number > string",
+			Expected: INVALID_COMPARATOR_TYPES,
+		},
+		CompileFailureTest{
+
+			Name:     "Unknown numeric mode",
+			Input:    "1 + 1",
+			Options:  []Option{WithNumericMode(NumericMode(7))},
+			Expected: "Unknown numeric mode 7",
+		},
+		CompileFailureTest{
+
+			Name:     "Irreplaceable operator",
+			Input:    "true && true",
+			Options:  []Option{WithOperator(AND, nil)},
+			Expected: "cannot be replaced",
+		},
+		CompileFailureTest{
+
+			Name:  "Custom operator error",
+			Input: "number + number",
+			Options: []Option{
+				WithOperator(PLUS, func(left interface{}, right interface{}) (interface{}, error) {
+					return nil, errors.New("Custom failure")
+				}),
+			},
+			Expected: "Custom failure",
+		},
+		CompileFailureTest{
+
+			Name:     "Diagnostics",
+			Input:    "foo( + bar(",
+			Options:  []Option{WithDiagnostics()},
+			Expected: "; ",
+		},
+	}
+
+	fmt.Printf("Running %d compile failure test cases...\n", len(compileTests))
+
+	for _, compileTest := range compileTests {
+
+		expression, err := Compile(compileTest.Input, compileTest.Options...)
+		if err == nil {
+			_, err = expression.Evaluate(EVALUATION_FAILURE_PARAMETERS)
+		}
+
+		if err == nil || !strings.Contains(err.Error(), compileTest.Expected) {
+
+			test.Logf("Test '%s' failed", compileTest.Name)
+			test.Logf("Got error: '%v', expected '%s'", err, compileTest.Expected)
+			test.Fail()
+		}
+	}
+}
+
+func TestCompileSettings(test *testing.T) {
+
+	expression, err := Compile("foo > '2014-01-02'", WithTypeChecks(false), WithQueryDateFormat("2006"), WithBudget(EvaluationBudget{MaxStages: 10}))
+	if err != nil {
+		test.Logf("Failed to compile: %v", err)
+		test.Fail()
+		return
+	}
+
+	if expression.ChecksTypes {
+		test.Logf("Expected type checks to be disabled")
+		test.Fail()
+	}
+
+	if expression.Budget.MaxStages != 10 {
+		test.Logf("Expected a budget of 10 stages, got %d", expression.Budget.MaxStages)
+		test.Fail()
+	}
+
+	query, err := expression.ToSQLQuery()
+	if err != nil || query != "[foo] > '2014'" {
+		test.Logf("Expected query date format to be used, got '%s' (%v)", query, err)
+		test.Fail()
+	}
+}
@@ -0,0 +1,418 @@
+package govaluate
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+/*
+	Represents a test of an expression evaluated with DECIMAL_NUMERICS.
+	[Expected] is given as the exact decimal string that the result should equal, or as a non-numeric value.
+*/
+type DecimalEvaluationTest struct {
+	Name       string
+	Input      string
+	Rounding   *DecimalRounding
+	Parameters map[string]interface{}
+	Expected   interface{}
+}
+
+func TestDecimalEvaluation(test *testing.T) {
+
+	evaluationTests := []DecimalEvaluationTest{
+
+		DecimalEvaluationTest{
+
+			Name:     "Decimal literal",
+			Input:    "0.1",
+			Expected: "0.1",
+		},
+		DecimalEvaluationTest{
+
+			Name:     "Exact addition",
+			Input:    "0.1 + 0.2",
+			Expected: "0.3",
+		},
+		DecimalEvaluationTest{
+
+			Name:     "Exact equality",
+			Input:    "0.1 + 0.2 == 0.3",
+			Expected: true,
+		},
+		DecimalEvaluationTest{
+
+			Name:     "Large integer precision",
+			Input:    "9007199254740993 + 1",
+			Expected: "9007199254740994",
+		},
+		DecimalEvaluationTest{
+
+			Name:     "Exact division",
+			Input:    "1 / 3 * 3",
+			Expected: "1",
+		},
+		DecimalEvaluationTest{
+
+			Name:     "Rounded division",
+			Input:    "2 / 3",
+			Rounding: &DecimalRounding{Scale: 2},
+			Expected: "0.67",
+		},
+		DecimalEvaluationTest{
+
+			Name:     "Half even rounding",
+			Input:    "0.125 / 1",
+			Rounding: &DecimalRounding{Scale: 2, Mode: ROUND_HALF_EVEN},
+			Expected: "0.12",
+		},
+		DecimalEvaluationTest{
+
+			Name:     "Half up rounding",
+			Input:    "0.125 / 1",
+			Rounding: &DecimalRounding{Scale: 2, Mode: ROUND_HALF_UP},
+			Expected: "0.13",
+		},
+		DecimalEvaluationTest{
+
+			Name:     "Negative half up rounding",
+			Input:    "-0.125 / 1",
+			Rounding: &DecimalRounding{Scale: 2, Mode: ROUND_HALF_UP},
+			Expected: "-0.13",
+		},
+		DecimalEvaluationTest{
+
+			Name:     "Half down rounding",
+			Input:    "0.125 / 1",
+			Rounding: &DecimalRounding{Scale: 2, Mode: ROUND_HALF_DOWN},
+			Expected: "0.12",
+		},
+		DecimalEvaluationTest{
+
+			Name:     "Up rounding",
+			Input:    "-1 / 3",
+			Rounding: &DecimalRounding{Scale: 1, Mode: ROUND_UP},
+			Expected: "-0.4",
+		},
+		DecimalEvaluationTest{
+
+			Name:     "Down rounding",
+			Input:    "2 / 3",
+			Rounding: &DecimalRounding{Scale: 1, Mode: ROUND_DOWN},
+			Expected: "0.6",
+		},
+		DecimalEvaluationTest{
+
+			Name:     "Ceiling rounding",
+			Input:    "-2 / 3",
+			Rounding: &DecimalRounding{Scale: 1, Mode: ROUND_CEILING},
+			Expected: "-0.6",
+		},
+		DecimalEvaluationTest{
+
+			Name:     "Floor rounding",
+			Input:    "2 / 3",
+			Rounding: &DecimalRounding{Scale: 0, Mode: ROUND_FLOOR},
+			Expected: "0",
+		},
+		DecimalEvaluationTest{
+
+			Name:     "Exact modulus",
+			Input:    "-7.5 % 2",
+			Expected: "-1.5",
+		},
+		DecimalEvaluationTest{
+
+			Name:     "Integer exponent",
+			Input:    "0.1 ** 3",
+			Expected: "0.001",
+		},
+		DecimalEvaluationTest{
+
+			Name:     "Negative exponent",
+			Input:    "2 ** -2",
+			Expected: "0.25",
+		},
+		DecimalEvaluationTest{
+
+			Name:     "Fractional exponent",
+			Input:    "4 ** 0.5",
+			Expected: "2",
+		},
+		DecimalEvaluationTest{
+
+			Name:     "Bitwise operators truncate",
+			Input:    "(5.9 | 2) << 1",
+			Expected: "14",
+		},
+		DecimalEvaluationTest{
+
+			Name:     "Negation",
+			Input:    "-(0.5)",
+			Expected: "-0.5",
+		},
+		DecimalEvaluationTest{
+
+			Name:     "Comparison",
+			Input:    "0.30000000000000001 > 0.3",
+			Expected: true,
+		},
+		DecimalEvaluationTest{
+
+			Name:     "Membership",
+			Input:    "1.50 in (1, 1.5)",
+			Expected: true,
+		},
+		DecimalEvaluationTest{
+
+			Name:     "Hex literal",
+			Input:    "0xFF",
+			Expected: "255",
+		},
+		DecimalEvaluationTest{
+
+			Name:  "Float parameter",
+			Input: "price * 3",
+			Parameters: map[string]interface{}{
+				"price": 0.1,
+			},
+			Expected: "0.3",
+		},
+		DecimalEvaluationTest{
+
+			Name:  "Float32 parameter",
+			Input: "price",
+			Parameters: map[string]interface{}{
+				"price": float32(0.1),
+			},
+			Expected: "0.1",
+		},
+		DecimalEvaluationTest{
+
+			Name:  "Integer parameters",
+			Input: "a - b",
+			Parameters: map[string]interface{}{
+				"a": uint8(3),
+				"b": int(5),
+			},
+			Expected: "-2",
+		},
+		DecimalEvaluationTest{
+
+			Name:  "Decimal parameter",
+			Input: "price + 1",
+			Parameters: map[string]interface{}{
+				"price": big.NewRat(1, 3),
+			},
+			Expected: "4/3",
+		},
+		DecimalEvaluationTest{
+
+			Name:  "Accessor field",
+			Input: "foo.Int + 0.5",
+			Parameters: map[string]interface{}{
+				"foo": dummyParameter{Int: 101},
+			},
+			Expected: "101.5",
+		},
+		DecimalEvaluationTest{
+
+			Name:     "String concatenation",
+			Input:    "'foo' + 1.50",
+			Expected: "foo1.5",
+		},
+		DecimalEvaluationTest{
+
+			Name:     "Repeating string concatenation",
+			Input:    "'' + 1 / 3",
+			Expected: "0.3333333333333333",
+		},
+	}
+
+	fmt.Printf("Running %d decimal evaluation test cases...\n", len(evaluationTests))
+
+	for _, evaluationTest := range evaluationTests {
+
+		var expression *EvaluableExpression
+		var err error
+
+		if evaluationTest.Rounding != nil {
+			expression, err = NewEvaluableExpressionWithDecimalRounding(evaluationTest.Input, nil, *evaluationTest.Rounding)
+		} else {
+			expression, err = NewEvaluableExpressionWithNumericMode(evaluationTest.Input, nil, DECIMAL_NUMERICS)
+		}
+
+		if err != nil {
+
+			test.Logf("Test '%s' failed to parse: '%s'", evaluationTest.Name, err)
+			test.Fail()
+			continue
+		}
+
+		result, err := expression.Evaluate(evaluationTest.Parameters)
+		if err != nil {
+
+			test.Logf("Test '%s' failed", evaluationTest.Name)
+			test.Logf("Encountered error: %s", err.Error())
+			test.Fail()
+			continue
+		}
+
+		if !decimalResultMatches(result, evaluationTest.Expected) {
+
+			test.Logf("Test '%s' failed", evaluationTest.Name)
+			test.Logf("Evaluation result '%v' (%T) does not match expected: '%v' (%T)", result, result, evaluationTest.Expected, evaluationTest.Expected)
+			test.Fail()
+		}
+	}
+
+	runEngineDecimalEvaluationTests(evaluationTests, test)
+}
+
+func TestDecimalEvaluationFailure(test *testing.T) {
+
+	evaluationTests := []EvaluationFailureTest{
+
+		EvaluationFailureTest{
+
+			Name:     "Division by zero",
+			Input:    "1 / (number - 1)",
+			Expected: "Decimal division by zero",
+		},
+		EvaluationFailureTest{
+
+			Name:     "Modulus by zero",
+			Input:    "1 % (number - 1)",
+			Expected: "Decimal modulus by zero",
+		},
+		EvaluationFailureTest{
+
+			Name:     "Zero to a negative power",
+			Input:    "(number - 1) ** -1",
+			Expected: "Decimal division by zero",
+		},
+		EvaluationFailureTest{
+
+			Name:     "Power of a power",
+			Input:    "((number + 12344) ** 65536) ** 65536",
+			Expected: "is too large to be computed",
+		},
+		EvaluationFailureTest{
+
+			Name:     "Negative power of a power",
+			Input:    "((number + 12344) ** -65536) ** 65536",
+			Expected: "is too large to be computed",
+		},
+		EvaluationFailureTest{
+
+			Name:     "Negative shift",
+			Input:    "1 << -number",
+			Expected: "negative amount",
+		},
+		EvaluationFailureTest{
+
+			Name:     "Non-numeric modifier",
+			Input:    "number - string",
+			Expected: INVALID_MODIFIER_TYPES,
+		},
+		EvaluationFailureTest{
+
+			Name:     "Non-numeric comparator",
+			Input:    "number > string",
+			Expected: INVALID_COMPARATOR_TYPES,
+		},
+	}
+
+	for _, testCase := range evaluationTests {
+
+		expression, err := NewEvaluableExpressionWithNumericMode(testCase.Input, nil, DECIMAL_NUMERICS)
+		if err != nil {
+
+			test.Logf("Test '%s' failed to parse: '%s'", testCase.Name, err)
+			test.Fail()
+			continue
+		}
+
+		_, err = expression.Evaluate(EVALUATION_FAILURE_PARAMETERS)
+		if err == nil || !strings.Contains(err.Error(), testCase.Expected) {
+
+			test.Logf("Test '%s' failed", testCase.Name)
+			test.Logf("Got error: '%v', expected '%s'", err, testCase.Expected)
+			test.Fail()
+		}
+	}
+
+	runEngineEvaluationFailureTests(evaluationTests, test, WithNumericMode(DECIMAL_NUMERICS))
+}
+
+func TestDecimalSQLQuery(test *testing.T) {
+
+	expression, err := NewEvaluableExpressionWithNumericMode("price > 0.10", nil, DECIMAL_NUMERICS)
+	if err != nil {
+		test.Logf("Failed to parse: %v", err)
+		test.Fail()
+		return
+	}
+
+	query, err := expression.ToSQLQuery()
+	if err != nil || query != "[price] > 0.1" {
+		test.Logf("Expected '[price] > 0.1', got '%s' (%v)", query, err)
+		test.Fail()
+	}
+}
+
+/*
+	Tests that changing a decimal result doesn't change the literal or parameter which it came from.
+*/
+func TestDecimalResultCopies(test *testing.T) {
+
+	inputs := []string{"1.5", "price", "flag ? 1.5 : price"}
+
+	for _, input := range inputs {
+		for _, engine := range evaluationEngines {
+
+			expression, err := Compile(input, WithNumericMode(DECIMAL_NUMERICS), WithEngine(engine))
+			if err != nil {
+				test.Logf("Failed to parse '%s': %v", input, err)
+				test.Fail()
+				continue
+			}
+
+			price := big.NewRat(3, 2)
+			parameters := MapParameters{"price": price, "flag": true}
+
+			result, _ := expression.Eval(parameters)
+			result.(*big.Rat).SetInt64(7)
+
+			results, _ := expression.EvalBatch([]Parameters{parameters}, 1)
+			results[0].(*big.Rat).SetInt64(7)
+
+			result, err = expression.Eval(parameters)
+			if err != nil || !decimalResultMatches(result, "1.5") || !decimalResultMatches(price, "1.5") {
+				test.Logf("Changing the result of '%s' with %v changed it to '%v' (%v), and the parameter to '%v'", input, engine, result, err, price)
+				test.Fail()
+			}
+		}
+	}
+}
+
+func decimalResultMatches(result interface{}, expected interface{}) bool {
+
+	expectedString, isString := expected.(string)
+	decimal, isDecimal := result.(*big.Rat)
+
+	if !isDecimal {
+		return result == expected
+	}
+	if !isString {
+		return false
+	}
+
+	// expected values may be in either fractional or decimal form.
+	expectedDecimal, ok := new(big.Rat).SetString(expectedString)
+	if ok {
+		return decimal.Cmp(expectedDecimal) == 0
+	}
+	return false
+}
@@ -0,0 +1,260 @@
+package govaluate
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+/*
+	Represents a test of correctly creating an Elasticsearch query from an expression.
+*/
+type ElasticsearchQueryTest struct {
+	Name     string
+	Input    string
+	Expected map[string]interface{}
+}
+
+/*
+	Shorthand for the queries built by these tests.
+*/
+type esMap map[string]interface{}
+type esList []interface{}
+
+func TestElasticsearchQuery(test *testing.T) {
+
+	testCases := []ElasticsearchQueryTest{
+
+		ElasticsearchQueryTest{
+
+			Name:     "Term",
+			Input:    "name == 'foo'",
+			Expected: esMap{"term": esMap{"name": "foo"}},
+		},
+		ElasticsearchQueryTest{
+
+			Name:     "Not term",
+			Input:    "name != 'foo'",
+			Expected: esMap{"bool": esMap{"must_not": esList{esMap{"term": esMap{"name": "foo"}}}}},
+		},
+		ElasticsearchQueryTest{
+
+			Name:  "Range",
+			Input: "a > 1 && b >= 2 && c < 3 && d <= 4",
+			Expected: esMap{"bool": esMap{"must": esList{
+				esMap{"range": esMap{"a": esMap{"gt": 1.0}}},
+				esMap{"range": esMap{"b": esMap{"gte": 2.0}}},
+				esMap{"range": esMap{"c": esMap{"lt": 3.0}}},
+				esMap{"range": esMap{"d": esMap{"lte": 4.0}}},
+			}}},
+		},
+		ElasticsearchQueryTest{
+
+			Name:     "Literal on the left",
+			Input:    "10 > age",
+			Expected: esMap{"range": esMap{"age": esMap{"lt": 10.0}}},
+		},
+		ElasticsearchQueryTest{
+
+			Name:     "Computed literal",
+			Input:    "age >= 6 * 3",
+			Expected: esMap{"range": esMap{"age": esMap{"gte": 18.0}}},
+		},
+		ElasticsearchQueryTest{
+
+			Name:  "Should",
+			Input: "a == 1 || (b == 2 || c == 3)",
+			Expected: esMap{"bool": esMap{"minimum_should_match": 1, "should": esList{
+				esMap{"term": esMap{"a": 1.0}},
+				esMap{"term": esMap{"b": 2.0}},
+				esMap{"term": esMap{"c": 3.0}},
+			}}},
+		},
+		ElasticsearchQueryTest{
+
+			Name:  "Nested bool",
+			Input: "a == 1 && !(b == 2 || c == 3)",
+			Expected: esMap{"bool": esMap{"must": esList{
+				esMap{"term": esMap{"a": 1.0}},
+				esMap{"bool": esMap{"must_not": esList{
+					esMap{"bool": esMap{"minimum_should_match": 1, "should": esList{
+						esMap{"term": esMap{"b": 2.0}},
+						esMap{"term": esMap{"c": 3.0}},
+					}}},
+				}}},
+			}}},
+		},
+		ElasticsearchQueryTest{
+
+			Name:     "Double inversion",
+			Input:    "!(name != 'foo')",
+			Expected: esMap{"term": esMap{"name": "foo"}},
+		},
+		ElasticsearchQueryTest{
+
+			Name:     "Object field",
+			Input:    "user.Address.City == 'Paris'",
+			Expected: esMap{"term": esMap{"user.Address.City": "Paris"}},
+		},
+		ElasticsearchQueryTest{
+
+			Name:  "Boolean field",
+			Input: "active && !deleted",
+			Expected: esMap{"bool": esMap{"must": esList{
+				esMap{"term": esMap{"active": true}},
+				esMap{"bool": esMap{"must_not": esList{esMap{"term": esMap{"deleted": true}}}}},
+			}}},
+		},
+		ElasticsearchQueryTest{
+
+			Name:  "Regexp",
+			Input: "name =~ 'fo+.*' && name !~ 'bar'",
+			Expected: esMap{"bool": esMap{"must": esList{
+				esMap{"regexp": esMap{"name": "fo+.*"}},
+				esMap{"bool": esMap{"must_not": esList{esMap{"regexp": esMap{"name": "bar"}}}}},
+			}}},
+		},
+		ElasticsearchQueryTest{
+
+			Name:     "Terms",
+			Input:    "tag in ('a', 'b', 'c')",
+			Expected: esMap{"terms": esMap{"tag": esList{"a", "b", "c"}}},
+		},
+		ElasticsearchQueryTest{
+
+			Name:  "Coalesce which matches missing fields",
+			Input: "(age ?? 0) < 18",
+			Expected: esMap{"bool": esMap{"minimum_should_match": 1, "should": esList{
+				esMap{"range": esMap{"age": esMap{"lt": 18.0}}},
+				esMap{"bool": esMap{"must_not": esList{esMap{"exists": esMap{"field": "age"}}}}},
+			}}},
+		},
+		ElasticsearchQueryTest{
+
+			Name:     "Coalesce which excludes missing fields",
+			Input:    "(age ?? 0) >= 18",
+			Expected: esMap{"range": esMap{"age": esMap{"gte": 18.0}}},
+		},
+		ElasticsearchQueryTest{
+
+			Name:  "Inverted coalesce which excludes missing fields",
+			Input: "(name ?? 'foo') != 'foo'",
+			Expected: esMap{"bool": esMap{"must": esList{
+				esMap{"exists": esMap{"field": "name"}},
+				esMap{"bool": esMap{"must_not": esList{esMap{"term": esMap{"name": "foo"}}}}},
+			}}},
+		},
+		ElasticsearchQueryTest{
+
+			Name:     "Time",
+			Input:    "created > '2014-01-02T00:00:00Z'",
+			Expected: esMap{"range": esMap{"created": esMap{"gt": time.Date(2014, 1, 2, 0, 0, 0, 0, time.UTC)}}},
+		},
+		ElasticsearchQueryTest{
+
+			Name:     "Constants",
+			Input:    "1 > 2",
+			Expected: esMap{"match_none": esMap{}},
+		},
+	}
+
+	test.Logf("Running %d Elasticsearch query test cases", len(testCases))
+
+	for _, testCase := range testCases {
+
+		expression, err := NewEvaluableExpression(testCase.Input)
+		if err != nil {
+
+			test.Logf("Test '%s' failed to parse: %s", testCase.Name, err)
+			test.Fail()
+			continue
+		}
+
+		actual, err := expression.ToElasticsearchQuery()
+		if err != nil {
+
+			test.Logf("Test '%s' failed to create query: %s", testCase.Name, err)
+			test.Fail()
+			continue
+		}
+
+		if !reflect.DeepEqual(normalizeElasticsearchQuery(actual), normalizeElasticsearchQuery(testCase.Expected)) {
+
+			test.Logf("Test '%s' did not create expected query.", testCase.Name)
+			test.Logf("Actual: '%v', expected '%v'", actual, testCase.Expected)
+			test.Fail()
+		}
+	}
+}
+
+func TestElasticsearchQueryFailure(test *testing.T) {
+
+	functions := map[string]ExpressionFunction{
+		"foo": func(arguments ...interface{}) (interface{}, error) {
+			return nil, nil
+		},
+	}
+
+	inputs := map[string]string{
+		"a + 1 > 2":       "Unable to write arithmetic '+' as an Elasticsearch query",
+		"2 < a * 3":       "Unable to write arithmetic '*' as an Elasticsearch query",
+		"-a == 1":         "Unable to write arithmetic '-' as an Elasticsearch query",
+		"a & 4":           "Unable to write arithmetic '&' as an Elasticsearch query",
+		"a > b":           "Only literals can be compared to parameters in an Elasticsearch query",
+		"a ? b : c":       "Unable to write operator ':' as an Elasticsearch query",
+		"foo() == 1":      "One side of '=' must be a parameter to be written as an Elasticsearch query",
+		"a.Method()":      "Unable to write method call 'a.Method' as an Elasticsearch query",
+		"'foo' =~ a":      "The left side of '=~' must be a parameter to be written as an Elasticsearch query",
+		"foo() && a == 1": "Unable to write function 'foo' as an Elasticsearch query",
+	}
+
+	test.Logf("Running %d Elasticsearch query failure test cases", len(inputs))
+
+	for input, expected := range inputs {
+
+		expression, err := NewEvaluableExpressionWithFunctions(input, functions)
+		if err != nil {
+
+			test.Logf("'%s' failed to parse: %s", input, err)
+			test.Fail()
+			continue
+		}
+
+		_, err = expression.ToElasticsearchQuery()
+		if err == nil || err.Error() != expected {
+
+			test.Logf("'%s' was expected to fail with '%s', but gave '%v'", input, expected, err)
+			test.Fail()
+		}
+	}
+}
+
+/*
+	Converts the shorthand types used by these tests into the types given by `ToElasticsearchQuery`, so they can be compared.
+*/
+func normalizeElasticsearchQuery(value interface{}) interface{} {
+
+	switch value.(type) {
+
+	case esMap:
+		return normalizeElasticsearchQuery(map[string]interface{}(value.(esMap)))
+
+	case map[string]interface{}:
+		ret := make(map[string]interface{})
+		for key, element := range value.(map[string]interface{}) {
+			ret[key] = normalizeElasticsearchQuery(element)
+		}
+		return ret
+
+	case esList:
+		return normalizeElasticsearchQuery([]interface{}(value.(esList)))
+
+	case []interface{}:
+		ret := make([]interface{}, 0)
+		for _, element := range value.([]interface{}) {
+			ret = append(ret, normalizeElasticsearchQuery(element))
+		}
+		return ret
+	}
+	return value
+}
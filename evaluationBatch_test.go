@@ -0,0 +1,252 @@
+package govaluate
+
+import (
+	"context"
+	"testing"
+)
+
+/*
+	Gives each of a slice of parameters in turn.
+*/
+type sliceIterator struct {
+	batch []Parameters
+}
+
+func (this *sliceIterator) Next() (Parameters, bool) {
+
+	if len(this.batch) == 0 {
+		return nil, false
+	}
+
+	ret := this.batch[0]
+	this.batch = this.batch[1:]
+	return ret, true
+}
+
+/*
+	Gives [remaining] parameters, each holding the index it was given at, and records the most it has given before they were evaluated.
+*/
+type countingIterator struct {
+	remaining int
+	given     int
+	evaluated int
+	pending   int
+}
+
+func (this *countingIterator) Next() (Parameters, bool) {
+
+	if this.remaining == 0 {
+		return nil, false
+	}
+
+	if this.given-this.evaluated > this.pending {
+		this.pending = this.given - this.evaluated
+	}
+
+	this.remaining--
+	this.given++
+	return &countedParameters{iterator: this, index: this.given - 1}, true
+}
+
+type countedParameters struct {
+	iterator *countingIterator
+	index    int
+}
+
+func (this *countedParameters) Get(name string) (interface{}, error) {
+
+	this.iterator.evaluated++
+	return this.index, nil
+}
+
+func TestEvalBatch(test *testing.T) {
+
+	var batch []Parameters
+
+	for i := 0; i < 100; i++ {
+		batch = append(batch, MapParameters{"a": i, "b": "foo"})
+	}
+
+	// one row is missing a parameter, and should fail on its own.
+	batch[42] = MapParameters{"b": "foo"}
+
+	for _, engine := range evaluationEngines {
+
+		expression, err := Compile("a % 2 == 0 ? b + a : a * 2", WithEngine(engine))
+		if err != nil {
+			test.Logf("Failed to parse: %v", err)
+			test.Fail()
+			return
+		}
+
+		for _, workers := range []int{0, 1, 3, 8, 200} {
+
+			results, errs := expression.EvalBatch(batch, workers)
+
+			if len(results) != len(batch) || len(errs) != len(batch) {
+				test.Logf("Expected %d results with %v and %d workers, got %d", len(batch), engine, workers, len(results))
+				test.Fail()
+				continue
+			}
+
+			for i, parameters := range batch {
+
+				expected, expectedErr := expression.Eval(parameters)
+
+				if results[i] != expected || (errs[i] == nil) != (expectedErr == nil) {
+					test.Logf("Row %d with %v and %d workers gave '%v' (%v), expected '%v' (%v)", i, engine, workers, results[i], errs[i], expected, expectedErr)
+					test.Fail()
+				}
+			}
+
+			if errs[42] == nil {
+				test.Logf("Expected the row without a parameter to fail with %v and %d workers", engine, workers)
+				test.Fail()
+			}
+		}
+	}
+}
+
+func TestEvalIterator(test *testing.T) {
+
+	batch := []Parameters{
+		MapParameters{"a": 1},
+		MapParameters{"a": 2},
+		nil,
+		MapParameters{"a": 3},
+	}
+
+	expression, _ := Compile("(a ?? 10) * 2", WithEngine(BYTECODE_ENGINE))
+
+	results, errs := expression.EvalIterator(&sliceIterator{batch}, 2)
+
+	if len(results) != 4 || results[0] != 2.0 || results[1] != 4.0 || results[3] != 6.0 {
+		test.Logf("Unexpected results: %v", results)
+		test.Fail()
+	}
+
+	// nil parameters are the same as no parameters, so 'a' is missing.
+	if errs[2] == nil || errs[0] != nil {
+		test.Logf("Unexpected errors: %v", errs)
+		test.Fail()
+	}
+}
+
+func TestEvalIteratorChunks(test *testing.T) {
+
+	iterator := &countingIterator{remaining: iteratorChunkSize*2 + 10}
+	expression, _ := NewEvaluableExpression("a")
+
+	results, _ := expression.EvalIterator(iterator, 1)
+
+	if len(results) != iteratorChunkSize*2+10 {
+		test.Logf("Expected %d results, got %d", iteratorChunkSize*2+10, len(results))
+		test.Fail()
+		return
+	}
+
+	for i, result := range results {
+		if result != float64(i) {
+			test.Logf("Expected result %d to be %d, got %v", i, i, result)
+			test.Fail()
+			return
+		}
+	}
+
+	// parameters are evaluated a chunk at a time, rather than all being taken first.
+	if iterator.pending > iteratorChunkSize {
+		test.Logf("Expected at most %d parameters to be taken before being evaluated, got %d", iteratorChunkSize, iterator.pending)
+		test.Fail()
+	}
+}
+
+/*
+	Cancels [cancel] once the wrapped iterator has given [limit] parameters.
+*/
+type cancellingIterator struct {
+	countingIterator
+	limit  int
+	cancel context.CancelFunc
+}
+
+func (this *cancellingIterator) Next() (Parameters, bool) {
+
+	if this.given == this.limit {
+		this.cancel()
+	}
+	return this.countingIterator.Next()
+}
+
+func TestEvalIteratorContext(test *testing.T) {
+
+	expression, _ := NewEvaluableExpression("a")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	iterator := &countingIterator{remaining: 10}
+
+	results, errs := expression.EvalIteratorContext(ctx, iterator, 1)
+	if len(results) != 0 || len(errs) != 0 || iterator.given != 0 {
+		test.Logf("Expected no parameters to be taken with a cancelled context, got %d results and %d taken", len(results), iterator.given)
+		test.Fail()
+	}
+
+	// cancelling while a chunk is being taken stops any more chunks being taken.
+	ctx, cancel = context.WithCancel(context.Background())
+	cancelling := &cancellingIterator{countingIterator: countingIterator{remaining: iteratorChunkSize * 3}, limit: 10, cancel: cancel}
+
+	results, errs = expression.EvalIteratorContext(ctx, cancelling, 1)
+	if len(results) != iteratorChunkSize || cancelling.given != iteratorChunkSize {
+		test.Logf("Expected one chunk of %d parameters to be taken, got %d results and %d taken", iteratorChunkSize, len(results), cancelling.given)
+		test.Fail()
+		return
+	}
+
+	if errs[0] != context.Canceled {
+		test.Logf("Expected the taken parameters to be cancelled, got '%v'", errs[0])
+		test.Fail()
+	}
+}
+
+func TestEvalBatchBudget(test *testing.T) {
+
+	parameters := MapParameters(EVALUATION_FAILURE_PARAMETERS)
+	batch := []Parameters{parameters, parameters, parameters}
+
+	for _, engine := range evaluationEngines {
+
+		expression, _ := Compile("number + number", WithEngine(engine), WithBudget(EvaluationBudget{MaxStages: 3}))
+
+		_, errs := expression.EvalBatch(batch, 1)
+
+		for i, err := range errs {
+			if err != nil {
+				test.Logf("Evaluation %d with %v exceeded its budget: %v", i, engine, err)
+				test.Fail()
+			}
+		}
+	}
+}
+
+func TestEvalBatchContext(test *testing.T) {
+
+	batch := []Parameters{DUMMY_PARAMETERS, DUMMY_PARAMETERS}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for _, engine := range evaluationEngines {
+
+		expression, _ := Compile("1 + 1", WithEngine(engine))
+
+		_, errs := expression.EvalBatchContext(ctx, batch, 2)
+
+		for i, err := range errs {
+			if err != context.Canceled {
+				test.Logf("Expected evaluation %d with %v to be cancelled, got '%v'", i, engine, err)
+				test.Fail()
+			}
+		}
+	}
+}
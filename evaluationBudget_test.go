@@ -0,0 +1,166 @@
+package govaluate
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+/*
+	Represents a test of an expression evaluated under an EvaluationBudget.
+	If [Exceeds] is true, evaluation is expected to fail with an ErrBudgetExceeded for [Limit].
+*/
+type BudgetTest struct {
+	Name      string
+	Input     string
+	Functions map[string]ExpressionFunction
+	Budget    EvaluationBudget
+	Exceeds   bool
+	Limit     BudgetLimit
+}
+
+func TestEvaluationBudget(test *testing.T) {
+
+	functions := map[string]ExpressionFunction{
+		"one": func(arguments ...interface{}) (interface{}, error) {
+			return 1.0, nil
+		},
+		"repeat": func(arguments ...interface{}) (interface{}, error) {
+			return strings.Repeat(arguments[0].(string), int(arguments[1].(float64))), nil
+		},
+	}
+
+	budgetTests := []BudgetTest{
+
+		BudgetTest{
+
+			Name:   "Unlimited",
+			Input:  "one() + one() + one()",
+			Budget: EvaluationBudget{},
+		},
+		BudgetTest{
+
+			Name:   "Within stage budget",
+			Input:  "number + number",
+			Budget: EvaluationBudget{MaxStages: 3},
+		},
+		BudgetTest{
+
+			Name:    "Exceeds stage budget",
+			Input:   "number + number + number",
+			Budget:  EvaluationBudget{MaxStages: 3},
+			Exceeds: true,
+			Limit:   STAGE_BUDGET,
+		},
+		BudgetTest{
+
+			Name:   "Short circuit does not spend",
+			Input:  "false && (number + number > 1)",
+			Budget: EvaluationBudget{MaxStages: 2},
+		},
+		BudgetTest{
+
+			Name:   "Within function call budget",
+			Input:  "one() + one()",
+			Budget: EvaluationBudget{MaxFunctionCalls: 2},
+		},
+		BudgetTest{
+
+			Name:    "Exceeds function call budget",
+			Input:   "one() + one() + one()",
+			Budget:  EvaluationBudget{MaxFunctionCalls: 2},
+			Exceeds: true,
+			Limit:   FUNCTION_CALL_BUDGET,
+		},
+		BudgetTest{
+
+			Name:   "Within string length budget",
+			Input:  "string + string",
+			Budget: EvaluationBudget{MaxStringLength: 6},
+		},
+		BudgetTest{
+
+			Name:    "Exceeds string length budget",
+			Input:   "string + string + string",
+			Budget:  EvaluationBudget{MaxStringLength: 6},
+			Exceeds: true,
+			Limit:   STRING_LENGTH_BUDGET,
+		},
+		BudgetTest{
+
+			Name:   "String length only limits concatenation",
+			Input:  "repeat(string, 10) == ''",
+			Budget: EvaluationBudget{MaxStringLength: 6},
+		},
+		BudgetTest{
+
+			Name:   "Within array length budget",
+			Input:  "1 in (1, 2, 3)",
+			Budget: EvaluationBudget{MaxArrayLength: 3},
+		},
+		BudgetTest{
+
+			Name:    "Exceeds array length budget",
+			Input:   "1 in (1, 2, 3, 4)",
+			Budget:  EvaluationBudget{MaxArrayLength: 3},
+			Exceeds: true,
+			Limit:   ARRAY_LENGTH_BUDGET,
+		},
+	}
+
+	fmt.Printf("Running %d budget test cases...\n", len(budgetTests))
+
+	for _, budgetTest := range budgetTests {
+
+		expression, err := NewEvaluableExpressionWithFunctions(budgetTest.Input, functions)
+		if err != nil {
+
+			test.Logf("Test '%s' failed to parse: '%s'", budgetTest.Name, err)
+			test.Fail()
+			continue
+		}
+
+		expression.Budget = budgetTest.Budget
+
+		_, err = expression.Evaluate(EVALUATION_FAILURE_PARAMETERS)
+
+		if !budgetTest.Exceeds {
+
+			if err != nil {
+				test.Logf("Test '%s' failed", budgetTest.Name)
+				test.Logf("Encountered error: %s", err.Error())
+				test.Fail()
+			}
+			continue
+		}
+
+		exceeded, ok := err.(ErrBudgetExceeded)
+		if !ok || exceeded.Limit != budgetTest.Limit {
+
+			test.Logf("Test '%s' failed", budgetTest.Name)
+			test.Logf("Expected budget error for %v, got '%v'", budgetTest.Limit, err)
+			test.Fail()
+		}
+	}
+}
+
+func TestEvaluationBudgetIsPerEvaluation(test *testing.T) {
+
+	expression, err := NewEvaluableExpression("number + number")
+	if err != nil {
+		test.Logf("Failed to parse: %v", err)
+		test.Fail()
+		return
+	}
+
+	expression.Budget = EvaluationBudget{MaxStages: 3}
+
+	for i := 0; i < 3; i++ {
+
+		_, err = expression.Evaluate(EVALUATION_FAILURE_PARAMETERS)
+		if err != nil {
+			test.Logf("Evaluation %d exceeded its budget: %v", i, err)
+			test.Fail()
+		}
+	}
+}
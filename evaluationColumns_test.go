@@ -0,0 +1,211 @@
+package govaluate
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+/*
+	Represents a test of evaluating an expression over columns, which should give the same result for each row as evaluating that row alone.
+*/
+type ColumnTest struct {
+	Name     string
+	Input    string
+	Options  []Option
+	Expected interface{}
+}
+
+func TestEvalColumns(test *testing.T) {
+
+	var calls int
+
+	functions := map[string]ExpressionFunction{
+		"count": func(arguments ...interface{}) (interface{}, error) {
+			calls++
+			return arguments[0], nil
+		},
+		"check": func(arguments ...interface{}) (interface{}, error) {
+			if arguments[0] == "" {
+				return nil, errors.New("Empty name")
+			}
+			return true, nil
+		},
+	}
+
+	columns := map[string]interface{}{
+		"price":  []float64{1, 2.5, 10, 0, -3},
+		"name":   []string{"foo", "bar", "", "baz", "foo"},
+		"active": []bool{true, false, true, true, false},
+		"mixed":  []interface{}{1, nil, "x", 2.5, true},
+		"extra":  []interface{}{nil, 4, nil, 1, nil},
+	}
+
+	columnTests := []ColumnTest{
+
+		ColumnTest{
+
+			Name:     "Arithmetic",
+			Input:    "price * 2 + 1",
+			Expected: []float64{3, 6, 21, 1, -5},
+		},
+		ColumnTest{
+
+			Name:     "Filter",
+			Input:    "price > 1 && active || name == 'foo'",
+			Expected: []bool{true, false, true, false, true},
+		},
+		ColumnTest{
+
+			Name:     "String operators",
+			Input:    "name + '!' >= 'c'",
+			Expected: []bool{true, false, false, false, true},
+		},
+		ColumnTest{
+
+			Name:     "Prefixes",
+			Input:    "!active ? -price : price",
+			Expected: []float64{1, -2.5, 10, 0, 3},
+		},
+		ColumnTest{
+
+			Name:     "Mixed types",
+			Input:    "mixed ?? 'none'",
+			Expected: []interface{}{1.0, "none", "x", 2.5, true},
+		},
+		ColumnTest{
+
+			Name:     "Coalescing numbers",
+			Input:    "(extra ?? 0) + price",
+			Expected: []float64{1, 6.5, 10, 1, -3},
+		},
+		ColumnTest{
+
+			Name:     "Concatenation",
+			Input:    "name + price",
+			Expected: []string{"foo1", "bar2.5", "10", "baz0", "foo-3"},
+		},
+		ColumnTest{
+
+			Name:     "Membership",
+			Input:    "price in (1, 10, 20)",
+			Expected: []bool{true, false, true, false, false},
+		},
+		ColumnTest{
+
+			Name:     "Regex",
+			Input:    "name =~ '^ba'",
+			Expected: []bool{false, true, false, true, false},
+		},
+		ColumnTest{
+
+			Name:     "Short-circuited errors",
+			Input:    "active && check(name)",
+			Options:  []Option{WithFunctions(functions)},
+			Expected: []bool{true, false, false, true, false},
+		},
+		ColumnTest{
+
+			Name:     "Type errors",
+			Input:    "mixed > 1",
+			Expected: []bool{false, false, false, true, false},
+		},
+		ColumnTest{
+
+			Name:     "Missing column",
+			Input:    "active || missing",
+			Expected: []bool{true, false, true, true, false},
+		},
+		ColumnTest{
+
+			Name:     "Custom operator",
+			Input:    "price + 1",
+			Options:  []Option{WithOperator(PLUS, func(left interface{}, right interface{}) (interface{}, error) { return left.(float64) * 10, nil })},
+			Expected: []float64{10, 25, 100, 0, -30},
+		},
+		ColumnTest{
+
+			Name:     "Integer numerics",
+			Input:    "price * 2",
+			Options:  []Option{WithNumericMode(INTEGER_NUMERICS)},
+			Expected: []float64{2, 5, 20, 0, -6},
+		},
+		ColumnTest{
+
+			Name:     "Budget",
+			Input:    "name + name",
+			Options:  []Option{WithBudget(EvaluationBudget{MaxStringLength: 4})},
+			Expected: []string{"", "", "", "", ""},
+		},
+	}
+
+	test.Logf("Running %d column test cases", len(columnTests))
+
+	for _, columnTest := range columnTests {
+
+		expression, err := Compile(columnTest.Input, append(columnTest.Options, WithFunctions(functions))...)
+		if err != nil {
+			test.Logf("Test '%s' failed to parse: %s", columnTest.Name, err)
+			test.Fail()
+			continue
+		}
+
+		result, err := expression.EvalColumns(columns)
+		if err != nil {
+			test.Logf("Test '%s' failed: %s", columnTest.Name, err)
+			test.Fail()
+			continue
+		}
+
+		if !reflect.DeepEqual(result.Values, columnTest.Expected) {
+			test.Logf("Test '%s' gave %#v, expected %#v", columnTest.Name, result.Values, columnTest.Expected)
+			test.Fail()
+			continue
+		}
+
+		// each row must fail in the same way as evaluating it alone. failed rows are left as the zero value in typed results.
+		for row := 0; row < 5; row++ {
+
+			parameters := &columnParameters{columns: columns, row: row}
+			_, expectedErr := expression.Eval(parameters)
+
+			var actualErr error
+			if result.Errors != nil {
+				actualErr = result.Errors[row]
+			}
+
+			if (actualErr == nil) != (expectedErr == nil) || (actualErr != nil && actualErr.Error() != expectedErr.Error()) {
+				test.Logf("Test '%s' row %d failed with '%v', expected '%v'", columnTest.Name, row, actualErr, expectedErr)
+				test.Fail()
+			}
+		}
+	}
+
+	// functions are only called for rows which aren't short-circuited.
+	calls = 0
+	expression, _ := Compile("active && count(price) > 0", WithFunctions(functions))
+	expression.EvalColumns(columns)
+
+	if calls != 3 {
+		test.Logf("Expected 3 calls, got %d", calls)
+		test.Fail()
+	}
+}
+
+func TestEvalColumnsFailure(test *testing.T) {
+
+	expression, _ := NewEvaluableExpression("a > b")
+
+	_, err := expression.EvalColumns(map[string]interface{}{"a": []float64{1, 2}, "b": []float64{1}})
+	if err == nil || !strings.Contains(err.Error(), "has 1 rows") {
+		test.Logf("Expected columns of different lengths to fail, got '%v'", err)
+		test.Fail()
+	}
+
+	_, err = expression.EvalColumns(map[string]interface{}{"a": []int{1, 2}})
+	if err == nil || !strings.Contains(err.Error(), "[]int") {
+		test.Logf("Expected a column of an unsupported type to fail, got '%v'", err)
+		test.Fail()
+	}
+}
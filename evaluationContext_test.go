@@ -0,0 +1,187 @@
+package govaluate
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type contextTestKey string
+
+func TestEvalContextCancelled(test *testing.T) {
+
+	expression, err := NewEvaluableExpression("1 + 1")
+	if err != nil {
+		test.Logf("Failed to parse: %v", err)
+		test.Fail()
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = expression.EvalContext(ctx, nil)
+	if err != context.Canceled {
+		test.Logf("Expected '%v', got '%v'", context.Canceled, err)
+		test.Fail()
+	}
+}
+
+func TestEvalContextStopsBetweenStages(test *testing.T) {
+
+	var called bool
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	functions := map[string]ExpressionFunction{
+		"cancel": func(arguments ...interface{}) (interface{}, error) {
+			cancel()
+			return true, nil
+		},
+		"next": func(arguments ...interface{}) (interface{}, error) {
+			called = true
+			return true, nil
+		},
+	}
+
+	expression, err := NewEvaluableExpressionWithFunctions("cancel() && next()", functions)
+	if err != nil {
+		test.Logf("Failed to parse: %v", err)
+		test.Fail()
+		return
+	}
+
+	_, err = expression.EvalContext(ctx, nil)
+	if err != context.Canceled {
+		test.Logf("Expected '%v', got '%v'", context.Canceled, err)
+		test.Fail()
+	}
+
+	if called {
+		test.Logf("Expected evaluation to stop before calling 'next'")
+		test.Fail()
+	}
+}
+
+func TestContextFunctions(test *testing.T) {
+
+	functions := map[string]ContextExpressionFunction{
+		"lookup": func(ctx context.Context, arguments ...interface{}) (interface{}, error) {
+
+			value, _ := ctx.Value(contextTestKey("prefix")).(string)
+			return value + arguments[0].(string), nil
+		},
+		"wait": func(ctx context.Context, arguments ...interface{}) (interface{}, error) {
+
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(time.Second):
+				return nil, errors.New("Function was not cancelled")
+			}
+		},
+		"background": func(ctx context.Context, arguments ...interface{}) (interface{}, error) {
+			return ctx == context.Background(), nil
+		},
+	}
+
+	expression, err := NewEvaluableExpressionWithContextFunctions("lookup(foo)", functions)
+	if err != nil {
+		test.Logf("Failed to parse: %v", err)
+		test.Fail()
+		return
+	}
+
+	ctx := context.WithValue(context.Background(), contextTestKey("prefix"), "pre-")
+
+	result, err := expression.EvalContext(ctx, MapParameters{"foo": "bar"})
+	if err != nil || result != "pre-bar" {
+		test.Logf("Expected 'pre-bar', got '%v' (%v)", result, err)
+		test.Fail()
+	}
+
+	expression, err = NewEvaluableExpressionWithContextFunctions("wait()", functions)
+	if err != nil {
+		test.Logf("Failed to parse: %v", err)
+		test.Fail()
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err = expression.EvalContext(ctx, nil)
+	if err != context.DeadlineExceeded {
+		test.Logf("Expected '%v', got '%v'", context.DeadlineExceeded, err)
+		test.Fail()
+	}
+
+	expression, err = NewEvaluableExpressionWithContextFunctions("background()", functions)
+	if err != nil {
+		test.Logf("Failed to parse: %v", err)
+		test.Fail()
+		return
+	}
+
+	result, err = expression.Eval(nil)
+	if err != nil || result != true {
+		test.Logf("Expected a background context when using Eval, got '%v' (%v)", result, err)
+		test.Fail()
+	}
+}
+
+/*
+	Tests that context functions are found by what each token holds, rather than by its text.
+*/
+func TestContextFunctionTokens(test *testing.T) {
+
+	lookup := func(ctx context.Context, arguments ...interface{}) (interface{}, error) {
+		return ctx.Value(contextTestKey("prefix")), nil
+	}
+
+	ctx := context.WithValue(context.Background(), contextTestKey("prefix"), "pre-")
+
+	tokens := []ExpressionToken{
+		ExpressionToken{Kind: FUNCTION, Value: ContextExpressionFunction(lookup)},
+		ExpressionToken{Kind: CLAUSE, Value: '('},
+		ExpressionToken{Kind: CLAUSE_CLOSE, Value: ')'},
+	}
+
+	expression, err := NewEvaluableExpressionFromTokens(tokens)
+	if err != nil {
+		test.Logf("Failed to parse: %v", err)
+		test.Fail()
+		return
+	}
+
+	result, err := expression.EvalContext(ctx, nil)
+	if err != nil || result != "pre-" {
+		test.Logf("Expected 'pre-', got '%v' (%v)", result, err)
+		test.Fail()
+	}
+
+	function, isFunction := expression.AST().(*FunctionNode)
+	if !isFunction || function.ContextFunction == nil || function.Name != "" {
+		test.Logf("Expected an unnamed context function, got %#v", expression.AST())
+		test.Fail()
+	}
+
+	_, err = expression.Format()
+	if err == nil {
+		test.Logf("Expected a function with no name to fail to format")
+		test.Fail()
+	}
+
+	// the last option given for a name decides which kind of function it is.
+	expression, _ = Compile("lookup()",
+		WithFunctions(map[string]ExpressionFunction{"lookup": func(arguments ...interface{}) (interface{}, error) { return "plain", nil }}),
+		WithContextFunctions(map[string]ContextExpressionFunction{"lookup": lookup}),
+	)
+
+	_, isContext := expression.Tokens()[0].Value.(ContextExpressionFunction)
+	if !isContext || expression.AST().(*FunctionNode).Name != "lookup" {
+		test.Logf("Expected the token to hold the context function, got %#v", expression.Tokens()[0])
+		test.Fail()
+	}
+}
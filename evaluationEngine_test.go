@@ -0,0 +1,352 @@
+package govaluate
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+/*
+	Every engine, each of which the evaluation tests are run with.
+*/
+var evaluationEngines = []EvaluationEngine{STAGE_ENGINE, CLOSURE_ENGINE, BYTECODE_ENGINE}
+
+/*
+	Runs the given evaluation tests (which the stage engine already passes) with every other engine,
+	compiling each with the given [options].
+*/
+func runEngineEvaluationTests(evaluationTests []EvaluationTest, test *testing.T, options ...Option) {
+
+	fmt.Printf("Running %d engine evaluation test cases...\n", len(evaluationTests))
+
+	for _, evaluationTest := range evaluationTests {
+		for _, engine := range evaluationEngines {
+
+			if engine == STAGE_ENGINE {
+				continue
+			}
+
+			expression, err := Compile(evaluationTest.Input, append([]Option{WithFunctions(evaluationTest.Functions), WithEngine(engine)}, options...)...)
+			if err != nil {
+
+				test.Logf("Test '%s' failed to parse: '%s'", evaluationTest.Name, err)
+				test.Fail()
+				continue
+			}
+
+			parameters := make(map[string]interface{}, 8)
+			for _, parameter := range evaluationTest.Parameters {
+				parameters[parameter.Name] = parameter.Value
+			}
+
+			result, err := expression.Evaluate(parameters)
+			if err != nil {
+
+				test.Logf("Test '%s' failed with %v", evaluationTest.Name, engine)
+				test.Logf("Encountered error: %s", err.Error())
+				test.Fail()
+				continue
+			}
+
+			if result != evaluationTest.Expected {
+
+				test.Logf("Test '%s' failed with %v", evaluationTest.Name, engine)
+				test.Logf("Evaluation result '%v' (%T) does not match expected: '%v' (%T)", result, result, evaluationTest.Expected, evaluationTest.Expected)
+				test.Fail()
+			}
+		}
+	}
+}
+
+/*
+	Runs the given evaluation failure tests (which the stage engine already passes) with every other engine,
+	compiling each with the given [options].
+*/
+func runEngineEvaluationFailureTests(evaluationTests []EvaluationFailureTest, test *testing.T, options ...Option) {
+
+	fmt.Printf("Running %d engine negative evaluation test cases...\n", len(evaluationTests))
+
+	for _, testCase := range evaluationTests {
+		for _, engine := range evaluationEngines {
+
+			if engine == STAGE_ENGINE {
+				continue
+			}
+
+			expression, err := Compile(testCase.Input, append([]Option{WithFunctions(testCase.Functions), WithEngine(engine)}, options...)...)
+			if err != nil {
+
+				test.Logf("Test '%s' failed", testCase.Name)
+				test.Logf("Expected evaluation error, but got parsing error: '%s'", err)
+				test.Fail()
+				continue
+			}
+
+			parameters := testCase.Parameters
+			if parameters == nil {
+				parameters = EVALUATION_FAILURE_PARAMETERS
+			}
+
+			_, err = expression.Evaluate(parameters)
+			if err == nil || !strings.Contains(err.Error(), testCase.Expected) {
+
+				test.Logf("Test '%s' failed with %v", testCase.Name, engine)
+				test.Logf("Got error: '%v', expected '%s'", err, testCase.Expected)
+				test.Fail()
+			}
+		}
+	}
+}
+
+/*
+	Runs the given decimal evaluation tests (which the stage engine already passes) with every other engine.
+*/
+func runEngineDecimalEvaluationTests(evaluationTests []DecimalEvaluationTest, test *testing.T) {
+
+	fmt.Printf("Running %d engine decimal evaluation test cases...\n", len(evaluationTests))
+
+	for _, evaluationTest := range evaluationTests {
+		for _, engine := range evaluationEngines {
+
+			if engine == STAGE_ENGINE {
+				continue
+			}
+
+			options := []Option{WithNumericMode(DECIMAL_NUMERICS), WithEngine(engine)}
+			if evaluationTest.Rounding != nil {
+				options = append(options, WithDecimalRounding(*evaluationTest.Rounding))
+			}
+
+			expression, err := Compile(evaluationTest.Input, options...)
+			if err != nil {
+
+				test.Logf("Test '%s' failed to parse: '%s'", evaluationTest.Name, err)
+				test.Fail()
+				continue
+			}
+
+			result, err := expression.Evaluate(evaluationTest.Parameters)
+			if err != nil {
+
+				test.Logf("Test '%s' failed with %v", evaluationTest.Name, engine)
+				test.Logf("Encountered error: %s", err.Error())
+				test.Fail()
+				continue
+			}
+
+			if !decimalResultMatches(result, evaluationTest.Expected) {
+
+				test.Logf("Test '%s' failed with %v", evaluationTest.Name, engine)
+				test.Logf("Evaluation result '%v' (%T) does not match expected: '%v' (%T)", result, result, evaluationTest.Expected, evaluationTest.Expected)
+				test.Fail()
+			}
+		}
+	}
+}
+
+/*
+	Represents a test of which type checks are hoisted out of the root stage of an expression.
+*/
+type HoistingTest struct {
+	Name     string
+	Input    string
+	Options  []Option
+	Left     bool
+	Right    bool
+	Combined bool
+}
+
+func TestClosureTypeCheckHoisting(test *testing.T) {
+
+	hoistingTests := []HoistingTest{
+
+		HoistingTest{
+
+			Name:  "Logical between comparisons",
+			Input: "a > 1 && b < 2",
+		},
+		HoistingTest{
+
+			Name:  "Logical between parameters",
+			Input: "a && b",
+			Left:  true,
+			Right: true,
+		},
+		HoistingTest{
+
+			Name:  "Logical with one comparison",
+			Input: "a || b == 2",
+			Left:  true,
+		},
+		HoistingTest{
+
+			Name:  "Arithmetic on arithmetic",
+			Input: "(a - b) * 2",
+		},
+		HoistingTest{
+
+			Name:    "Integer arithmetic on arithmetic",
+			Input:   "(a - b) * 2",
+			Options: []Option{WithNumericMode(INTEGER_NUMERICS)},
+			Left:    true,
+		},
+		HoistingTest{
+
+			Name:     "Comparison with literal",
+			Input:    "a > 1",
+			Combined: true,
+		},
+		HoistingTest{
+
+			Name:    "Logical on a replaced operator",
+			Input:   "a == 1 && b",
+			Options: []Option{WithOperator(EQ, func(left interface{}, right interface{}) (interface{}, error) { return left, nil })},
+			Left:    true,
+			Right:   true,
+		},
+	}
+
+	test.Logf("Running %d type check hoisting test cases", len(hoistingTests))
+
+	for _, hoistingTest := range hoistingTests {
+
+		expression, err := Compile(hoistingTest.Input, hoistingTest.Options...)
+		if err != nil {
+
+			test.Logf("Test '%s' failed to parse: %s", hoistingTest.Name, err)
+			test.Fail()
+			continue
+		}
+
+		options := newCompileOptions(hoistingTest.Options)
+		checks := hoistTypeChecks(expression.evaluationStages, options)
+
+		if (checks.left != nil) != hoistingTest.Left ||
+			(checks.right != nil) != hoistingTest.Right ||
+			(checks.combined != nil) != hoistingTest.Combined {
+
+			test.Logf("Test '%s' failed", hoistingTest.Name)
+			test.Logf("Expected checks (left %v, right %v, combined %v), got (left %v, right %v, combined %v)",
+				hoistingTest.Left, hoistingTest.Right, hoistingTest.Combined,
+				checks.left != nil, checks.right != nil, checks.combined != nil)
+			test.Fail()
+		}
+	}
+}
+
+func TestCompiledEngineSettings(test *testing.T) {
+
+	for _, engine := range []EvaluationEngine{CLOSURE_ENGINE, BYTECODE_ENGINE} {
+
+		expression, err := Compile("number + string", WithEngine(engine))
+		if err != nil {
+			test.Logf("Failed to parse: %v", err)
+			test.Fail()
+			return
+		}
+
+		// settings changed after compiling still apply.
+		expression.Budget = EvaluationBudget{MaxStringLength: 3}
+
+		_, err = expression.Evaluate(EVALUATION_FAILURE_PARAMETERS)
+		if _, exceeded := err.(ErrBudgetExceeded); !exceeded {
+			test.Logf("Expected the string length budget to be exceeded with %v, got '%v'", engine, err)
+			test.Fail()
+		}
+
+		expression, _ = Compile("number - string", WithEngine(engine))
+
+		_, err = expression.Evaluate(EVALUATION_FAILURE_PARAMETERS)
+		if err == nil || !strings.Contains(err.Error(), INVALID_MODIFIER_TYPES) {
+			test.Logf("Expected a type error with %v, got '%v'", engine, err)
+			test.Fail()
+		}
+
+		expression.ChecksTypes = false
+
+		func() {
+			defer func() {
+				if recover() == nil {
+					test.Logf("Expected a panic with type checks turned off with %v", engine)
+					test.Fail()
+				}
+			}()
+			expression.Evaluate(EVALUATION_FAILURE_PARAMETERS)
+		}()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		expression, _ = Compile("1 + 1", WithEngine(engine))
+
+		_, err = expression.EvalContext(ctx, nil)
+		if err != context.Canceled {
+			test.Logf("Expected '%v' with %v, got '%v'", context.Canceled, engine, err)
+			test.Fail()
+		}
+	}
+
+	_, err := Compile("1", WithEngine(EvaluationEngine(-1)))
+	if err == nil {
+		test.Logf("Expected an unknown engine to be rejected")
+		test.Fail()
+	}
+}
+
+func TestBytecodeProgram(test *testing.T) {
+
+	expression, err := Compile("(a > 1 && b) ? c ?? 2 : 3", WithEngine(BYTECODE_ENGINE))
+	if err != nil {
+		test.Logf("Failed to parse: %v", err)
+		test.Fail()
+		return
+	}
+
+	// '??' binds more tightly than ':', but less tightly than '?', so the ternary's true side is only 'c'.
+	expected := []bytecodeOpcode{
+		loadOpcode, pushOpcode, applyOpcode, jumpIfFalseOpcode, loadOpcode, applyOpcode,
+		jumpIfFalseOpcode, loadOpcode, jumpOpcode, pushOpcode, applyOpcode,
+		jumpIfNotNilOpcode, pushOpcode, applyOpcode,
+		jumpIfNotNilOpcode, pushOpcode, jumpOpcode, pushOpcode, applyOpcode,
+	}
+
+	var actual []bytecodeOpcode
+	for _, instruction := range expression.program.instructions {
+		actual = append(actual, instruction.opcode)
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		test.Logf("Expected opcodes %v, got %v", expected, actual)
+		test.Fail()
+		return
+	}
+
+	// the first instruction enters ':', '??', '?', the parentheses, '&&', '>' and 'a'.
+	if len(expression.program.instructions[0].enters) != 7 {
+		test.Logf("Expected the first instruction to enter 7 stages, but it enters %d", len(expression.program.instructions[0].enters))
+		test.Fail()
+	}
+
+	result, err := expression.Evaluate(map[string]interface{}{"a": 2, "b": true, "c": nil})
+	if err != nil || result != 2.0 {
+		test.Logf("Expected 2, got '%v' (%v)", result, err)
+		test.Fail()
+	}
+}
+
+func TestBytecodeAllocations(test *testing.T) {
+
+	expression, _ := Compile("(a > 1 && b < 2) || c == 'foo'", WithEngine(BYTECODE_ENGINE))
+	parameters := MapParameters{"a": 2.0, "b": 1.0, "c": "bar"}
+
+	allocations := testing.AllocsPerRun(100, func() {
+		expression.Eval(parameters)
+	})
+
+	if allocations != 0 {
+		test.Logf("Expected evaluation to allocate nothing, but it allocated %v times", allocations)
+		test.Fail()
+	}
+}
@@ -188,6 +188,7 @@ func TestModifierTyping(test *testing.T) {
 	}
 
 	runEvaluationFailureTests(evaluationTests, test)
+	runEngineEvaluationFailureTests(evaluationTests, test)
 }
 
 func TestLogicalOperatorTyping(test *testing.T) {
@@ -244,6 +245,7 @@ func TestLogicalOperatorTyping(test *testing.T) {
 	}
 
 	runEvaluationFailureTests(evaluationTests, test)
+	runEngineEvaluationFailureTests(evaluationTests, test)
 }
 
 /*
@@ -371,6 +373,7 @@ func TestComparatorTyping(test *testing.T) {
 	}
 
 	runEvaluationFailureTests(evaluationTests, test)
+	runEngineEvaluationFailureTests(evaluationTests, test)
 }
 
 func TestTernaryTyping(test *testing.T) {
@@ -391,6 +394,7 @@ func TestTernaryTyping(test *testing.T) {
 	}
 
 	runEvaluationFailureTests(evaluationTests, test)
+	runEngineEvaluationFailureTests(evaluationTests, test)
 }
 
 func TestRegexParameterCompilation(test *testing.T) {
@@ -417,6 +421,7 @@ func TestRegexParameterCompilation(test *testing.T) {
 	}
 
 	runEvaluationFailureTests(evaluationTests, test)
+	runEngineEvaluationFailureTests(evaluationTests, test)
 }
 
 func TestFunctionExecution(test *testing.T) {
@@ -436,6 +441,7 @@ func TestFunctionExecution(test *testing.T) {
 	}
 
 	runEvaluationFailureTests(evaluationTests, test)
+	runEngineEvaluationFailureTests(evaluationTests, test)
 }
 
 func TestInvalidParameterCalls(test *testing.T) {
@@ -493,6 +499,7 @@ func TestInvalidParameterCalls(test *testing.T) {
 	}
 
 	runEvaluationFailureTests(evaluationTests, test)
+	runEngineEvaluationFailureTests(evaluationTests, test)
 }
 
 func runEvaluationFailureTests(evaluationTests []EvaluationFailureTest, test *testing.T) {
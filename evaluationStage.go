@@ -40,6 +40,13 @@ type evaluationStage struct {
 
 	// regardless of which type check is used, this string format will be used as the error message for type errors
 	typeErrorFormat string
+
+	// what this stage was planned from, which isn't needed to evaluate it, but is needed to describe it as a Node.
+	// [name] is the name of a parameter or function, [path] is the full path of an accessor,
+	// and [value] is the value of a literal, or the function to call.
+	name  string
+	path  []string
+	value interface{}
 }
 
 var (
@@ -62,6 +69,9 @@ func (this *evaluationStage) setToNonStage(other evaluationStage) {
 	this.rightTypeCheck = other.rightTypeCheck
 	this.typeCheck = other.typeCheck
 	this.typeErrorFormat = other.typeErrorFormat
+	this.name = other.name
+	this.path = other.path
+	this.value = other.value
 }
 
 func (this *evaluationStage) isShortCircuitable() bool {
@@ -247,6 +257,25 @@ func makeFunctionStage(function ExpressionFunction) evaluationOperator {
 	}
 }
 
+func makeContextFunctionStage(function ContextExpressionFunction) evaluationOperator {
+
+	return func(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
+
+		ctx := contextOf(parameters)
+
+		if right == nil {
+			return function(ctx)
+		}
+
+		switch right.(type) {
+		case []interface{}:
+			return function(ctx, right.([]interface{})...)
+		default:
+			return function(ctx, right)
+		}
+	}
+}
+
 func typeConvertParam(p reflect.Value, t reflect.Type) (ret reflect.Value, err error) {
 	defer func() {
 		if r := recover(); r != nil {
@@ -399,7 +428,7 @@ func makeAccessorStage(pair []string) evaluationOperator {
 			return nil, errors.New("Method call '" + pair[0] + "." + pair[1] + "' did not return either one value, or a value and an error. Cannot interpret meaning.")
 		}
 
-		value = castToFloat64(value)
+		value = sanitizeParameterValue(parameters, value)
 		return value, nil
 	}
 }
@@ -0,0 +1,375 @@
+package govaluate
+
+import (
+	"context"
+	"sync"
+)
+
+/*
+	Represents what a single bytecodeInstruction does.
+*/
+type bytecodeOpcode int
+
+const (
+
+	// pushes a literal value.
+	pushOpcode bytecodeOpcode = iota
+
+	// pushes the value of a parameter.
+	loadOpcode
+
+	// pops the operands of a stage, and pushes the result of its operator.
+	applyOpcode
+
+	// continues at the target instruction.
+	jumpOpcode
+
+	// continue at the target instruction if the top of the stack is false, true, or not nil (respectively), leaving it on the stack.
+	jumpIfFalseOpcode
+	jumpIfTrueOpcode
+	jumpIfNotNilOpcode
+)
+
+/*
+	A single instruction of a bytecodeProgram.
+*/
+type bytecodeInstruction struct {
+	opcode bytecodeOpcode
+
+	// the stages which `evaluateStage` would enter (checking for cancellation and spending the budget) just before this instruction.
+	enters []*evaluationStage
+
+	// the stage this instruction evaluates, for loadOpcode and applyOpcode.
+	stage *evaluationStage
+
+	// the value pushed by pushOpcode.
+	value interface{}
+
+	// what applyOpcode needs: which operands to pop, the type checks which couldn't be hoisted, and the fast path, if there is one.
+	hasLeft, hasRight bool
+	checks            typeChecks
+	fastPath          stageFastPath
+
+	// where jumps continue.
+	target int
+}
+
+/*
+	A tree of stages compiled into a flat sequence of instructions, for BYTECODE_ENGINE.
+*/
+type bytecodeProgram struct {
+	instructions []bytecodeInstruction
+	numericMode  NumericMode
+
+	// the deepest the stack ever gets while running this program.
+	depth int
+
+	// machines which have finished running this program, so that their stacks can be reused.
+	machines sync.Pool
+}
+
+/*
+	The state needed to run a bytecodeProgram once.
+	Machines are reused between evaluations, so that running a program needn't allocate.
+*/
+type bytecodeMachine struct {
+	stack      []interface{}
+	parameters sanitizedParameters
+	budget     EvaluationBudget
+}
+
+/*
+	Keeps track of the instructions emitted so far while compiling a bytecodeProgram.
+*/
+type bytecodeCompiler struct {
+	options      *compileOptions
+	instructions []bytecodeInstruction
+
+	// stages which have been entered, but which haven't yet been given to an instruction.
+	entering []*evaluationStage
+
+	depth, maxDepth int
+}
+
+/*
+	Compiles the tree of stages under [root] into a program, for BYTECODE_ENGINE.
+	Running the program gives exactly the same results and errors (including cancellation and budget errors, in the same order)
+	as `evaluateStage` does for [root].
+*/
+func compileBytecode(root *evaluationStage, options *compileOptions) *bytecodeProgram {
+
+	var compiler *bytecodeCompiler
+	var ret *bytecodeProgram
+
+	compiler = &bytecodeCompiler{options: options}
+	compiler.compileStage(root)
+
+	ret = &bytecodeProgram{
+		instructions: compiler.instructions,
+		numericMode:  options.numericMode,
+		depth:        compiler.maxDepth,
+	}
+
+	ret.machines.New = func() interface{} {
+		return &bytecodeMachine{stack: make([]interface{}, 0, ret.depth)}
+	}
+	return ret
+}
+
+func (this *bytecodeCompiler) compileStage(stage *evaluationStage) {
+
+	var jump, skip int
+
+	this.entering = append(this.entering, stage)
+
+	if stage.symbol == LITERAL {
+
+		value, err := stage.operator(nil, nil, nil)
+		if err == nil {
+			this.emit(bytecodeInstruction{opcode: pushOpcode, value: value}, 1)
+			return
+		}
+	}
+
+	if stage.symbol == VALUE && stage.name != "" {
+		this.emit(bytecodeInstruction{opcode: loadOpcode, stage: stage}, 1)
+		return
+	}
+
+	// parentheses only group, so they don't need an instruction of their own.
+	if stage.symbol == NOOP && stage.rightStage != nil {
+		this.compileStage(stage.rightStage)
+		return
+	}
+
+	jump, skip = -1, -1
+
+	if stage.leftStage != nil {
+
+		this.compileStage(stage.leftStage)
+
+		switch stage.symbol {
+		case AND:
+			jump = this.emit(bytecodeInstruction{opcode: jumpIfFalseOpcode}, 0)
+		case OR:
+			jump = this.emit(bytecodeInstruction{opcode: jumpIfTrueOpcode}, 0)
+		case COALESCE:
+			jump = this.emit(bytecodeInstruction{opcode: jumpIfNotNilOpcode}, 0)
+		case TERNARY_TRUE:
+			skip = this.emit(bytecodeInstruction{opcode: jumpIfFalseOpcode}, 0)
+		case TERNARY_FALSE:
+			skip = this.emit(bytecodeInstruction{opcode: jumpIfNotNilOpcode}, 0)
+		}
+	}
+
+	if stage.rightStage != nil {
+		this.compileStage(stage.rightStage)
+	}
+
+	// a ternary whose right side is skipped still applies its operator, with a placeholder for the right side.
+	if skip >= 0 {
+
+		jump = this.emit(bytecodeInstruction{opcode: jumpOpcode}, 0)
+		this.instructions[skip].target = len(this.instructions)
+
+		this.depth--
+		this.emit(bytecodeInstruction{opcode: pushOpcode, value: shortCircuitHolder}, 1)
+		this.instructions[jump].target = len(this.instructions)
+		jump = -1
+	}
+
+	this.emitApply(stage)
+
+	if jump >= 0 {
+		this.instructions[jump].target = len(this.instructions)
+	}
+}
+
+func (this *bytecodeCompiler) emitApply(stage *evaluationStage) {
+
+	var instruction bytecodeInstruction
+	var change int
+
+	instruction = bytecodeInstruction{
+		opcode:   applyOpcode,
+		stage:    stage,
+		hasLeft:  stage.leftStage != nil,
+		hasRight: stage.rightStage != nil,
+		checks:   hoistTypeChecks(stage, this.options),
+	}
+
+	if instruction.hasLeft && instruction.hasRight {
+		instruction.fastPath = findStageFastPath(stage, this.options)
+	}
+
+	change = 1
+	if instruction.hasLeft {
+		change--
+	}
+	if instruction.hasRight {
+		change--
+	}
+	this.emit(instruction, change)
+}
+
+/*
+	Appends the given [instruction], which changes the depth of the stack by [change], and returns its index.
+*/
+func (this *bytecodeCompiler) emit(instruction bytecodeInstruction, change int) int {
+
+	instruction.enters = this.entering
+	this.entering = nil
+
+	this.instructions = append(this.instructions, instruction)
+
+	this.depth += change
+	if this.depth > this.maxDepth {
+		this.maxDepth = this.depth
+	}
+	return len(this.instructions) - 1
+}
+
+/*
+	Runs this program with the given [parameters], with the settings of the expression being evaluated.
+*/
+func (this *bytecodeProgram) run(ctx context.Context, parameters Parameters, checksTypes bool, budget EvaluationBudget) (interface{}, error) {
+
+	var machine *bytecodeMachine
+	var result interface{}
+	var err error
+
+	machine = this.machines.Get().(*bytecodeMachine)
+	machine.parameters = sanitizedParameters{
+		orig:        parameters,
+		numericMode: this.numericMode,
+		ctx:         ctx,
+		done:        ctx.Done(),
+		checksTypes: checksTypes,
+	}
+
+	if budget.isLimited() {
+		machine.budget = budget
+		machine.parameters.budget = &machine.budget
+	}
+
+	result, err = machine.execute(this.instructions, &machine.parameters)
+
+	this.release(machine)
+	return result, err
+}
+
+/*
+	Returns a machine for running this program, which should be given back with `release` once it's no longer needed.
+*/
+func (this *bytecodeProgram) acquire() *bytecodeMachine {
+	return this.machines.Get().(*bytecodeMachine)
+}
+
+/*
+	Clears the given [machine], and keeps it to be reused by later evaluations.
+*/
+func (this *bytecodeProgram) release(machine *bytecodeMachine) {
+
+	// nothing from an evaluation should be kept alive by an unused machine.
+	machine.stack = machine.stack[:cap(machine.stack)]
+	for i := range machine.stack {
+		machine.stack[i] = nil
+	}
+	machine.stack = machine.stack[:0]
+	machine.parameters = sanitizedParameters{}
+
+	this.machines.Put(machine)
+}
+
+/*
+	Runs the given [instructions] with the given [parameters], using this machine's stack.
+*/
+func (this *bytecodeMachine) execute(instructions []bytecodeInstruction, parameters *sanitizedParameters) (interface{}, error) {
+
+	var instruction *bytecodeInstruction
+	var left, right, result interface{}
+	var stack []interface{}
+	var ok bool
+	var err error
+
+	stack = this.stack[:0]
+
+	for i := 0; i < len(instructions); i++ {
+
+		instruction = &instructions[i]
+
+		if instruction.enters != nil && (parameters.done != nil || parameters.budget != nil) {
+			for _, stage := range instruction.enters {
+
+				err = enterCompiledStage(stage, parameters)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		switch instruction.opcode {
+
+		case pushOpcode:
+			stack = append(stack, instruction.value)
+
+		case loadOpcode:
+
+			result, err = parameters.orig.Get(instruction.stage.name)
+			if err != nil {
+				return nil, err
+			}
+
+			if parameters.numericMode == FLOAT_NUMERICS {
+				stack = append(stack, castToFloat64(result))
+			} else {
+				stack = append(stack, parameters.sanitize(result))
+			}
+
+		case applyOpcode:
+
+			left, right = nil, nil
+
+			if instruction.hasRight {
+				right = stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+			}
+			if instruction.hasLeft {
+				left = stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+			}
+
+			ok = false
+			if instruction.fastPath != nil {
+				result, ok = instruction.fastPath(left, right)
+			}
+
+			if !ok {
+				result, err = exitCompiledStage(instruction.stage, instruction.checks, left, right, parameters)
+				if err != nil {
+					return nil, err
+				}
+			}
+			stack = append(stack, result)
+
+		case jumpOpcode:
+			i = instruction.target - 1
+
+		case jumpIfFalseOpcode:
+			if stack[len(stack)-1] == false {
+				i = instruction.target - 1
+			}
+
+		case jumpIfTrueOpcode:
+			if stack[len(stack)-1] == true {
+				i = instruction.target - 1
+			}
+
+		case jumpIfNotNilOpcode:
+			if stack[len(stack)-1] != nil {
+				i = instruction.target - 1
+			}
+		}
+	}
+	return stack[len(stack)-1], nil
+}
@@ -0,0 +1,645 @@
+package govaluate
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+/*
+	A stage compiled by `compileStageClosure`, which evaluates the stage (and every stage under it) with the given parameters.
+*/
+type stageClosure func(parameters *sanitizedParameters) (interface{}, error)
+
+/*
+	Given the values of both sides of a stage, returns the stage's result and true if it can be computed directly,
+	or false if the stage's type checks and operator need to be used instead.
+*/
+type stageFastPath func(left interface{}, right interface{}) (interface{}, bool)
+
+/*
+	Compiles the tree of stages under [root] into a tree of closures, for CLOSURE_ENGINE.
+	Each closure gives exactly the same results and errors (including cancellation and budget errors, in the same order)
+	as `evaluateStage` does for the same stage.
+*/
+func compileStageClosure(root *evaluationStage, options *compileOptions) stageClosure {
+
+	var left, right stageClosure
+	var checks typeChecks
+	var fastPath stageFastPath
+
+	if root == nil {
+		return nil
+	}
+
+	if root.symbol == LITERAL {
+		value, err := root.operator(nil, nil, nil)
+		return makeLiteralClosure(root, value, err)
+	}
+
+	if root.symbol == VALUE && root.name != "" {
+		return makeParameterClosure(root, options.numericMode)
+	}
+
+	left = compileStageClosure(root.leftStage, options)
+	right = compileStageClosure(root.rightStage, options)
+
+	if root.symbol == NOOP && right != nil {
+		return makeNoopClosure(root, right)
+	}
+
+	checks = hoistTypeChecks(root, options)
+
+	switch root.symbol {
+	case AND:
+		return makeAndClosure(root, checks, left, right)
+	case OR:
+		return makeOrClosure(root, checks, left, right)
+	}
+
+	fastPath = findStageFastPath(root, options)
+	if fastPath != nil && left != nil && right != nil {
+		return makeBinaryClosure(root, checks, left, right, fastPath)
+	}
+	return makeStageClosure(root, checks, left, right)
+}
+
+/*
+	Returns the type checks that [stage] needs at evaluation time.
+	A check is dropped if the operand it checks can be shown to always pass it, either because the operand is a literal,
+	or because of the type of value its operator always returns.
+*/
+func hoistTypeChecks(stage *evaluationStage, options *compileOptions) typeChecks {
+
+	var ret typeChecks
+	var left, right interface{}
+	var leftKnown, rightKnown bool
+
+	left, leftKnown = findStageSample(stage.leftStage, options)
+	right, rightKnown = findStageSample(stage.rightStage, options)
+
+	// the combined check overrides the others, which are never used alongside it.
+	if stage.typeCheck != nil {
+
+		if !leftKnown || !rightKnown || !stage.typeCheck(left, right) {
+			ret.combined = stage.typeCheck
+		}
+		return ret
+	}
+
+	if stage.leftTypeCheck != nil && (!leftKnown || !stage.leftTypeCheck(left)) {
+		ret.left = stage.leftTypeCheck
+	}
+	if stage.rightTypeCheck != nil && (!rightKnown || !stage.rightTypeCheck(right)) {
+		ret.right = stage.rightTypeCheck
+	}
+	return ret
+}
+
+/*
+	Returns a value of the same type as every value that [stage] evaluates to, and true,
+	or false if that type can't be known until the stage is evaluated.
+	Literals return their actual value, so that checks which depend on more than the type are still correct.
+*/
+func findStageSample(stage *evaluationStage, options *compileOptions) (interface{}, bool) {
+
+	if stage == nil {
+		return nil, true
+	}
+
+	if _, found := options.operators[stage.symbol]; found {
+		return nil, false
+	}
+
+	switch stage.symbol {
+
+	case LITERAL:
+
+		value, err := stage.operator(nil, nil, nil)
+		return value, err == nil
+
+	case NOOP:
+		return findStageSample(stage.rightStage, options)
+
+	case EQ:
+		fallthrough
+	case NEQ:
+		fallthrough
+	case GT:
+		fallthrough
+	case LT:
+		fallthrough
+	case GTE:
+		fallthrough
+	case LTE:
+		fallthrough
+	case REQ:
+		fallthrough
+	case NREQ:
+		fallthrough
+	case IN:
+		fallthrough
+	case AND:
+		fallthrough
+	case OR:
+		fallthrough
+	case INVERT:
+		return true, true
+
+	// only float numerics are sure to produce float64s; the others may produce any kind of number.
+	case MINUS:
+		fallthrough
+	case MULTIPLY:
+		fallthrough
+	case DIVIDE:
+		fallthrough
+	case MODULUS:
+		fallthrough
+	case EXPONENT:
+		fallthrough
+	case NEGATE:
+		fallthrough
+	case BITWISE_AND:
+		fallthrough
+	case BITWISE_OR:
+		fallthrough
+	case BITWISE_XOR:
+		fallthrough
+	case BITWISE_LSHIFT:
+		fallthrough
+	case BITWISE_RSHIFT:
+		fallthrough
+	case BITWISE_NOT:
+		if options.numericMode == FLOAT_NUMERICS {
+			return float64(0), true
+		}
+	}
+
+	return nil, false
+}
+
+/*
+	Does the work which `evaluateStage` does before evaluating any stage: checking for cancellation, then spending the budget.
+*/
+func enterCompiledStage(stage *evaluationStage, parameters *sanitizedParameters) error {
+
+	var err error
+
+	if parameters.done != nil {
+		err = parameters.cancelled()
+		if err != nil {
+			return err
+		}
+	}
+
+	if parameters.budget != nil {
+		return parameters.budget.spend(stage, &parameters.usage)
+	}
+	return nil
+}
+
+/*
+	Does the work which `evaluateStage` does once both sides of [stage] have been evaluated:
+	checking types (with the given [checks]), applying the operator, then checking the result against the budget.
+*/
+func exitCompiledStage(stage *evaluationStage, checks typeChecks, left interface{}, right interface{}, parameters *sanitizedParameters) (interface{}, error) {
+
+	var result interface{}
+	var err error
+
+	if parameters.checksTypes {
+		if checks.combined == nil {
+
+			err = typeCheck(checks.left, left, stage.symbol, stage.typeErrorFormat)
+			if err != nil {
+				return nil, err
+			}
+
+			err = typeCheck(checks.right, right, stage.symbol, stage.typeErrorFormat)
+			if err != nil {
+				return nil, err
+			}
+		} else if !checks.combined(left, right) {
+
+			errorMsg := fmt.Sprintf(stage.typeErrorFormat, left, stage.symbol.String())
+			return nil, errors.New(errorMsg)
+		}
+	}
+
+	result, err = stage.operator(left, right, parameters)
+	if err != nil || parameters.budget == nil {
+		return result, err
+	}
+
+	err = parameters.budget.checkResult(stage.symbol, result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func makeLiteralClosure(stage *evaluationStage, value interface{}, valueErr error) stageClosure {
+
+	return func(parameters *sanitizedParameters) (interface{}, error) {
+
+		if parameters.done != nil || parameters.budget != nil {
+
+			err := enterCompiledStage(stage, parameters)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return value, valueErr
+	}
+}
+
+func makeParameterClosure(stage *evaluationStage, mode NumericMode) stageClosure {
+
+	return func(parameters *sanitizedParameters) (interface{}, error) {
+
+		if parameters.done != nil || parameters.budget != nil {
+
+			err := enterCompiledStage(stage, parameters)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		value, err := parameters.orig.Get(stage.name)
+		if err != nil {
+			return nil, err
+		}
+
+		if mode == FLOAT_NUMERICS {
+			return castToFloat64(value), nil
+		}
+		return parameters.sanitize(value), nil
+	}
+}
+
+func makeNoopClosure(stage *evaluationStage, right stageClosure) stageClosure {
+
+	return func(parameters *sanitizedParameters) (interface{}, error) {
+
+		if parameters.done != nil || parameters.budget != nil {
+
+			err := enterCompiledStage(stage, parameters)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return right(parameters)
+	}
+}
+
+func makeAndClosure(stage *evaluationStage, checks typeChecks, left stageClosure, right stageClosure) stageClosure {
+
+	return func(parameters *sanitizedParameters) (interface{}, error) {
+
+		var leftValue, rightValue interface{}
+		var err error
+
+		if parameters.done != nil || parameters.budget != nil {
+
+			err = enterCompiledStage(stage, parameters)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		leftValue, err = left(parameters)
+		if err != nil {
+			return nil, err
+		}
+
+		if leftValue == false {
+			return false, nil
+		}
+
+		rightValue, err = right(parameters)
+		if err != nil {
+			return nil, err
+		}
+
+		if leftValue == true {
+			if rightBool, ok := rightValue.(bool); ok {
+				return boolIface(rightBool), nil
+			}
+		}
+		return exitCompiledStage(stage, checks, leftValue, rightValue, parameters)
+	}
+}
+
+func makeOrClosure(stage *evaluationStage, checks typeChecks, left stageClosure, right stageClosure) stageClosure {
+
+	return func(parameters *sanitizedParameters) (interface{}, error) {
+
+		var leftValue, rightValue interface{}
+		var err error
+
+		if parameters.done != nil || parameters.budget != nil {
+
+			err = enterCompiledStage(stage, parameters)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		leftValue, err = left(parameters)
+		if err != nil {
+			return nil, err
+		}
+
+		if leftValue == true {
+			return true, nil
+		}
+
+		rightValue, err = right(parameters)
+		if err != nil {
+			return nil, err
+		}
+
+		if leftValue == false {
+			if rightBool, ok := rightValue.(bool); ok {
+				return boolIface(rightBool), nil
+			}
+		}
+		return exitCompiledStage(stage, checks, leftValue, rightValue, parameters)
+	}
+}
+
+/*
+	Returns a closure for a stage with two operands, which uses [fastPath] whenever it can, and the stage's operator otherwise.
+*/
+func makeBinaryClosure(stage *evaluationStage, checks typeChecks, left stageClosure, right stageClosure, fastPath stageFastPath) stageClosure {
+
+	return func(parameters *sanitizedParameters) (interface{}, error) {
+
+		var leftValue, rightValue, result interface{}
+		var ok bool
+		var err error
+
+		if parameters.done != nil || parameters.budget != nil {
+
+			err = enterCompiledStage(stage, parameters)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		leftValue, err = left(parameters)
+		if err != nil {
+			return nil, err
+		}
+
+		rightValue, err = right(parameters)
+		if err != nil {
+			return nil, err
+		}
+
+		result, ok = fastPath(leftValue, rightValue)
+		if ok {
+			return result, nil
+		}
+		return exitCompiledStage(stage, checks, leftValue, rightValue, parameters)
+	}
+}
+
+/*
+	Returns a closure for any stage, which does everything `evaluateStage` does, apart from the type checks which were hoisted.
+*/
+func makeStageClosure(stage *evaluationStage, checks typeChecks, left stageClosure, right stageClosure) stageClosure {
+
+	var shortCircuits bool
+
+	shortCircuits = stage.isShortCircuitable()
+
+	return func(parameters *sanitizedParameters) (interface{}, error) {
+
+		var leftValue, rightValue interface{}
+		var err error
+
+		if parameters.done != nil || parameters.budget != nil {
+
+			err = enterCompiledStage(stage, parameters)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if left != nil {
+			leftValue, err = left(parameters)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if shortCircuits {
+			switch stage.symbol {
+			case COALESCE:
+				if leftValue != nil {
+					return leftValue, nil
+				}
+			case TERNARY_TRUE:
+				if leftValue == false {
+					rightValue = shortCircuitHolder
+				}
+			case TERNARY_FALSE:
+				if leftValue != nil {
+					rightValue = shortCircuitHolder
+				}
+			}
+		}
+
+		if rightValue != shortCircuitHolder && right != nil {
+			rightValue, err = right(parameters)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return exitCompiledStage(stage, checks, leftValue, rightValue, parameters)
+	}
+}
+
+/*
+	Returns the fast path for the given [stage], or nil if it has none.
+	Apart from the logical operators, only operators which haven't been replaced have fast paths, and only under float numerics.
+*/
+func findStageFastPath(stage *evaluationStage, options *compileOptions) stageFastPath {
+
+	switch stage.symbol {
+	case AND:
+		return andFastPath
+	case OR:
+		return orFastPath
+	}
+
+	if _, found := options.operators[stage.symbol]; found || options.numericMode != FLOAT_NUMERICS {
+		return nil
+	}
+
+	switch stage.symbol {
+	case EQ:
+		return equalFastPath
+	case NEQ:
+		return notEqualFastPath
+	case GT:
+		return gtFastPath
+	case GTE:
+		return gteFastPath
+	case LT:
+		return ltFastPath
+	case LTE:
+		return lteFastPath
+	case PLUS:
+		return addFastPath
+	case MINUS:
+		return subtractFastPath
+	case MULTIPLY:
+		return multiplyFastPath
+	case DIVIDE:
+		return divideFastPath
+	case MODULUS:
+		return modulusFastPath
+	case EXPONENT:
+		return exponentFastPath
+	}
+	return nil
+}
+
+func andFastPath(left interface{}, right interface{}) (interface{}, bool) {
+
+	l, lok := left.(bool)
+	r, rok := right.(bool)
+	return boolIface(l && r), lok && rok
+}
+
+func orFastPath(left interface{}, right interface{}) (interface{}, bool) {
+
+	l, lok := left.(bool)
+	r, rok := right.(bool)
+	return boolIface(l || r), lok && rok
+}
+
+/*
+	Compares values of the same basic type without reflection, which gives the same answer as `reflect.DeepEqual` for them.
+*/
+func equalFastPath(left interface{}, right interface{}) (interface{}, bool) {
+
+	switch l := left.(type) {
+	case float64:
+		if r, ok := right.(float64); ok {
+			return boolIface(l == r), true
+		}
+	case string:
+		if r, ok := right.(string); ok {
+			return boolIface(l == r), true
+		}
+	case bool:
+		if r, ok := right.(bool); ok {
+			return boolIface(l == r), true
+		}
+	}
+	return nil, false
+}
+
+func notEqualFastPath(left interface{}, right interface{}) (interface{}, bool) {
+
+	result, ok := equalFastPath(left, right)
+	if !ok {
+		return nil, false
+	}
+	return boolIface(result == false), true
+}
+
+func gtFastPath(left interface{}, right interface{}) (interface{}, bool) {
+
+	l, r, ok := findFloatOperands(left, right)
+	return boolIface(ok && l > r), ok
+}
+
+func gteFastPath(left interface{}, right interface{}) (interface{}, bool) {
+
+	l, r, ok := findFloatOperands(left, right)
+	return boolIface(ok && l >= r), ok
+}
+
+func ltFastPath(left interface{}, right interface{}) (interface{}, bool) {
+
+	l, r, ok := findFloatOperands(left, right)
+	return boolIface(ok && l < r), ok
+}
+
+func lteFastPath(left interface{}, right interface{}) (interface{}, bool) {
+
+	l, r, ok := findFloatOperands(left, right)
+	return boolIface(ok && l <= r), ok
+}
+
+func addFastPath(left interface{}, right interface{}) (interface{}, bool) {
+
+	l, r, ok := findFloatOperands(left, right)
+	if !ok {
+		return nil, false
+	}
+	return l + r, true
+}
+
+func subtractFastPath(left interface{}, right interface{}) (interface{}, bool) {
+
+	l, r, ok := findFloatOperands(left, right)
+	if !ok {
+		return nil, false
+	}
+	return l - r, true
+}
+
+func multiplyFastPath(left interface{}, right interface{}) (interface{}, bool) {
+
+	l, r, ok := findFloatOperands(left, right)
+	if !ok {
+		return nil, false
+	}
+	return l * r, true
+}
+
+func divideFastPath(left interface{}, right interface{}) (interface{}, bool) {
+
+	l, r, ok := findFloatOperands(left, right)
+	if !ok {
+		return nil, false
+	}
+	return l / r, true
+}
+
+func modulusFastPath(left interface{}, right interface{}) (interface{}, bool) {
+
+	l, r, ok := findFloatOperands(left, right)
+	if !ok {
+		return nil, false
+	}
+	return math.Mod(l, r), true
+}
+
+func exponentFastPath(left interface{}, right interface{}) (interface{}, bool) {
+
+	l, r, ok := findFloatOperands(left, right)
+	if !ok {
+		return nil, false
+	}
+	return math.Pow(l, r), true
+}
+
+/*
+	Returns both given values as float64s, and true, or false if either isn't a float64.
+*/
+func findFloatOperands(left interface{}, right interface{}) (float64, float64, bool) {
+
+	l, ok := left.(float64)
+	if !ok {
+		return 0, 0, false
+	}
+
+	r, ok := right.(float64)
+	return l, r, ok
+}
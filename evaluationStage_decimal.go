@@ -0,0 +1,605 @@
+package govaluate
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+)
+
+/*
+	The largest exponent (or shift amount) which will be computed exactly for decimals.
+	Anything larger would produce numbers so large that computing them could stall an evaluation.
+*/
+const maxExactDecimalPower = 1 << 16
+
+/*
+	The largest number of bits (of numerator and denominator together) that the result of a decimal power may have.
+	Bounding the exponent alone isn't enough, since a power of a power can grow without limit.
+*/
+const maxExactDecimalBits = 1 << 20
+
+/*
+	Represents how the result of a division is rounded when an expression uses DECIMAL_NUMERICS.
+	Division results are rounded to [Scale] digits after the radix point, using the given [Mode].
+*/
+type DecimalRounding struct {
+	Scale int
+	Mode  RoundingMode
+}
+
+/*
+	Represents a method of rounding a decimal to a given number of digits.
+*/
+type RoundingMode int
+
+const (
+
+	// rounds to the nearest value, and ties to the nearest even digit ("banker's rounding").
+	ROUND_HALF_EVEN RoundingMode = iota
+
+	// rounds to the nearest value, and ties away from zero.
+	ROUND_HALF_UP
+
+	// rounds to the nearest value, and ties toward zero.
+	ROUND_HALF_DOWN
+
+	// rounds away from zero.
+	ROUND_UP
+
+	// rounds toward zero (truncation).
+	ROUND_DOWN
+
+	// rounds toward positive infinity.
+	ROUND_CEILING
+
+	// rounds toward negative infinity.
+	ROUND_FLOOR
+)
+
+/*
+	Operators which replace their float64-only counterparts in `stageSymbolMap` when an expression uses DECIMAL_NUMERICS.
+	Division is not listed here, since it depends on the expression's rounding; see `makeDecimalDivideStage`.
+*/
+var decimalStageSymbolMap = map[OperatorSymbol]evaluationOperator{
+	EQ:             equalDecimalStage,
+	NEQ:            notEqualDecimalStage,
+	GT:             gtDecimalStage,
+	LT:             ltDecimalStage,
+	GTE:            gteDecimalStage,
+	LTE:            lteDecimalStage,
+	IN:             inDecimalStage,
+	BITWISE_OR:     bitwiseOrDecimalStage,
+	BITWISE_AND:    bitwiseAndDecimalStage,
+	BITWISE_XOR:    bitwiseXORDecimalStage,
+	BITWISE_LSHIFT: leftShiftDecimalStage,
+	BITWISE_RSHIFT: rightShiftDecimalStage,
+	PLUS:           addDecimalStage,
+	MINUS:          subtractDecimalStage,
+	MULTIPLY:       multiplyDecimalStage,
+	MODULUS:        modulusDecimalStage,
+	EXPONENT:       exponentDecimalStage,
+	NEGATE:         negateDecimalStage,
+	BITWISE_NOT:    bitwiseNotDecimalStage,
+}
+
+/*
+	Returns the type checks to use for the given [symbol] when an expression uses DECIMAL_NUMERICS.
+	These are the same as `findIntegerTypeChecks`, except that numbers may also be decimals.
+*/
+func findDecimalTypeChecks(symbol OperatorSymbol) typeChecks {
+
+	switch symbol {
+	case GT:
+		fallthrough
+	case LT:
+		fallthrough
+	case GTE:
+		fallthrough
+	case LTE:
+		return typeChecks{
+			combined: comparatorDecimalTypeCheck,
+		}
+	case PLUS:
+		return typeChecks{
+			combined: additionDecimalTypeCheck,
+		}
+	case BITWISE_LSHIFT:
+		fallthrough
+	case BITWISE_RSHIFT:
+		fallthrough
+	case BITWISE_OR:
+		fallthrough
+	case BITWISE_AND:
+		fallthrough
+	case BITWISE_XOR:
+		fallthrough
+	case MINUS:
+		fallthrough
+	case MULTIPLY:
+		fallthrough
+	case DIVIDE:
+		fallthrough
+	case MODULUS:
+		fallthrough
+	case EXPONENT:
+		return typeChecks{
+			left:  isDecimalNumber,
+			right: isDecimalNumber,
+		}
+	case NEGATE:
+		fallthrough
+	case BITWISE_NOT:
+		return typeChecks{
+			right: isDecimalNumber,
+		}
+	}
+
+	return findTypeChecks(symbol)
+}
+
+/*
+	Creates the division operator for DECIMAL_NUMERICS.
+	If [rounding] is nil, division is exact.
+*/
+func makeDecimalDivideStage(rounding *DecimalRounding) evaluationOperator {
+
+	return func(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
+
+		l, r, err := toRatPair(left, right)
+		if err != nil {
+			return nil, err
+		}
+
+		if r.Sign() == 0 {
+			return nil, errors.New("Decimal division by zero")
+		}
+
+		ret := new(big.Rat).Quo(l, r)
+		if rounding != nil {
+			ret = roundRat(ret, rounding.Scale, rounding.Mode)
+		}
+		return ret, nil
+	}
+}
+
+/*
+	Converts any number into a decimal.
+	Floats are converted using their shortest decimal representation, so that (for instance) 0.1 becomes exactly 1/10.
+	Returns nil if the value isn't a number, or is a float which can't be represented as a decimal (such as NaN or infinity).
+*/
+func toRat(value interface{}) *big.Rat {
+
+	switch v := value.(type) {
+	case *big.Rat:
+		return v
+	case *big.Int:
+		return new(big.Rat).SetInt(v)
+	case int64:
+		return new(big.Rat).SetInt64(v)
+	case uint64:
+		return new(big.Rat).SetUint64(v)
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil
+		}
+
+		ret, _ := new(big.Rat).SetString(strconv.FormatFloat(v, 'g', -1, 64))
+		return ret
+	}
+	return nil
+}
+
+func toRatPair(left interface{}, right interface{}) (*big.Rat, *big.Rat, error) {
+
+	l := toRat(left)
+	if l == nil {
+		return nil, nil, fmt.Errorf("Value '%v' cannot be represented as a decimal", left)
+	}
+
+	r := toRat(right)
+	if r == nil {
+		return nil, nil, fmt.Errorf("Value '%v' cannot be represented as a decimal", right)
+	}
+	return l, r, nil
+}
+
+/*
+	Returns the given evaluation [result] (and its [err]), copying it if it's a decimal.
+	Literals and parameters are passed through evaluation as they are, so without a copy,
+	a caller changing the result would change the expression (or the parameter) too.
+*/
+func copyDecimalResult(result interface{}, err error) (interface{}, error) {
+
+	value, isRat := result.(*big.Rat)
+	if isRat && value != nil {
+		return new(big.Rat).Set(value), err
+	}
+	return result, err
+}
+
+/*
+	Truncates a decimal toward zero, for use with bitwise operators.
+*/
+func ratToInt(value *big.Rat) *big.Int {
+	return new(big.Int).Quo(value.Num(), value.Denom())
+}
+
+/*
+	Rounds [value] to [scale] digits after the radix point, using the given [mode].
+*/
+func roundRat(value *big.Rat, scale int, mode RoundingMode) *big.Rat {
+
+	var quotient, remainder, doubled, factor *big.Int
+	var scaled *big.Rat
+	var increment bool
+	var sign, half int
+
+	if scale < 0 {
+		scale = 0
+	}
+
+	factor = new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(scale)), nil)
+	scaled = new(big.Rat).Mul(value, new(big.Rat).SetInt(factor))
+
+	if scaled.IsInt() {
+		return value
+	}
+
+	quotient, remainder = new(big.Int).QuoRem(scaled.Num(), scaled.Denom(), new(big.Int))
+	sign = scaled.Sign()
+
+	// compare twice the remainder to the denominator, to know whether the discarded part is below, at, or above one half.
+	doubled = new(big.Int).Abs(remainder)
+	doubled.Lsh(doubled, 1)
+	half = doubled.Cmp(scaled.Denom())
+
+	switch mode {
+	case ROUND_HALF_EVEN:
+		increment = half > 0 || (half == 0 && quotient.Bit(0) == 1)
+	case ROUND_HALF_UP:
+		increment = half >= 0
+	case ROUND_HALF_DOWN:
+		increment = half > 0
+	case ROUND_UP:
+		increment = true
+	case ROUND_DOWN:
+		increment = false
+	case ROUND_CEILING:
+		increment = sign > 0
+	case ROUND_FLOOR:
+		increment = sign < 0
+	}
+
+	// the quotient was truncated toward zero, so moving away from zero depends on the sign.
+	if increment {
+		quotient.Add(quotient, big.NewInt(int64(sign)))
+	}
+
+	return new(big.Rat).SetFrac(quotient, factor)
+}
+
+/*
+	Formats a decimal the way a person would write it, such as "1.25" rather than "5/4".
+	Decimals which don't terminate (such as 1/3) are given to 16 digits after the radix point.
+*/
+func formatDecimal(value *big.Rat) string {
+
+	var denominator *big.Int
+	var digits int
+
+	if value.IsInt() {
+		return value.Num().String()
+	}
+
+	// a fraction only terminates if its denominator has no prime factors other than 2 and 5.
+	// the number of digits it needs is however many times the larger of those divides it.
+	denominator = new(big.Int).Set(value.Denom())
+	twos, fives := 0, 0
+
+	for denominator.Bit(0) == 0 {
+		denominator.Rsh(denominator, 1)
+		twos++
+	}
+	for new(big.Int).Mod(denominator, big.NewInt(5)).Sign() == 0 {
+		denominator.Quo(denominator, big.NewInt(5))
+		fives++
+	}
+
+	digits = twos
+	if fives > digits {
+		digits = fives
+	}
+
+	if denominator.Cmp(big.NewInt(1)) != 0 {
+		digits = 16
+	}
+	return value.FloatString(digits)
+}
+
+/*
+	Checks equality the same way as `equalStage`, except that numbers of any type are equal if their values are.
+*/
+func decimalsEqual(left interface{}, right interface{}) bool {
+
+	if isDecimalNumber(left) && isDecimalNumber(right) {
+		return toRat(left).Cmp(toRat(right)) == 0
+	}
+	return reflect.DeepEqual(left, right)
+}
+
+func equalDecimalStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
+	return boolIface(decimalsEqual(left, right)), nil
+}
+func notEqualDecimalStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
+	return boolIface(!decimalsEqual(left, right)), nil
+}
+func gteDecimalStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
+	if isString(left) && isString(right) {
+		return boolIface(left.(string) >= right.(string)), nil
+	}
+	return boolIface(toRat(left).Cmp(toRat(right)) >= 0), nil
+}
+func gtDecimalStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
+	if isString(left) && isString(right) {
+		return boolIface(left.(string) > right.(string)), nil
+	}
+	return boolIface(toRat(left).Cmp(toRat(right)) > 0), nil
+}
+func lteDecimalStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
+	if isString(left) && isString(right) {
+		return boolIface(left.(string) <= right.(string)), nil
+	}
+	return boolIface(toRat(left).Cmp(toRat(right)) <= 0), nil
+}
+func ltDecimalStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
+	if isString(left) && isString(right) {
+		return boolIface(left.(string) < right.(string)), nil
+	}
+	return boolIface(toRat(left).Cmp(toRat(right)) < 0), nil
+}
+
+func inDecimalStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
+
+	for _, value := range right.([]interface{}) {
+
+		if isDecimalNumber(left) && isDecimalNumber(value) {
+			if decimalsEqual(left, value) {
+				return true, nil
+			}
+			continue
+		}
+
+		if left == value {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func addDecimalStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
+
+	// string concat if either are strings
+	if isString(left) || isString(right) {
+		return fmt.Sprintf("%v%v", decimalString(left), decimalString(right)), nil
+	}
+
+	l, r, err := toRatPair(left, right)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Rat).Add(l, r), nil
+}
+func subtractDecimalStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
+
+	l, r, err := toRatPair(left, right)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Rat).Sub(l, r), nil
+}
+func multiplyDecimalStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
+
+	l, r, err := toRatPair(left, right)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Rat).Mul(l, r), nil
+}
+func modulusDecimalStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
+
+	l, r, err := toRatPair(left, right)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.Sign() == 0 {
+		return nil, errors.New("Decimal modulus by zero")
+	}
+
+	// same as math.Mod; the result has the sign of [left].
+	quotient := ratToInt(new(big.Rat).Quo(l, r))
+	product := new(big.Rat).Mul(r, new(big.Rat).SetInt(quotient))
+	return new(big.Rat).Sub(l, product), nil
+}
+func exponentDecimalStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
+
+	l, r, err := toRatPair(left, right)
+	if err != nil {
+		return nil, err
+	}
+
+	// only integer powers can be computed exactly.
+	if r.IsInt() && r.Num().IsInt64() {
+
+		exponent := r.Num().Int64()
+		if exponent <= maxExactDecimalPower && exponent >= -maxExactDecimalPower {
+
+			if exponent < 0 && l.Sign() == 0 {
+				return nil, errors.New("Decimal division by zero")
+			}
+			return ratPower(l, exponent)
+		}
+	}
+
+	lf, _ := l.Float64()
+	rf, _ := r.Float64()
+
+	ret := toRat(math.Pow(lf, rf))
+	if ret == nil {
+		return nil, fmt.Errorf("Result of '%v ** %v' cannot be represented as a decimal", decimalString(l), decimalString(r))
+	}
+	return ret, nil
+}
+func negateDecimalStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
+
+	r := toRat(right)
+	if r == nil {
+		return nil, fmt.Errorf("Value '%v' cannot be represented as a decimal", right)
+	}
+	return new(big.Rat).Neg(r), nil
+}
+func bitwiseNotDecimalStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
+
+	r := toRat(right)
+	if r == nil {
+		return nil, fmt.Errorf("Value '%v' cannot be represented as a decimal", right)
+	}
+	return new(big.Rat).SetInt(new(big.Int).Not(ratToInt(r))), nil
+}
+
+func bitwiseOrDecimalStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
+
+	l, r, err := toRatPair(left, right)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Rat).SetInt(new(big.Int).Or(ratToInt(l), ratToInt(r))), nil
+}
+func bitwiseAndDecimalStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
+
+	l, r, err := toRatPair(left, right)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Rat).SetInt(new(big.Int).And(ratToInt(l), ratToInt(r))), nil
+}
+func bitwiseXORDecimalStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
+
+	l, r, err := toRatPair(left, right)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Rat).SetInt(new(big.Int).Xor(ratToInt(l), ratToInt(r))), nil
+}
+func leftShiftDecimalStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
+
+	l, amount, err := decimalShiftOperands(left, right)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Rat).SetInt(new(big.Int).Lsh(ratToInt(l), amount)), nil
+}
+func rightShiftDecimalStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
+
+	l, amount, err := decimalShiftOperands(left, right)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Rat).SetInt(new(big.Int).Rsh(ratToInt(l), amount)), nil
+}
+
+func decimalShiftOperands(left interface{}, right interface{}) (*big.Rat, uint, error) {
+
+	l, r, err := toRatPair(left, right)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	amount := ratToInt(r)
+	if amount.Sign() < 0 {
+		return nil, 0, fmt.Errorf("Cannot shift by a negative amount: %v", amount)
+	}
+	if amount.Cmp(big.NewInt(maxExactDecimalPower)) > 0 {
+		return nil, 0, fmt.Errorf("Cannot shift by more than %d bits: %v", maxExactDecimalPower, amount)
+	}
+	return l, uint(amount.Uint64()), nil
+}
+
+/*
+	Raises [base] to the integer power [exponent], exactly.
+	Returns an error instead if the result would have more than `maxExactDecimalBits` bits.
+*/
+func ratPower(base *big.Rat, exponent int64) (*big.Rat, error) {
+
+	var numerator, denominator *big.Int
+	var power *big.Int
+	var bits int64
+
+	if exponent < 0 {
+		base = new(big.Rat).Inv(base)
+		exponent = -exponent
+	}
+
+	// an integer of n bits, raised to the power e, has more than (n-1)*e bits. Powers of 0 and 1 (which have one bit) never grow.
+	bits = int64(base.Num().BitLen() + base.Denom().BitLen() - 2)
+	if bits*exponent > maxExactDecimalBits {
+		return nil, fmt.Errorf("Result of raising a decimal to the power %d is too large to be computed", exponent)
+	}
+
+	power = big.NewInt(exponent)
+	numerator = new(big.Int).Exp(base.Num(), power, nil)
+	denominator = new(big.Int).Exp(base.Denom(), power, nil)
+	return new(big.Rat).SetFrac(numerator, denominator), nil
+}
+
+/*
+	Returns the given [value] formatted as a decimal, if it is one. Otherwise returns the value unchanged.
+*/
+func decimalString(value interface{}) interface{} {
+
+	switch v := value.(type) {
+	case *big.Rat:
+		return formatDecimal(v)
+	}
+	return value
+}
+
+func isDecimalNumber(value interface{}) bool {
+
+	switch value.(type) {
+	case *big.Rat:
+		return true
+	}
+	return isNumber(value) && toRat(value) != nil
+}
+
+/*
+	The same as `additionTypeCheck`, except that decimals (and any other kind of number) are accepted.
+*/
+func additionDecimalTypeCheck(left interface{}, right interface{}) bool {
+
+	if isDecimalNumber(left) && isDecimalNumber(right) {
+		return true
+	}
+	if !isString(left) && !isString(right) {
+		return false
+	}
+	return true
+}
+
+/*
+	The same as `comparatorTypeCheck`, except that decimals (and any other kind of number) are accepted.
+*/
+func comparatorDecimalTypeCheck(left interface{}, right interface{}) bool {
+
+	if isDecimalNumber(left) && isDecimalNumber(right) {
+		return true
+	}
+	if isString(left) && isString(right) {
+		return true
+	}
+	return false
+}
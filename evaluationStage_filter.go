@@ -0,0 +1,220 @@
+package govaluate
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+)
+
+/*
+	Helpers for translating stages into the filters of document query languages (see `ToMongoQuery` and `ToElasticsearchQuery`),
+	which can only compare a field of a document to a literal.
+*/
+
+/*
+	Returns the field and literal compared by the given comparator [stage], given the kind of [query] being written (such as "a Mongo query") for errors.
+	Comparisons can be written either way around, but filters always have the field first;
+	if the literal was on the left, [flipped] is true.
+	The literal is nil for `in`, whose array is on the right side of [stage].
+*/
+func findFilterComparison(stage *evaluationStage, query string) (field *evaluationStage, literal *evaluationStage, flipped bool, err error) {
+
+	field = skipNoopStages(stage.leftStage)
+	literal = skipNoopStages(stage.rightStage)
+
+	if !isFilterField(field) && isFilterField(literal) {
+
+		switch stage.symbol {
+		case IN:
+			fallthrough
+		case REQ:
+			fallthrough
+		case NREQ:
+			return nil, nil, false, errors.New(fmt.Sprintf("The left side of '%v' must be a parameter to be written as %s", stage.symbol, query))
+		}
+
+		field, literal = literal, field
+		flipped = true
+	}
+
+	if !isFilterField(field) {
+		return nil, nil, false, errors.New(fmt.Sprintf("One side of '%v' must be a parameter to be written as %s", stage.symbol, query))
+	}
+
+	if stage.symbol == IN {
+		literal = nil
+	}
+	return field, literal, flipped, nil
+}
+
+/*
+	Returns the comparator which gives the same result as [symbol] with its operands swapped.
+*/
+func flipComparator(symbol OperatorSymbol) OperatorSymbol {
+
+	switch symbol {
+	case GT:
+		return LT
+	case GTE:
+		return LTE
+	case LT:
+		return GT
+	case LTE:
+		return GTE
+	}
+	return symbol
+}
+
+/*
+	Returns true if the given [stage] refers to a field, either directly or with a literal fallback (such as `foo ?? 0`).
+*/
+func isFilterField(stage *evaluationStage) bool {
+
+	if stage == nil {
+		return false
+	}
+
+	switch stage.symbol {
+	case VALUE:
+		return true
+	case ACCESS:
+		return stage.rightStage == nil
+	case COALESCE:
+		return isFilterField(skipNoopStages(stage.leftStage)) && skipNoopStages(stage.rightStage).symbol == LITERAL
+	}
+	return false
+}
+
+/*
+	Returns the dotted path of the field referred to by the given [stage], such as "user.Age".
+*/
+func findFilterPath(stage *evaluationStage) string {
+
+	if stage.symbol == ACCESS {
+		return strings.Join(stage.path, ".")
+	}
+	return stage.name
+}
+
+/*
+	Returns the value of the given literal [stage], as it should be written in a filter.
+*/
+func findFilterLiteral(stage *evaluationStage, query string) (interface{}, error) {
+
+	if stage == nil || stage.symbol != LITERAL {
+		return nil, errors.New(fmt.Sprintf("Only literals can be compared to parameters in %s", query))
+	}
+
+	switch stage.value.(type) {
+	case *regexp.Regexp:
+		return stage.value.(*regexp.Regexp).String(), nil
+	case *big.Rat:
+		value, _ := stage.value.(*big.Rat).Float64()
+		return value, nil
+	}
+	return stage.value, nil
+}
+
+/*
+	Returns the values of every literal in the array on the right side of an `in`.
+*/
+func findFilterArray(stage *evaluationStage, query string) ([]interface{}, error) {
+
+	var ret []interface{}
+	var value interface{}
+	var err error
+
+	stage = skipNoopStages(stage)
+	if stage == nil {
+		return nil, errors.New(fmt.Sprintf("Unable to write an empty array as %s", query))
+	}
+
+	for _, element := range flattenSeparatedStages(stage) {
+
+		value, err = findFilterLiteral(skipNoopStages(element), query)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, value)
+	}
+	return ret, nil
+}
+
+/*
+	Returns whether a coalesced [field] (such as `foo ?? 0`) matches the comparator [stage] when the field is missing,
+	by comparing its fallback to [value].
+*/
+func matchesFilterFallback(stage *evaluationStage, field *evaluationStage, value interface{}, flipped bool, query string) (bool, error) {
+
+	var fallback, result interface{}
+	var err error
+
+	fallback, err = findFilterLiteral(skipNoopStages(field.rightStage), query)
+	if err != nil {
+		return false, err
+	}
+
+	if flipped {
+		result, err = applyLiteralStage(stage, value, fallback)
+	} else {
+		result, err = applyLiteralStage(stage, fallback, value)
+	}
+
+	if err != nil {
+		return false, err
+	}
+	return result == true, nil
+}
+
+/*
+	Returns the result of the operator of the given [stage] on the given values, after checking their types.
+*/
+func applyLiteralStage(stage *evaluationStage, left interface{}, right interface{}) (interface{}, error) {
+
+	var err error
+
+	if stage.typeCheck != nil {
+		if !stage.typeCheck(left, right) {
+			return nil, errors.New(fmt.Sprintf(stage.typeErrorFormat, left, stage.symbol.String()))
+		}
+	} else {
+
+		err = typeCheck(stage.leftTypeCheck, left, stage.symbol, stage.typeErrorFormat)
+		if err != nil {
+			return nil, err
+		}
+
+		err = typeCheck(stage.rightTypeCheck, right, stage.symbol, stage.typeErrorFormat)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return stage.operator(left, right, nil)
+}
+
+/*
+	Returns the first stage under [stage] which isn't a parenthesis.
+*/
+func skipNoopStages(stage *evaluationStage) *evaluationStage {
+
+	for stage != nil && stage.symbol == NOOP {
+		stage = stage.rightStage
+	}
+	return stage
+}
+
+/*
+	Returns every operand of a chain of the given logical operator [symbol], such as each of `a`, `b` and `c` in `a && (b && c)`.
+*/
+func flattenLogicalStages(stage *evaluationStage, symbol OperatorSymbol) []*evaluationStage {
+
+	stage = skipNoopStages(stage)
+
+	if stage == nil || stage.symbol != symbol {
+		return []*evaluationStage{stage}
+	}
+	return append(flattenLogicalStages(stage.leftStage, symbol), flattenLogicalStages(stage.rightStage, symbol)...)
+}
@@ -0,0 +1,714 @@
+package govaluate
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+	"reflect"
+)
+
+/*
+	Operators which replace their float64-only counterparts in `stageSymbolMap` when an expression uses INTEGER_NUMERICS.
+	Each of these accepts any mix of int64, uint64, and float64.
+	Integer results which would overflow their type (such as "2 ** 64") are computed as float64 instead, the same as mixed operands, rather than wrapping.
+*/
+var integerStageSymbolMap = map[OperatorSymbol]evaluationOperator{
+	EQ:             equalNumberStage,
+	NEQ:            notEqualNumberStage,
+	GT:             gtNumberStage,
+	LT:             ltNumberStage,
+	GTE:            gteNumberStage,
+	LTE:            lteNumberStage,
+	IN:             inNumberStage,
+	BITWISE_OR:     bitwiseOrIntegerStage,
+	BITWISE_AND:    bitwiseAndIntegerStage,
+	BITWISE_XOR:    bitwiseXORIntegerStage,
+	BITWISE_LSHIFT: leftShiftIntegerStage,
+	BITWISE_RSHIFT: rightShiftIntegerStage,
+	PLUS:           addIntegerStage,
+	MINUS:          subtractIntegerStage,
+	MULTIPLY:       multiplyIntegerStage,
+	DIVIDE:         divideIntegerStage,
+	MODULUS:        modulusIntegerStage,
+	EXPONENT:       exponentIntegerStage,
+	NEGATE:         negateIntegerStage,
+	BITWISE_NOT:    bitwiseNotIntegerStage,
+}
+
+/*
+	Returns the type checks to use for the given [symbol] when an expression uses INTEGER_NUMERICS.
+	These are the same as `findTypeChecks`, except that anywhere a float64 was required, any number is accepted.
+*/
+func findIntegerTypeChecks(symbol OperatorSymbol) typeChecks {
+
+	switch symbol {
+	case GT:
+		fallthrough
+	case LT:
+		fallthrough
+	case GTE:
+		fallthrough
+	case LTE:
+		return typeChecks{
+			combined: comparatorNumberTypeCheck,
+		}
+	case PLUS:
+		return typeChecks{
+			combined: additionNumberTypeCheck,
+		}
+	case BITWISE_LSHIFT:
+		fallthrough
+	case BITWISE_RSHIFT:
+		fallthrough
+	case BITWISE_OR:
+		fallthrough
+	case BITWISE_AND:
+		fallthrough
+	case BITWISE_XOR:
+		fallthrough
+	case MINUS:
+		fallthrough
+	case MULTIPLY:
+		fallthrough
+	case DIVIDE:
+		fallthrough
+	case MODULUS:
+		fallthrough
+	case EXPONENT:
+		return typeChecks{
+			left:  isNumber,
+			right: isNumber,
+		}
+	case NEGATE:
+		fallthrough
+	case BITWISE_NOT:
+		return typeChecks{
+			right: isNumber,
+		}
+	}
+
+	return findTypeChecks(symbol)
+}
+
+/*
+	Replaces the operators and type checks of every stage in the tree under [root] with those appropriate for the given numeric [mode].
+	[rounding] is only used by DECIMAL_NUMERICS, and may be nil.
+*/
+func applyNumericMode(root *evaluationStage, mode NumericMode, rounding *DecimalRounding) {
+
+	var operator evaluationOperator
+	var checks typeChecks
+	var found bool
+
+	if root == nil || mode == FLOAT_NUMERICS {
+		return
+	}
+
+	applyNumericMode(root.leftStage, mode, rounding)
+	applyNumericMode(root.rightStage, mode, rounding)
+
+	switch mode {
+	case DECIMAL_NUMERICS:
+
+		if root.symbol == DIVIDE {
+			operator, found = makeDecimalDivideStage(rounding), true
+		} else {
+			operator, found = decimalStageSymbolMap[root.symbol]
+		}
+		checks = findDecimalTypeChecks(root.symbol)
+
+	default:
+		operator, found = integerStageSymbolMap[root.symbol]
+		checks = findIntegerTypeChecks(root.symbol)
+	}
+
+	if !found {
+		return
+	}
+
+	root.operator = operator
+	root.leftTypeCheck = checks.left
+	root.rightTypeCheck = checks.right
+	root.typeCheck = checks.combined
+}
+
+/*
+	Converts the given pair of numbers to the same type, so that they can be operated on together.
+	If either is a float64, both become float64.
+	If both are integers, but one is signed and the other isn't, they both become whichever type can hold both values,
+	or float64 if neither can.
+*/
+func promoteNumbers(left interface{}, right interface{}) (interface{}, interface{}) {
+
+	switch l := left.(type) {
+
+	case float64:
+		return l, toFloat64(right)
+
+	case int64:
+		switch r := right.(type) {
+		case int64:
+			return l, r
+		case uint64:
+			if r <= math.MaxInt64 {
+				return l, int64(r)
+			}
+			if l >= 0 {
+				return uint64(l), r
+			}
+		}
+
+	case uint64:
+		switch r := right.(type) {
+		case uint64:
+			return l, r
+		case int64:
+			if l <= math.MaxInt64 {
+				return int64(l), r
+			}
+			if r >= 0 {
+				return l, uint64(r)
+			}
+		}
+	}
+
+	return toFloat64(left), toFloat64(right)
+}
+
+/*
+	Converts any number to an integer, for use with bitwise operators.
+	Floats are truncated to int64, as they are with FLOAT_NUMERICS.
+*/
+func toInteger(value interface{}) interface{} {
+
+	switch v := value.(type) {
+	case float64:
+		return int64(v)
+	}
+	return value
+}
+
+func toFloat64(value interface{}) float64 {
+
+	switch v := value.(type) {
+	case float64:
+		return v
+	case int64:
+		return float64(v)
+	case uint64:
+		return float64(v)
+	}
+	return 0
+}
+
+func toUint64(value interface{}) uint64 {
+
+	switch v := value.(type) {
+	case int64:
+		return uint64(v)
+	case uint64:
+		return v
+	}
+	return 0
+}
+
+/*
+	Compares two numbers of any type.
+	Returns -1, 0, or 1 if [left] is less than, equal to, or greater than [right],
+	and false if the two can't be ordered (such as when one is NaN).
+*/
+func compareNumbers(left interface{}, right interface{}) (int, bool) {
+
+	left, right = promoteNumbers(left, right)
+
+	switch l := left.(type) {
+	case int64:
+		r := right.(int64)
+		if l < r {
+			return -1, true
+		}
+		if l > r {
+			return 1, true
+		}
+		return 0, true
+	case uint64:
+		r := right.(uint64)
+		if l < r {
+			return -1, true
+		}
+		if l > r {
+			return 1, true
+		}
+		return 0, true
+	}
+
+	l := left.(float64)
+	r := right.(float64)
+	if l < r {
+		return -1, true
+	}
+	if l > r {
+		return 1, true
+	}
+	if l == r {
+		return 0, true
+	}
+	return 0, false
+}
+
+/*
+	Checks equality the same way as `equalStage`, except that numbers of different types are equal if their values are.
+*/
+func numbersEqual(left interface{}, right interface{}) bool {
+
+	if isNumber(left) && isNumber(right) {
+		comparison, ordered := compareNumbers(left, right)
+		return ordered && comparison == 0
+	}
+	return reflect.DeepEqual(left, right)
+}
+
+func equalNumberStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
+	return boolIface(numbersEqual(left, right)), nil
+}
+func notEqualNumberStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
+	return boolIface(!numbersEqual(left, right)), nil
+}
+func gteNumberStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
+	if isString(left) && isString(right) {
+		return boolIface(left.(string) >= right.(string)), nil
+	}
+	comparison, ordered := compareNumbers(left, right)
+	return boolIface(ordered && comparison >= 0), nil
+}
+func gtNumberStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
+	if isString(left) && isString(right) {
+		return boolIface(left.(string) > right.(string)), nil
+	}
+	comparison, ordered := compareNumbers(left, right)
+	return boolIface(ordered && comparison > 0), nil
+}
+func lteNumberStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
+	if isString(left) && isString(right) {
+		return boolIface(left.(string) <= right.(string)), nil
+	}
+	comparison, ordered := compareNumbers(left, right)
+	return boolIface(ordered && comparison <= 0), nil
+}
+func ltNumberStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
+	if isString(left) && isString(right) {
+		return boolIface(left.(string) < right.(string)), nil
+	}
+	comparison, ordered := compareNumbers(left, right)
+	return boolIface(ordered && comparison < 0), nil
+}
+
+func inNumberStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
+
+	for _, value := range right.([]interface{}) {
+
+		if isNumber(left) && isNumber(value) {
+			if numbersEqual(left, value) {
+				return true, nil
+			}
+			continue
+		}
+
+		if left == value {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func addIntegerStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
+
+	// string concat if either are strings
+	if isString(left) || isString(right) {
+		return fmt.Sprintf("%v%v", left, right), nil
+	}
+
+	left, right = promoteNumbers(left, right)
+
+	switch l := left.(type) {
+	case int64:
+		r := right.(int64)
+		sum := l + r
+		if (r > 0 && sum < l) || (r < 0 && sum > l) {
+			return float64(l) + float64(r), nil
+		}
+		return sum, nil
+	case uint64:
+		r := right.(uint64)
+		sum, carry := bits.Add64(l, r, 0)
+		if carry != 0 {
+			return float64(l) + float64(r), nil
+		}
+		return sum, nil
+	}
+	return left.(float64) + right.(float64), nil
+}
+func subtractIntegerStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
+
+	left, right = promoteNumbers(left, right)
+
+	switch l := left.(type) {
+	case int64:
+		r := right.(int64)
+		difference := l - r
+		if (r > 0 && difference > l) || (r < 0 && difference < l) {
+			return float64(l) - float64(r), nil
+		}
+		return difference, nil
+	case uint64:
+		r := right.(uint64)
+		if r <= l {
+			return l - r, nil
+		}
+
+		// a negative result can't be unsigned.
+		if r-l <= 1<<63 {
+			return -int64(r - l), nil
+		}
+		return toFloat64(l) - toFloat64(r), nil
+	}
+	return left.(float64) - right.(float64), nil
+}
+func multiplyIntegerStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
+
+	left, right = promoteNumbers(left, right)
+
+	switch l := left.(type) {
+	case int64:
+		r := right.(int64)
+		hi, lo := bits.Mul64(absInt64(l), absInt64(r))
+		product, fits := signedInteger(lo, (l < 0) != (r < 0))
+		if hi != 0 || !fits {
+			return float64(l) * float64(r), nil
+		}
+		return product, nil
+	case uint64:
+		r := right.(uint64)
+		hi, lo := bits.Mul64(l, r)
+		if hi != 0 {
+			return float64(l) * float64(r), nil
+		}
+		return lo, nil
+	}
+	return left.(float64) * right.(float64), nil
+}
+func divideIntegerStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
+
+	left, right = promoteNumbers(left, right)
+
+	switch l := left.(type) {
+	case int64:
+		r := right.(int64)
+		if r == 0 {
+			return nil, errors.New("Integer division by zero")
+		}
+		return l / r, nil
+	case uint64:
+		r := right.(uint64)
+		if r == 0 {
+			return nil, errors.New("Integer division by zero")
+		}
+		return l / r, nil
+	}
+	return left.(float64) / right.(float64), nil
+}
+func modulusIntegerStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
+
+	left, right = promoteNumbers(left, right)
+
+	switch l := left.(type) {
+	case int64:
+		r := right.(int64)
+		if r == 0 {
+			return nil, errors.New("Integer modulus by zero")
+		}
+		return l % r, nil
+	case uint64:
+		r := right.(uint64)
+		if r == 0 {
+			return nil, errors.New("Integer modulus by zero")
+		}
+		return l % r, nil
+	}
+	return math.Mod(left.(float64), right.(float64)), nil
+}
+func exponentIntegerStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
+
+	var exponent uint64
+
+	// only non-negative integer powers of integers are integers.
+	switch r := right.(type) {
+	case int64:
+		if r < 0 {
+			return math.Pow(toFloat64(left), toFloat64(right)), nil
+		}
+		exponent = uint64(r)
+	case uint64:
+		exponent = r
+	default:
+		return math.Pow(toFloat64(left), toFloat64(right)), nil
+	}
+
+	switch l := left.(type) {
+	case int64:
+		power, fits := integerPower(absInt64(l), exponent)
+		if fits {
+			ret, fits := signedInteger(power, l < 0 && exponent&1 == 1)
+			if fits {
+				return ret, nil
+			}
+		}
+	case uint64:
+		power, fits := integerPower(l, exponent)
+		if fits {
+			return power, nil
+		}
+	}
+
+	return math.Pow(toFloat64(left), toFloat64(right)), nil
+}
+func negateIntegerStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
+
+	switch r := right.(type) {
+	case int64:
+		return -r, nil
+	case uint64:
+		if r <= 1<<63 {
+			return -int64(r), nil
+		}
+		return -float64(r), nil
+	}
+	return -right.(float64), nil
+}
+func bitwiseNotIntegerStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
+
+	switch r := toInteger(right).(type) {
+	case int64:
+		return ^r, nil
+	case uint64:
+		return ^r, nil
+	}
+	return nil, nil
+}
+
+func bitwiseOrIntegerStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
+
+	left, right = promoteBits(left, right)
+
+	switch l := left.(type) {
+	case int64:
+		return l | right.(int64), nil
+	}
+	return left.(uint64) | right.(uint64), nil
+}
+func bitwiseAndIntegerStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
+
+	left, right = promoteBits(left, right)
+
+	switch l := left.(type) {
+	case int64:
+		return l & right.(int64), nil
+	}
+	return left.(uint64) & right.(uint64), nil
+}
+func bitwiseXORIntegerStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
+
+	left, right = promoteBits(left, right)
+
+	switch l := left.(type) {
+	case int64:
+		return l ^ right.(int64), nil
+	}
+	return left.(uint64) ^ right.(uint64), nil
+}
+func leftShiftIntegerStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
+
+	amount, err := shiftAmount(right)
+	if err != nil {
+		return nil, err
+	}
+
+	switch l := toInteger(left).(type) {
+	case int64:
+		if l == 0 || (amount < 64 && (l<<amount)>>amount == l) {
+			return l << amount, nil
+		}
+		return shiftFloat64(float64(l), amount), nil
+	case uint64:
+		if l == 0 || (amount < 64 && (l<<amount)>>amount == l) {
+			return l << amount, nil
+		}
+		return shiftFloat64(float64(l), amount), nil
+	}
+	return nil, nil
+}
+func rightShiftIntegerStage(left interface{}, right interface{}, parameters Parameters) (interface{}, error) {
+
+	amount, err := shiftAmount(right)
+	if err != nil {
+		return nil, err
+	}
+
+	switch l := toInteger(left).(type) {
+	case int64:
+		return l >> amount, nil
+	case uint64:
+		return l >> amount, nil
+	}
+	return nil, nil
+}
+
+/*
+	Converts both sides of a bitwise operator to the same integer type.
+	Two signed values stay signed, otherwise both are treated as unsigned bits.
+*/
+func promoteBits(left interface{}, right interface{}) (interface{}, interface{}) {
+
+	left = toInteger(left)
+	right = toInteger(right)
+
+	if isInt64(left) && isInt64(right) {
+		return left, right
+	}
+	return toUint64(left), toUint64(right)
+}
+
+func shiftAmount(value interface{}) (uint64, error) {
+
+	switch v := toInteger(value).(type) {
+	case int64:
+		if v < 0 {
+			return 0, fmt.Errorf("Cannot shift by a negative amount: %d", v)
+		}
+		return uint64(v), nil
+	case uint64:
+		return v, nil
+	}
+	return 0, nil
+}
+
+/*
+	Raises [base] to the power of [exponent] by repeated squaring.
+	Returns false if the result doesn't fit in a uint64.
+*/
+func integerPower(base uint64, exponent uint64) (uint64, bool) {
+
+	var ret uint64 = 1
+	var hi uint64
+
+	for exponent > 0 {
+
+		if exponent&1 == 1 {
+			hi, ret = bits.Mul64(ret, base)
+			if hi != 0 {
+				return 0, false
+			}
+		}
+
+		// the base is only squared if it's used again, so that squaring the last power can't overflow.
+		exponent >>= 1
+		if exponent > 0 {
+			hi, base = bits.Mul64(base, base)
+			if hi != 0 {
+				return 0, false
+			}
+		}
+	}
+	return ret, true
+}
+
+/*
+	Returns the magnitude of the given [value], which (unlike the value itself) always fits.
+*/
+func absInt64(value int64) uint64 {
+
+	if value < 0 {
+		return uint64(-value)
+	}
+	return uint64(value)
+}
+
+/*
+	Returns the int64 with the given [magnitude], which is [negative] or not.
+	Returns false if it doesn't fit in an int64.
+*/
+func signedInteger(magnitude uint64, negative bool) (int64, bool) {
+
+	if negative {
+		if magnitude > 1<<63 {
+			return 0, false
+		}
+		return -int64(magnitude), true
+	}
+
+	if magnitude > math.MaxInt64 {
+		return 0, false
+	}
+	return int64(magnitude), true
+}
+
+/*
+	Returns [value] shifted left by [amount] bits, for shifts which overflow an integer.
+*/
+func shiftFloat64(value float64, amount uint64) float64 {
+
+	// any larger shift is already infinite.
+	if amount > 2048 {
+		amount = 2048
+	}
+	return math.Ldexp(value, int(amount))
+}
+
+func isInt64(value interface{}) bool {
+	switch value.(type) {
+	case int64:
+		return true
+	}
+	return false
+}
+
+func isNumber(value interface{}) bool {
+	switch value.(type) {
+	case float64:
+		return true
+	case int64:
+		return true
+	case uint64:
+		return true
+	}
+	return false
+}
+
+/*
+	The same as `additionTypeCheck`, except that any kind of number is accepted.
+*/
+func additionNumberTypeCheck(left interface{}, right interface{}) bool {
+
+	if isNumber(left) && isNumber(right) {
+		return true
+	}
+	if !isString(left) && !isString(right) {
+		return false
+	}
+	return true
+}
+
+/*
+	The same as `comparatorTypeCheck`, except that any kind of number is accepted.
+*/
+func comparatorNumberTypeCheck(left interface{}, right interface{}) bool {
+
+	if isNumber(left) && isNumber(right) {
+		return true
+	}
+	if isString(left) && isString(right) {
+		return true
+	}
+	return false
+}
@@ -0,0 +1,131 @@
+package govaluate
+
+/*
+	Returns the syntax tree of this expression, or nil if the expression is empty.
+	The tree is built anew on each call, so changing it has no effect on this expression.
+*/
+func (this EvaluableExpression) AST() Node {
+	return buildNode(this.evaluationStages)
+}
+
+/*
+	Creates the Node which describes the tree of stages under [stage].
+*/
+func buildNode(stage *evaluationStage) Node {
+
+	if stage == nil {
+		return nil
+	}
+
+	switch stage.symbol {
+
+	case NOOP:
+		// parenthesis only matter to the structure of the tree, which nodes already represent.
+		return buildNode(stage.rightStage)
+
+	case VALUE:
+		return &VariableNode{Name: stage.name}
+
+	case LITERAL:
+		return &LiteralNode{Value: stage.value}
+
+	case ACCESS:
+		return &AccessorNode{
+			Path:         append([]string{}, stage.path...),
+			Arguments:    buildArgumentNodes(stage.rightStage),
+			IsMethodCall: stage.rightStage != nil,
+		}
+
+	case FUNCTIONAL:
+
+		ret := &FunctionNode{
+			Name:      stage.name,
+			Arguments: buildArgumentNodes(stage.rightStage),
+		}
+
+		switch stage.value.(type) {
+		case ContextExpressionFunction:
+			ret.ContextFunction = stage.value.(ContextExpressionFunction)
+		case ExpressionFunction:
+			ret.Function = stage.value.(ExpressionFunction)
+		}
+		return ret
+
+	case SEPARATE:
+		return &ArrayNode{Elements: buildElementNodes(stage)}
+
+	case NEGATE:
+		fallthrough
+	case INVERT:
+		fallthrough
+	case BITWISE_NOT:
+		return &UnaryNode{
+			Operator: stage.symbol,
+			Operand:  buildNode(stage.rightStage),
+		}
+
+	case TERNARY_TRUE:
+		return &TernaryNode{
+			Condition: buildNode(stage.leftStage),
+			Then:      buildNode(stage.rightStage),
+		}
+
+	case TERNARY_FALSE:
+
+		// "a ? b : c" is planned as a TERNARY_FALSE whose left is the TERNARY_TRUE.
+		// without one, this is just an operator which returns its right side if its left is nil.
+		if stage.leftStage != nil && stage.leftStage.symbol == TERNARY_TRUE {
+			return &TernaryNode{
+				Condition: buildNode(stage.leftStage.leftStage),
+				Then:      buildNode(stage.leftStage.rightStage),
+				Else:      buildNode(stage.rightStage),
+			}
+		}
+	}
+
+	return &BinaryNode{
+		Operator: stage.symbol,
+		Left:     buildNode(stage.leftStage),
+		Right:    buildNode(stage.rightStage),
+	}
+}
+
+/*
+	Creates the nodes for the arguments of a function or method, given the parenthesized [stage] that they were planned as.
+*/
+func buildArgumentNodes(stage *evaluationStage) []Node {
+
+	if stage == nil {
+		return []Node{}
+	}
+
+	if stage.symbol == NOOP {
+		stage = stage.rightStage
+	}
+
+	if stage == nil {
+		return []Node{}
+	}
+
+	if stage.symbol == SEPARATE {
+		return buildElementNodes(stage)
+	}
+	return []Node{buildNode(stage)}
+}
+
+/*
+	Creates the nodes for every element of the array built by the given SEPARATE [stage].
+	Separators are evaluated left-to-right, each appending its right side to the array built by its left, so only the left side is flattened.
+*/
+func buildElementNodes(stage *evaluationStage) []Node {
+
+	var ret []Node
+
+	if stage.leftStage != nil && stage.leftStage.symbol == SEPARATE {
+		ret = buildElementNodes(stage.leftStage)
+	} else {
+		ret = []Node{buildNode(stage.leftStage)}
+	}
+
+	return append(ret, buildNode(stage.rightStage))
+}
@@ -723,6 +723,7 @@ func TestNoParameterEvaluation(test *testing.T) {
 	}
 
 	runEvaluationTests(evaluationTests, test)
+	runEngineEvaluationTests(evaluationTests, test)
 }
 
 func TestParameterizedEvaluation(test *testing.T) {
@@ -1422,6 +1423,7 @@ func TestParameterizedEvaluation(test *testing.T) {
 	}
 
 	runEvaluationTests(evaluationTests, test)
+	runEngineEvaluationTests(evaluationTests, test)
 }
 
 /*
@@ -1,8 +1,20 @@
 package govaluate
 
+import (
+	"context"
+)
+
 /*
 	Represents a function that can be called from within an expression.
 	This method must return an error if, for any reason, it is unable to produce exactly one unambiguous result.
 	An error returned will halt execution of the expression.
 */
 type ExpressionFunction func(arguments ...interface{}) (interface{}, error)
+
+/*
+	Represents a function that can be called from within an expression, and which is given the context that the expression is being evaluated with.
+	When evaluated with `Eval` (rather than `EvalContext`), the context given is `context.Background()`.
+
+	Functions which do I/O, or may otherwise block, should honor the context's cancellation and deadline.
+*/
+type ContextExpressionFunction func(ctx context.Context, arguments ...interface{}) (interface{}, error)
@@ -0,0 +1,236 @@
+package govaluate
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+/*
+	Tests that expressions are formatted in canonical form, and that the formatted expression parses back into the same tree.
+*/
+func TestFormat(test *testing.T) {
+
+	testCases := []QueryTest{
+
+		QueryTest{
+
+			Name:     "Spacing",
+			Input:    "a>1&&b<=2",
+			Expected: "a > 1 && b <= 2",
+		},
+		QueryTest{
+
+			Name:     "Redundant parentheses",
+			Input:    "((a + (b * c))) && (d)",
+			Expected: "a + b * c && d",
+		},
+		QueryTest{
+
+			Name:     "Needed parentheses",
+			Input:    "(a + b) * c",
+			Expected: "(a + b) * c",
+		},
+		QueryTest{
+
+			Name:     "Right operand of the same precedence",
+			Input:    "a - (b - c) - d",
+			Expected: "a - (b - c) - d",
+		},
+		QueryTest{
+
+			Name:     "Prefix",
+			Input:    "!(a || b) && -c > ~d",
+			Expected: "!(a || b) && -c > ~d",
+		},
+		QueryTest{
+
+			Name:     "Negative number",
+			Input:    "a - -1",
+			Expected: "a - -1",
+		},
+		QueryTest{
+
+			Name:     "Computed negative numbers",
+			Input:    "2 - 5 + a * (2 - 5.5)",
+			Expected: "-3 + a * -3.5",
+		},
+		QueryTest{
+
+			Name:     "Infinities",
+			Input:    "a > 1 / 0 || a ** (-1 / 0)",
+			Expected: "a > (1 / 0) || a ** (-1 / 0)",
+		},
+		QueryTest{
+
+			Name:     "Double quoted string",
+			Input:    "a == \"foo\"",
+			Expected: "a == 'foo'",
+		},
+		QueryTest{
+
+			Name:     "Escaped string",
+			Input:    `a == 'it\'s \"q\" \\'`,
+			Expected: `a == 'it\'s \"q\" \\'`,
+		},
+		QueryTest{
+
+			Name:     "Numbers",
+			Input:    "a > 1.50 && b < 0x10 && c == 100000000000000000000",
+			Expected: "a > 1.5 && b < 16 && c == 100000000000000000000",
+		},
+		QueryTest{
+
+			Name:     "Computed literals",
+			Input:    "a > 2 * 3 + 1",
+			Expected: "a > 7",
+		},
+		QueryTest{
+
+			Name:     "Escaped variables",
+			Input:    "[foo bar] + [baz] + [in] + [true] + [a\\]b]",
+			Expected: "[foo bar] + baz + [in] + [true] + [a\\]b]",
+		},
+		QueryTest{
+
+			Name:     "Accessors",
+			Input:    "foo.Bar > 1 && foo.Bar.Baz(1,2)",
+			Expected: "foo.Bar > 1 && foo.Bar.Baz(1, 2)",
+		},
+		QueryTest{
+
+			Name:     "Ternary",
+			Input:    "a ? (b ? 1 : 2) : c ? 3",
+			Expected: "a ? (b ? 1 : 2) : c ? 3",
+		},
+		QueryTest{
+
+			Name:     "Coalesce",
+			Input:    "(a ?? b) ?? 'c'",
+			Expected: "a ?? b ?? 'c'",
+		},
+		QueryTest{
+
+			Name:     "Arrays",
+			Input:    "a IN ( 1,'b' ,c )",
+			Expected: "a in (1, 'b', c)",
+		},
+		QueryTest{
+
+			Name:     "Regex",
+			Input:    "a =~ '^\\\\d+$'",
+			Expected: "a =~ '^\\\\d+$'",
+		},
+		QueryTest{
+
+			Name:     "Time",
+			Input:    "a > '2014-01-02T15:04:05Z'",
+			Expected: "a > '2014-01-02T15:04:05Z'",
+		},
+	}
+
+	test.Logf("Running %d format test cases", len(testCases))
+
+	for _, testCase := range testCases {
+
+		expression, err := NewEvaluableExpression(testCase.Input)
+		if err != nil {
+
+			test.Logf("Test '%s' failed to parse: %s", testCase.Name, err)
+			test.Fail()
+			continue
+		}
+
+		actual, err := expression.Format()
+		if err != nil {
+
+			test.Logf("Test '%s' failed to format: %s", testCase.Name, err)
+			test.Fail()
+			continue
+		}
+
+		if actual != testCase.Expected {
+
+			test.Logf("Test '%s' did not format as expected.", testCase.Name)
+			test.Logf("Actual: '%s', expected '%s'", actual, testCase.Expected)
+			test.Fail()
+			continue
+		}
+
+		// formatting must be stable, and give the same tree back.
+		reparsed, err := NewEvaluableExpression(actual)
+		if err != nil {
+
+			test.Logf("Test '%s' formatted into an expression which failed to parse: %s", testCase.Name, err)
+			test.Fail()
+			continue
+		}
+
+		if !reflect.DeepEqual(reparsed.AST(), expression.AST()) {
+
+			test.Logf("Test '%s' formatted into an expression with a different tree", testCase.Name)
+			test.Fail()
+		}
+	}
+}
+
+func TestFormatFunctions(test *testing.T) {
+
+	functions := map[string]ExpressionFunction{
+		"foo": func(arguments ...interface{}) (interface{}, error) {
+			return nil, nil
+		},
+	}
+
+	actual, err := Format("foo( [foo], foo() )", WithFunctions(functions))
+	if err != nil {
+		test.Logf("Failed to format: %s", err)
+		test.Fail()
+		return
+	}
+
+	if actual != "foo([foo], foo())" {
+		test.Logf("Unexpected format: '%s'", actual)
+		test.Fail()
+	}
+}
+
+func TestFormatNode(test *testing.T) {
+
+	actual, err := FormatNode(Or(Not(Gt(Var("x"), Int(-3))), Neg(Num(-0.5)), Neg(Str("a"))))
+	if err != nil {
+		test.Logf("Failed to format: %s", err)
+		test.Fail()
+		return
+	}
+
+	if actual != "!(x > -3) || -(-0.5) || -('a')" {
+		test.Logf("Unexpected format: '%s'", actual)
+		test.Fail()
+	}
+
+	// expressions which weren't parsed from a string are given as formatted.
+	expression, err := CompileAST(Add(Var("a b"), Num(1)))
+	if err != nil {
+		test.Logf("Failed to compile: %s", err)
+		test.Fail()
+		return
+	}
+
+	if expression.String() != "[a b] + 1" {
+		test.Logf("Unexpected string: '%s'", expression.String())
+		test.Fail()
+	}
+
+	actual, err = FormatNode(Eq(Var("x"), Num(math.NaN())))
+	if err != nil || actual != "x == (0 / 0)" {
+		test.Logf("Unexpected format of NaN: '%s' (%v)", actual, err)
+		test.Fail()
+	}
+
+	_, err = FormatNode(Gt(Var("x"), nil))
+	if err == nil {
+		test.Logf("Expected an error formatting a tree with a missing operand")
+		test.Fail()
+	}
+}
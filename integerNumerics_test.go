@@ -0,0 +1,399 @@
+package govaluate
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestIntegerEvaluation(test *testing.T) {
+
+	evaluationTests := []EvaluationTest{
+
+		EvaluationTest{
+
+			Name:     "Integer literal",
+			Input:    "1",
+			Expected: int64(1),
+		},
+		EvaluationTest{
+
+			Name:     "Float literal",
+			Input:    "1.5",
+			Expected: 1.5,
+		},
+		EvaluationTest{
+
+			Name:     "Integer addition",
+			Input:    "9007199254740993 + 1",
+			Expected: int64(9007199254740994),
+		},
+		EvaluationTest{
+
+			Name:     "Mixed addition promotes to float",
+			Input:    "1 + 0.5",
+			Expected: 1.5,
+		},
+		EvaluationTest{
+
+			Name:     "Integer division truncates",
+			Input:    "7 / 2",
+			Expected: int64(3),
+		},
+		EvaluationTest{
+
+			Name:     "Integer modulus",
+			Input:    "-7 % 3",
+			Expected: int64(-1),
+		},
+		EvaluationTest{
+
+			Name:     "Integer exponent",
+			Input:    "3 ** 4",
+			Expected: int64(81),
+		},
+		EvaluationTest{
+
+			Name:     "Negative exponent promotes to float",
+			Input:    "2 ** -1",
+			Expected: 0.5,
+		},
+		EvaluationTest{
+
+			Name:     "Large literal is unsigned",
+			Input:    "18446744073709551615",
+			Expected: uint64(math.MaxUint64),
+		},
+		EvaluationTest{
+
+			Name:     "Large hex literal is unsigned",
+			Input:    "0xFFFFFFFFFFFFFFFF",
+			Expected: uint64(math.MaxUint64),
+		},
+		EvaluationTest{
+
+			Name:     "Bitwise OR keeps precision",
+			Input:    "0x7FFFFFFFFFFFFF00 | 0xFF",
+			Expected: int64(math.MaxInt64),
+		},
+		EvaluationTest{
+
+			Name:     "Left shift",
+			Input:    "1 << 62",
+			Expected: int64(1 << 62),
+		},
+		EvaluationTest{
+
+			Name:     "Bitwise NOT",
+			Input:    "~0",
+			Expected: int64(-1),
+		},
+		EvaluationTest{
+
+			Name:     "Mixed equality",
+			Input:    "1 == 1.0",
+			Expected: true,
+		},
+		EvaluationTest{
+
+			Name:     "Mixed comparison",
+			Input:    "2 > 1.5",
+			Expected: true,
+		},
+		EvaluationTest{
+
+			Name:     "Mixed membership",
+			Input:    "1.0 in (1, 2)",
+			Expected: true,
+		},
+		EvaluationTest{
+
+			Name:  "Large int64 parameter",
+			Input: "id + 1",
+			Parameters: []EvaluationParameter{
+				EvaluationParameter{
+					Name:  "id",
+					Value: int64(math.MaxInt64 - 1),
+				},
+			},
+			Expected: int64(math.MaxInt64),
+		},
+		EvaluationTest{
+
+			Name:  "Unsigned parameter",
+			Input: "id >> 1",
+			Parameters: []EvaluationParameter{
+				EvaluationParameter{
+					Name:  "id",
+					Value: uint(8),
+				},
+			},
+			Expected: uint64(4),
+		},
+		EvaluationTest{
+
+			Name:  "Unsigned subtraction below zero",
+			Input: "a - b",
+			Parameters: []EvaluationParameter{
+				EvaluationParameter{
+					Name:  "a",
+					Value: uint8(3),
+				},
+				EvaluationParameter{
+					Name:  "b",
+					Value: uint16(5),
+				},
+			},
+			Expected: int64(-2),
+		},
+		EvaluationTest{
+
+			Name:  "Small int parameter",
+			Input: "foo * 2",
+			Parameters: []EvaluationParameter{
+				EvaluationParameter{
+					Name:  "foo",
+					Value: int8(-3),
+				},
+			},
+			Expected: int64(-6),
+		},
+		EvaluationTest{
+
+			Name:  "Float32 parameter",
+			Input: "foo * 2",
+			Parameters: []EvaluationParameter{
+				EvaluationParameter{
+					Name:  "foo",
+					Value: float32(0.25),
+				},
+			},
+			Expected: 0.5,
+		},
+		EvaluationTest{
+
+			Name:  "Accessor field",
+			Input: "foo.Int + 1",
+			Parameters: []EvaluationParameter{
+				EvaluationParameter{
+					Name:  "foo",
+					Value: dummyParameter{Int: 101},
+				},
+			},
+			Expected: int64(102),
+		},
+		EvaluationTest{
+
+			Name:     "Largest int64 sum",
+			Input:    "9223372036854775806 + 1",
+			Expected: int64(math.MaxInt64),
+		},
+		EvaluationTest{
+
+			Name:     "Int64 sum overflow promotes to float",
+			Input:    "9223372036854775807 + 1",
+			Expected: math.Pow(2, 63),
+		},
+		EvaluationTest{
+
+			Name:     "Smallest int64 difference",
+			Input:    "-9223372036854775807 - 1",
+			Expected: int64(math.MinInt64),
+		},
+		EvaluationTest{
+
+			Name:     "Int64 difference overflow promotes to float",
+			Input:    "-9223372036854775808 - 1",
+			Expected: -math.Pow(2, 63),
+		},
+		EvaluationTest{
+
+			Name:     "Largest uint64 sum",
+			Input:    "18446744073709551614 + 1",
+			Expected: uint64(math.MaxUint64),
+		},
+		EvaluationTest{
+
+			Name:     "Uint64 sum overflow promotes to float",
+			Input:    "18446744073709551615 + 1",
+			Expected: math.Pow(2, 64),
+		},
+		EvaluationTest{
+
+			Name:     "Smallest int64 product",
+			Input:    "-4611686018427387904 * 2",
+			Expected: int64(math.MinInt64),
+		},
+		EvaluationTest{
+
+			Name:     "Int64 product overflow promotes to float",
+			Input:    "4611686018427387904 * 2",
+			Expected: math.Pow(2, 63),
+		},
+		EvaluationTest{
+
+			Name:     "Largest uint64 product",
+			Input:    "18446744073709551615 * 1",
+			Expected: uint64(math.MaxUint64),
+		},
+		EvaluationTest{
+
+			Name:     "Uint64 product overflow promotes to float",
+			Input:    "9223372036854775808 * 2",
+			Expected: math.Pow(2, 64),
+		},
+		EvaluationTest{
+
+			Name:     "Smallest int64 power",
+			Input:    "(-2) ** 63",
+			Expected: int64(math.MinInt64),
+		},
+		EvaluationTest{
+
+			Name:     "Int64 power overflow promotes to float",
+			Input:    "2 ** 63",
+			Expected: math.Pow(2, 63),
+		},
+		EvaluationTest{
+
+			Name:     "Uint64 power overflow promotes to float",
+			Input:    "2 ** 64",
+			Expected: math.Pow(2, 64),
+		},
+		EvaluationTest{
+
+			Name:     "Largest uint64 power",
+			Input:    "18446744073709551615 ** 1",
+			Expected: uint64(math.MaxUint64),
+		},
+		EvaluationTest{
+
+			Name:     "Smallest int64 shift",
+			Input:    "-1 << 63",
+			Expected: int64(math.MinInt64),
+		},
+		EvaluationTest{
+
+			Name:     "Int64 shift overflow promotes to float",
+			Input:    "1 << 63",
+			Expected: math.Pow(2, 63),
+		},
+		EvaluationTest{
+
+			Name:     "Uint64 shift overflow promotes to float",
+			Input:    "9223372036854775808 << 1",
+			Expected: math.Pow(2, 64),
+		},
+		EvaluationTest{
+
+			Name:     "Shift past every bit promotes to float",
+			Input:    "1 << 64",
+			Expected: math.Pow(2, 64),
+		},
+		EvaluationTest{
+
+			Name:     "String concatenation",
+			Input:    "'foo' + 1",
+			Expected: "foo1",
+		},
+	}
+
+	runIntegerEvaluationTests(evaluationTests, test)
+	runEngineEvaluationTests(evaluationTests, test, WithNumericMode(INTEGER_NUMERICS))
+}
+
+func TestIntegerEvaluationFailure(test *testing.T) {
+
+	evaluationTests := []EvaluationFailureTest{
+
+		EvaluationFailureTest{
+
+			Name:     "Division by zero",
+			Input:    "1 / (number - 1)",
+			Expected: "Integer division by zero",
+		},
+		EvaluationFailureTest{
+
+			Name:     "Modulus by zero",
+			Input:    "1 % (number - 1)",
+			Expected: "Integer modulus by zero",
+		},
+		EvaluationFailureTest{
+
+			Name:     "Negative shift",
+			Input:    "1 << -number",
+			Expected: "negative amount",
+		},
+		EvaluationFailureTest{
+
+			Name:     "Non-numeric modifier",
+			Input:    "number - string",
+			Expected: INVALID_MODIFIER_TYPES,
+		},
+		EvaluationFailureTest{
+
+			Name:     "Non-numeric comparator",
+			Input:    "number > string",
+			Expected: INVALID_COMPARATOR_TYPES,
+		},
+	}
+
+	for _, testCase := range evaluationTests {
+
+		expression, err := NewEvaluableExpressionWithNumericMode(testCase.Input, nil, INTEGER_NUMERICS)
+		if err != nil {
+
+			test.Logf("Test '%s' failed to parse: '%s'", testCase.Name, err)
+			test.Fail()
+			continue
+		}
+
+		_, err = expression.Evaluate(EVALUATION_FAILURE_PARAMETERS)
+		if err == nil || !strings.Contains(err.Error(), testCase.Expected) {
+
+			test.Logf("Test '%s' failed", testCase.Name)
+			test.Logf("Got error: '%v', expected '%s'", err, testCase.Expected)
+			test.Fail()
+		}
+	}
+
+	runEngineEvaluationFailureTests(evaluationTests, test, WithNumericMode(INTEGER_NUMERICS))
+}
+
+func runIntegerEvaluationTests(evaluationTests []EvaluationTest, test *testing.T) {
+
+	fmt.Printf("Running %d integer evaluation test cases...\n", len(evaluationTests))
+
+	for _, evaluationTest := range evaluationTests {
+
+		expression, err := NewEvaluableExpressionWithNumericMode(evaluationTest.Input, evaluationTest.Functions, INTEGER_NUMERICS)
+		if err != nil {
+
+			test.Logf("Test '%s' failed to parse: '%s'", evaluationTest.Name, err)
+			test.Fail()
+			continue
+		}
+
+		parameters := make(map[string]interface{}, 8)
+		for _, parameter := range evaluationTest.Parameters {
+			parameters[parameter.Name] = parameter.Value
+		}
+
+		result, err := expression.Evaluate(parameters)
+		if err != nil {
+
+			test.Logf("Test '%s' failed", evaluationTest.Name)
+			test.Logf("Encountered error: %s", err.Error())
+			test.Fail()
+			continue
+		}
+
+		if result != evaluationTest.Expected {
+
+			test.Logf("Test '%s' failed", evaluationTest.Name)
+			test.Logf("Evaluation result '%v' (%T) does not match expected: '%v' (%T)", result, result, evaluationTest.Expected, evaluationTest.Expected)
+			test.Fail()
+		}
+	}
+}
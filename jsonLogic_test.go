@@ -0,0 +1,333 @@
+package govaluate
+
+import (
+	"reflect"
+	"testing"
+)
+
+/*
+	Represents a test of converting an expression to or from JsonLogic.
+*/
+type JsonLogicTest struct {
+	Name       string
+	Expression string
+	JsonLogic  string
+}
+
+func TestToJsonLogic(test *testing.T) {
+
+	testCases := []JsonLogicTest{
+
+		JsonLogicTest{
+
+			Name:       "Comparison",
+			Expression: "x > 3",
+			JsonLogic:  `{">":[{"var":"x"},3]}`,
+		},
+		JsonLogicTest{
+
+			Name:       "Logical chains",
+			Expression: "a == 1 && b != 'two' && (c || d <= 4)",
+			JsonLogic:  `{"and":[{"==":[{"var":"a"},1]},{"!=":[{"var":"b"},"two"]},{"or":[{"var":"c"},{"<=":[{"var":"d"},4]}]}]}`,
+		},
+		JsonLogicTest{
+
+			Name:       "Inversion",
+			Expression: "!(a < 1)",
+			JsonLogic:  `{"!":[{"<":[{"var":"a"},1]}]}`,
+		},
+		JsonLogicTest{
+
+			Name:       "Arithmetic",
+			Expression: "-a + b * 2 - c / 3 % 4 >= 0",
+			JsonLogic:  `{">=":[{"-":[{"+":[{"-":[{"var":"a"}]},{"*":[{"var":"b"},2]}]},{"%":[{"/":[{"var":"c"},3]},4]}]},0]}`,
+		},
+		JsonLogicTest{
+
+			Name:       "Membership",
+			Expression: "name in ('foo', 'bar')",
+			JsonLogic:  `{"in":[{"var":"name"},["foo","bar"]]}`,
+		},
+		JsonLogicTest{
+
+			Name:       "Accessor",
+			Expression: "user.Age >= 18",
+			JsonLogic:  `{">=":[{"var":"user.Age"},18]}`,
+		},
+		JsonLogicTest{
+
+			Name:       "Default",
+			Expression: "(x ?? 5) > 1",
+			JsonLogic:  `{">":[{"var":["x",5]},1]}`,
+		},
+		JsonLogicTest{
+
+			Name:       "Coalesce",
+			Expression: "x ?? y",
+			JsonLogic:  `{"??":[{"var":"x"},{"var":"y"}]}`,
+		},
+		JsonLogicTest{
+
+			Name:       "Ternary",
+			Expression: "a ? 'x' : (b ? 'y' : 'z')",
+			JsonLogic:  `{"if":[{"var":"a"},"x",{"var":"b"},"y","z"]}`,
+		},
+		JsonLogicTest{
+
+			Name:       "Ternary without else",
+			Expression: "a ? 1",
+			JsonLogic:  `{"if":[{"var":"a"},1]}`,
+		},
+		JsonLogicTest{
+
+			Name:       "Regex",
+			Expression: "name =~ '^fo+' && name !~ 'bar'",
+			JsonLogic:  `{"and":[{"=~":[{"var":"name"},"^fo+"]},{"!~":[{"var":"name"},"bar"]}]}`,
+		},
+		JsonLogicTest{
+
+			Name:       "Bitwise",
+			Expression: "(a & 1 | b << 2) ** 2 > ~c",
+			JsonLogic:  `{">":[{"**":[{"|":[{"&":[{"var":"a"},1]},{"<<":[{"var":"b"},2]}]},2]},{"~":[{"var":"c"}]}]}`,
+		},
+		JsonLogicTest{
+
+			Name:       "Time",
+			Expression: "created > '2014-01-02T15:04:05Z'",
+			JsonLogic:  `{">":[{"var":"created"},"2014-01-02T15:04:05Z"]}`,
+		},
+	}
+
+	test.Logf("Running %d JsonLogic export test cases", len(testCases))
+
+	for _, testCase := range testCases {
+
+		expression, err := NewEvaluableExpression(testCase.Expression)
+		if err != nil {
+
+			test.Logf("Test '%s' failed to parse: %s", testCase.Name, err)
+			test.Fail()
+			continue
+		}
+
+		actual, err := expression.ToJsonLogic()
+		if err != nil {
+
+			test.Logf("Test '%s' failed to write JsonLogic: %s", testCase.Name, err)
+			test.Fail()
+			continue
+		}
+
+		if string(actual) != testCase.JsonLogic {
+
+			test.Logf("Test '%s' did not write expected JsonLogic.", testCase.Name)
+			test.Logf("Actual: '%s', expected '%s'", actual, testCase.JsonLogic)
+			test.Fail()
+			continue
+		}
+
+		// every rule written must be read back into the same expression.
+		compiled, err := CompileJsonLogic(actual)
+		if err != nil {
+
+			test.Logf("Test '%s' failed to read its JsonLogic back: %s", testCase.Name, err)
+			test.Fail()
+			continue
+		}
+
+		if !reflect.DeepEqual(compiled.AST(), expression.AST()) {
+
+			test.Logf("Test '%s' read its JsonLogic back into a different expression: '%s'", testCase.Name, compiled.String())
+			test.Fail()
+		}
+	}
+}
+
+func TestCompileJsonLogic(test *testing.T) {
+
+	testCases := []JsonLogicTest{
+
+		JsonLogicTest{
+
+			Name:       "Single argument",
+			JsonLogic:  `{"!": {"var": "a"}}`,
+			Expression: "!a",
+		},
+		JsonLogicTest{
+
+			Name:       "Strict equality",
+			JsonLogic:  `{"and": [{"===": [{"var": "a"}, 1]}, {"!==": [{"var": "b"}, 2]}]}`,
+			Expression: "a == 1 && b != 2",
+		},
+		JsonLogicTest{
+
+			Name:       "Double negation",
+			JsonLogic:  `{"!!": [{"var": "a"}]}`,
+			Expression: "!(!a)",
+		},
+		JsonLogicTest{
+
+			Name:       "Between",
+			JsonLogic:  `{"<": [1, {"var": "x"}, 10]}`,
+			Expression: "1 < x && x < 10",
+		},
+		JsonLogicTest{
+
+			Name:       "Variadic arithmetic",
+			JsonLogic:  `{"+": [{"var": "a"}, {"var": "b"}, {"*": [{"var": "c"}, 2, 3]}]}`,
+			Expression: "a + b + c * 2 * 3",
+		},
+		JsonLogicTest{
+
+			Name:       "Short membership",
+			JsonLogic:  `{"or": [{"in": [{"var": "a"}, ["x"]]}, {"in": [{"var": "b"}, []]}, {"in": [{"var": "c"}, {"var": "list"}]}]}`,
+			Expression: "a == 'x' || false || c in list",
+		},
+		JsonLogicTest{
+
+			Name:       "Elvis",
+			JsonLogic:  `{"?:": [{"var": "a"}, 1, 2]}`,
+			Expression: "a ? 1 : 2",
+		},
+		JsonLogicTest{
+
+			Name:       "Accessor with default",
+			JsonLogic:  `{"==": [{"var": ["user.Name", "nobody"]}, "root"]}`,
+			Expression: "(user.Name ?? 'nobody') == 'root'",
+		},
+		JsonLogicTest{
+
+			Name:       "Function",
+			JsonLogic:  `{">": [{"max": [{"var": "a"}, {"var": "b"}]}, 1.5]}`,
+			Expression: "max(a, b) > 1.5",
+		},
+	}
+
+	functions := map[string]ExpressionFunction{
+		"max": func(arguments ...interface{}) (interface{}, error) {
+			return nil, nil
+		},
+	}
+
+	test.Logf("Running %d JsonLogic import test cases", len(testCases))
+
+	for _, testCase := range testCases {
+
+		expected, err := NewEvaluableExpressionWithFunctions(testCase.Expression, functions)
+		if err != nil {
+
+			test.Logf("Test '%s' failed to parse: %s", testCase.Name, err)
+			test.Fail()
+			continue
+		}
+
+		actual, err := CompileJsonLogic([]byte(testCase.JsonLogic), WithFunctions(functions))
+		if err != nil {
+
+			test.Logf("Test '%s' failed to read JsonLogic: %s", testCase.Name, err)
+			test.Fail()
+			continue
+		}
+
+		actualText, _ := actual.Format()
+		expectedText, _ := expected.Format()
+
+		if actualText != expectedText {
+
+			test.Logf("Test '%s' did not read expected expression.", testCase.Name)
+			test.Logf("Actual: '%s', expected '%s'", actualText, expectedText)
+			test.Fail()
+		}
+	}
+}
+
+func TestJsonLogicEvaluation(test *testing.T) {
+
+	expression, err := CompileJsonLogic([]byte(`{"if": [{"<": [{"var": "temp"}, 0]}, "freezing", {"<": [{"var": "temp"}, 100]}, "liquid", "gas"]}`))
+	if err != nil {
+		test.Logf("Failed to read JsonLogic: %s", err)
+		test.Fail()
+		return
+	}
+
+	for temp, expected := range map[float64]string{-10: "freezing", 20: "liquid", 150: "gas"} {
+
+		actual, err := expression.Evaluate(map[string]interface{}{"temp": temp})
+		if err != nil || actual != expected {
+			test.Logf("Expected '%s' for %v, but got '%v' (%v)", expected, temp, actual, err)
+			test.Fail()
+		}
+	}
+
+	// numbers aren't rounded through floats in decimal mode.
+	expression, err = CompileJsonLogic([]byte(`{"==": [{"+": [0.1, {"var": "x"}]}, 0.3]}`), WithNumericMode(DECIMAL_NUMERICS))
+	if err != nil {
+		test.Logf("Failed to read JsonLogic: %s", err)
+		test.Fail()
+		return
+	}
+
+	actual, err := expression.Evaluate(map[string]interface{}{"x": 0.2})
+	if err != nil || actual != true {
+		test.Logf("Expected decimal JsonLogic to be true, but got '%v' (%v)", actual, err)
+		test.Fail()
+	}
+}
+
+func TestJsonLogicFailure(test *testing.T) {
+
+	rules := []string{
+		`{"and": []}`,
+		`{"==": [1]}`,
+		`{"a": 1, "b": 2}`,
+		`{"var": ""}`,
+		`{"var": ["a", 1, 2]}`,
+		`{"unknown": [1]}`,
+		`{"==": [{"var": "a"}, null]}`,
+		`{"if": [true]}`,
+		`{"==": [`,
+	}
+
+	test.Logf("Running %d JsonLogic failure test cases", len(rules))
+
+	for _, rule := range rules {
+
+		_, err := CompileJsonLogic([]byte(rule))
+		if err == nil {
+			test.Logf("'%s' was expected to fail to read", rule)
+			test.Fail()
+		}
+	}
+
+	expression, _ := NewEvaluableExpression("foo.Bar() == 1")
+	_, err := expression.ToJsonLogic()
+	if err == nil {
+		test.Logf("Expected a method call to fail to write as JsonLogic")
+		test.Fail()
+	}
+
+	expression, _ = NewEvaluableExpression("a > 1 / 0")
+	_, err = expression.ToJsonLogic()
+	if err == nil {
+		test.Logf("Expected an infinite number to fail to write as JsonLogic")
+		test.Fail()
+	}
+}
+
+func TestJsonLogicUnsignedIntegers(test *testing.T) {
+
+	expression, _ := Compile("a == 18446744073709551615", WithNumericMode(INTEGER_NUMERICS))
+
+	actual, err := expression.ToJsonLogic()
+	if err != nil || string(actual) != `{"==":[{"var":"a"},18446744073709551615]}` {
+		test.Logf("Unsigned integer was written as '%s' (%v)", actual, err)
+		test.Fail()
+		return
+	}
+
+	compiled, err := CompileJsonLogic(actual, WithNumericMode(INTEGER_NUMERICS))
+	if err != nil || !reflect.DeepEqual(compiled.AST(), expression.AST()) {
+		test.Logf("Unsigned integer was read back as '%v' (%v)", compiled, err)
+		test.Fail()
+	}
+}
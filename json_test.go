@@ -0,0 +1,242 @@
+package govaluate
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+/*
+	Represents a test of serializing an expression, then reading it back.
+*/
+type SerializationTest struct {
+	Name       string
+	Input      string
+	Options    []Option
+	Parameters map[string]interface{}
+	Expected   interface{}
+}
+
+func TestSerialization(test *testing.T) {
+
+	functions := map[string]ExpressionFunction{
+		"double": func(arguments ...interface{}) (interface{}, error) {
+			return arguments[0].(float64) * 2, nil
+		},
+	}
+
+	testCases := []SerializationTest{
+
+		SerializationTest{
+
+			Name:       "Operators",
+			Input:      "(a + 2) * -b > 4 && !(c || d) ? 'yes' : 'no'",
+			Parameters: map[string]interface{}{"a": 1, "b": -2, "c": false, "d": false},
+			Expected:   "yes",
+		},
+		SerializationTest{
+
+			Name:       "Literals",
+			Input:      "a == 'foo' && b > '2014-01-02T15:04:05Z' && c =~ '^x+$' && d == true",
+			Parameters: map[string]interface{}{"a": "foo", "b": float64(time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC).Unix()), "c": "xx", "d": true},
+			Expected:   true,
+		},
+		SerializationTest{
+
+			Name:       "Arrays and coalescence",
+			Input:      "(a ?? 2) in (1, 2, 3)",
+			Parameters: map[string]interface{}{"a": nil},
+			Expected:   true,
+		},
+		SerializationTest{
+
+			Name:       "Accessors",
+			Input:      "foo.Int - bar + foo.Func()",
+			Parameters: map[string]interface{}{"foo": fooParameter.Value, "bar": 1},
+			Expected:   "100funk",
+		},
+		SerializationTest{
+
+			Name:       "Functions",
+			Input:      "double(a) + double(1)",
+			Options:    []Option{WithFunctions(functions)},
+			Parameters: map[string]interface{}{"a": 3},
+			Expected:   float64(8),
+		},
+		SerializationTest{
+
+			Name:       "Integer numerics",
+			Input:      "a / 2 + 9007199254740993",
+			Options:    []Option{WithNumericMode(INTEGER_NUMERICS)},
+			Parameters: map[string]interface{}{"a": 7},
+			Expected:   int64(9007199254740996),
+		},
+		SerializationTest{
+
+			Name:       "Decimal numerics",
+			Input:      "a / 3 + 0.1",
+			Options:    []Option{WithDecimalRounding(DecimalRounding{Scale: 2, Mode: ROUND_HALF_UP})},
+			Parameters: map[string]interface{}{"a": 2},
+			Expected:   "0.77",
+		},
+		SerializationTest{
+
+			Name:       "Unsigned integers",
+			Input:      "a == 18446744073709551615",
+			Options:    []Option{WithNumericMode(INTEGER_NUMERICS)},
+			Parameters: map[string]interface{}{"a": uint64(18446744073709551615)},
+			Expected:   true,
+		},
+		SerializationTest{
+
+			Name:       "Infinities",
+			Input:      "a < 1 / 0 && a > -1 / 0",
+			Parameters: map[string]interface{}{"a": 5},
+			Expected:   true,
+		},
+		SerializationTest{
+
+			Name:       "Not a number",
+			Input:      "a == 0 / 0",
+			Parameters: map[string]interface{}{"a": 5},
+			Expected:   false,
+		},
+	}
+
+	test.Logf("Running %d serialization test cases", len(testCases))
+
+	for _, testCase := range testCases {
+
+		expression, err := Compile(testCase.Input, testCase.Options...)
+		if err != nil {
+
+			test.Logf("Test '%s' failed to parse: %s", testCase.Name, err)
+			test.Fail()
+			continue
+		}
+
+		data, err := json.Marshal(expression)
+		if err != nil {
+
+			test.Logf("Test '%s' failed to serialize: %s", testCase.Name, err)
+			test.Fail()
+			continue
+		}
+
+		// functions are given again, but the numeric mode is stored.
+		read, err := UnmarshalExpression(data, WithFunctions(functions))
+		if err != nil {
+
+			test.Logf("Test '%s' failed to read back: %s", testCase.Name, err)
+			test.Fail()
+			continue
+		}
+
+		readText, _ := read.Format()
+		expectedText, _ := expression.Format()
+
+		if read.String() != expression.String() || readText != expectedText {
+
+			test.Logf("Test '%s' was read back as a different expression: '%s'", testCase.Name, readText)
+			test.Fail()
+			continue
+		}
+
+		actual, err := read.Evaluate(testCase.Parameters)
+		if err != nil {
+
+			test.Logf("Test '%s' failed to evaluate: %s", testCase.Name, err)
+			test.Fail()
+			continue
+		}
+
+		if decimal, isDecimal := actual.(interface{ FloatString(int) string }); isDecimal {
+			actual = decimal.FloatString(2)
+		}
+
+		if actual != testCase.Expected {
+
+			test.Logf("Test '%s' evaluated to '%v' (%T), expected '%v'", testCase.Name, actual, actual, testCase.Expected)
+			test.Fail()
+		}
+	}
+}
+
+func TestSerializationSettings(test *testing.T) {
+
+	var read EvaluableExpression
+
+	expression, _ := Compile("a > 1", WithBudget(EvaluationBudget{MaxStages: 10}), WithQueryDateFormat("2006"))
+	expression.ChecksTypes = false
+
+	data, err := json.Marshal(expression)
+	if err != nil {
+		test.Logf("Failed to serialize: %s", err)
+		test.Fail()
+		return
+	}
+
+	err = json.Unmarshal(data, &read)
+	if err != nil {
+		test.Logf("Failed to read back: %s", err)
+		test.Fail()
+		return
+	}
+
+	if read.Budget != expression.Budget || read.QueryDateFormat != "2006" || read.ChecksTypes {
+		test.Logf("Settings were not read back: %+v", read)
+		test.Fail()
+	}
+
+	// expressions built from trees have no original text, but are still written.
+	expression, _ = CompileAST(Add(Var("a"), Num(1)))
+
+	data, _ = json.Marshal(expression)
+	err = json.Unmarshal(data, &read)
+	if err != nil || read.String() != "a + 1" {
+		test.Logf("Expression built from a tree was read back as '%s' (%v)", read.String(), err)
+		test.Fail()
+	}
+}
+
+func TestSerializationFailure(test *testing.T) {
+
+	var read EvaluableExpression
+
+	functions := map[string]ExpressionFunction{
+		"foo": func(arguments ...interface{}) (interface{}, error) {
+			return nil, nil
+		},
+	}
+
+	expression, _ := NewEvaluableExpressionWithFunctions("foo() == 1", functions)
+	data, _ := json.Marshal(expression)
+
+	err := json.Unmarshal(data, &read)
+	if err == nil {
+		test.Logf("Expected an expression with a function to fail to read without functions")
+		test.Fail()
+	}
+
+	inputs := []string{
+		`{"version": 2, "numericMode": "FLOAT_NUMERICS", "root": null}`,
+		`{"version": 1, "numericMode": "BINARY_NUMERICS", "root": null}`,
+		`{"version": 1, "numericMode": "FLOAT_NUMERICS", "root": {"kind": "binary", "operator": "<>", "operands": [{"kind": "variable", "name": "a"}, {"kind": "variable", "name": "b"}]}}`,
+		`{"version": 1, "numericMode": "FLOAT_NUMERICS", "root": {"kind": "unary", "operator": "!", "operands": []}}`,
+		`{"version": 1, "numericMode": "FLOAT_NUMERICS", "root": {"kind": "literal", "type": "pattern", "value": "[a"}}`,
+		`{"version": 1, "numericMode": "FLOAT_NUMERICS", "root": {"kind": "literal", "type": "complex", "value": 1}}`,
+		`{"version": 1, "numericMode": "FLOAT_NUMERICS", "root": {"kind": "literal", "type": "float", "value": "Infinity"}}`,
+		`{"version": 1, "numericMode": "FLOAT_NUMERICS", "root": {"kind": "group"}}`,
+	}
+
+	test.Logf("Running %d serialization failure test cases", len(inputs))
+
+	for _, input := range inputs {
+
+		_, err = UnmarshalExpression([]byte(input))
+		if err == nil {
+			test.Logf("'%s' was expected to fail to read", input)
+			test.Fail()
+		}
+	}
+}
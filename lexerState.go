@@ -318,45 +318,115 @@ func (this lexerState) canTransitionTo(kind TokenKind) bool {
 
 func checkExpressionSyntax(tokens []ExpressionToken) error {
 
+	var errs ParseErrors
+
+	errs = findSyntaxErrors(tokens, false)
+	if len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+/*
+	Checks that every token in [tokens] can follow the one before it.
+	If [recovering] is false, only the first problem is returned.
+	Otherwise, after each problem this skips ahead to the next clause boundary (a separator, logical operator, or parenthesis)
+	and continues checking from there. UNKNOWN tokens are assumed to have already been reported by the lexer, and are skipped the same way.
+*/
+func findSyntaxErrors(tokens []ExpressionToken, recovering bool) ParseErrors {
+
 	var state lexerState
 	var lastToken ExpressionToken
+	var errs ParseErrors
 	var err error
+	var skipping bool
 
 	state = validLexerStates[0]
 
 	for _, token := range tokens {
 
+		if token.Kind == UNKNOWN {
+			skipping = true
+			continue
+		}
+
+		if skipping {
+
+			if !isClauseBoundary(token.Kind) {
+				continue
+			}
+
+			skipping = false
+			state, _ = getLexerStateForToken(token.Kind)
+			lastToken = token
+			continue
+		}
+
 		if !state.canTransitionTo(token.Kind) {
 
 			// call out a specific error for tokens looking like they want to be functions.
 			if lastToken.Kind == VARIABLE && token.Kind == CLAUSE {
-				return errors.New("Undefined function " + lastToken.Value.(string))
+				errs = append(errs, newTokenParseError(lastToken, nil, "Undefined function %s", lastToken.Value.(string)))
+			} else {
+
+				firstStateName := fmt.Sprintf("%s [%v]", state.kind.String(), lastToken.Value)
+				nextStateName := fmt.Sprintf("%s [%v]", token.Kind.String(), token.Value)
+
+				errs = append(errs, newTokenParseError(token, state.validNextKinds, "Cannot transition token types from %s to %s", firstStateName, nextStateName))
 			}
 
-			firstStateName := fmt.Sprintf("%s [%v]", state.kind.String(), lastToken.Value)
-			nextStateName := fmt.Sprintf("%s [%v]", token.Kind.String(), token.Value)
+			if !recovering {
+				return errs
+			}
 
-			return errors.New("Cannot transition token types from " + firstStateName + " to " + nextStateName)
+			// the failing token may itself be a boundary, in which case checking can resume right after it.
+			skipping = !isClauseBoundary(token.Kind)
+			if !skipping {
+				state, _ = getLexerStateForToken(token.Kind)
+				lastToken = token
+			}
+			continue
 		}
 
 		state, err = getLexerStateForToken(token.Kind)
 		if err != nil {
-			return err
+			return append(errs, newTokenParseError(token, nil, "%s", err.Error()))
 		}
 
 		if !state.isNullable && token.Value == nil {
 
-			errorMsg := fmt.Sprintf("Token kind '%v' cannot have a nil value", token.Kind.String())
-			return errors.New(errorMsg)
+			errs = append(errs, newTokenParseError(token, nil, "Token kind '%v' cannot have a nil value", token.Kind.String()))
+			if !recovering {
+				return errs
+			}
 		}
 
 		lastToken = token
 	}
 
-	if !state.isEOF {
-		return errors.New("Unexpected end of expression")
+	if !skipping && !state.isEOF {
+		errs = append(errs, newPositionParseError(lastToken.End, state.validNextKinds, "Unexpected end of expression"))
 	}
-	return nil
+	return errs
+}
+
+/*
+	Returns true if the given [kind] of token separates one clause of an expression from another,
+	meaning that syntax checking can safely restart from it after an error.
+*/
+func isClauseBoundary(kind TokenKind) bool {
+
+	switch kind {
+	case SEPARATOR:
+		fallthrough
+	case LOGICALOP:
+		fallthrough
+	case CLAUSE:
+		fallthrough
+	case CLAUSE_CLOSE:
+		return true
+	}
+	return false
 }
 
 func getLexerStateForToken(kind TokenKind) (lexerState, error) {
@@ -368,6 +438,6 @@ func getLexerStateForToken(kind TokenKind) (lexerState, error) {
 		}
 	}
 
-	errorMsg := fmt.Sprintf("No lexer state found for token kind '%v'\n", kind.String())
+	errorMsg := fmt.Sprintf("No lexer state found for token kind '%v'", kind.String())
 	return validLexerStates[0], errors.New(errorMsg)
 }
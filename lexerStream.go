@@ -1,22 +1,49 @@
 package govaluate
 
 type lexerStream struct {
-	source   []rune
-	position int
-	length   int
+	source    []rune
+	positions []Position
+	position  int
+	length    int
 }
 
 func newLexerStream(source string) *lexerStream {
 
 	var ret *lexerStream
 	var runes []rune
+	var positions []Position
+	var line, column int
+
+	line = 1
+	column = 1
+
+	for offset, character := range source {
 
-	for _, character := range source {
 		runes = append(runes, character)
+		positions = append(positions, Position{
+			Offset: offset,
+			Line:   line,
+			Column: column,
+		})
+
+		if character == '\n' {
+			line++
+			column = 1
+			continue
+		}
+		column++
 	}
 
+	// one extra position, representing the end of the stream.
+	positions = append(positions, Position{
+		Offset: len(source),
+		Line:   line,
+		Column: column,
+	})
+
 	ret = new(lexerStream)
 	ret.source = runes
+	ret.positions = positions
 	ret.length = len(runes)
 	return ret
 }
@@ -37,3 +64,18 @@ func (this *lexerStream) rewind(amount int) {
 func (this lexerStream) canRead() bool {
 	return this.position < this.length
 }
+
+/*
+	Returns the source position of the character at the given rune [index] of the stream.
+	Indices at (or past) the end of the stream give the position just after the last character.
+*/
+func (this lexerStream) positionOf(index int) Position {
+
+	if index < 0 {
+		index = 0
+	}
+	if index > this.length {
+		index = this.length
+	}
+	return this.positions[index]
+}
@@ -0,0 +1,247 @@
+package govaluate
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+/*
+	Represents a test of correctly creating a Mongo query filter from an expression.
+*/
+type MongoQueryTest struct {
+	Name     string
+	Input    string
+	Expected map[string]interface{}
+}
+
+/*
+	Shorthand for the filters built by these tests.
+*/
+type bsonM map[string]interface{}
+type bsonA []interface{}
+
+func TestMongoQuery(test *testing.T) {
+
+	testCases := []MongoQueryTest{
+
+		MongoQueryTest{
+
+			Name:     "Equality",
+			Input:    "name == 'foo'",
+			Expected: bsonM{"name": "foo"},
+		},
+		MongoQueryTest{
+
+			Name:     "Comparators",
+			Input:    "a != 1 && b > 2 && c >= 3 && d < 4 && e <= 5",
+			Expected: bsonM{"$and": bsonA{bsonM{"a": bsonM{"$ne": 1.0}}, bsonM{"b": bsonM{"$gt": 2.0}}, bsonM{"c": bsonM{"$gte": 3.0}}, bsonM{"d": bsonM{"$lt": 4.0}}, bsonM{"e": bsonM{"$lte": 5.0}}}},
+		},
+		MongoQueryTest{
+
+			Name:     "Literal on the left",
+			Input:    "10 > age",
+			Expected: bsonM{"age": bsonM{"$lt": 10.0}},
+		},
+		MongoQueryTest{
+
+			Name:     "Computed literal",
+			Input:    "age >= 6 * 3",
+			Expected: bsonM{"age": bsonM{"$gte": 18.0}},
+		},
+		MongoQueryTest{
+
+			Name:     "Or",
+			Input:    "a == 1 || (b == 2 || c == 3)",
+			Expected: bsonM{"$or": bsonA{bsonM{"a": 1.0}, bsonM{"b": 2.0}, bsonM{"c": 3.0}}},
+		},
+		MongoQueryTest{
+
+			Name:     "Nested logic",
+			Input:    "a == 1 && (b == 2 || c == 3)",
+			Expected: bsonM{"$and": bsonA{bsonM{"a": 1.0}, bsonM{"$or": bsonA{bsonM{"b": 2.0}, bsonM{"c": 3.0}}}}},
+		},
+		MongoQueryTest{
+
+			Name:     "Accessor",
+			Input:    "user.Address.City == 'Paris'",
+			Expected: bsonM{"user.Address.City": "Paris"},
+		},
+		MongoQueryTest{
+
+			Name:     "Boolean field",
+			Input:    "active && !deleted",
+			Expected: bsonM{"$and": bsonA{bsonM{"active": true}, bsonM{"deleted": bsonM{"$ne": true}}}},
+		},
+		MongoQueryTest{
+
+			Name:     "Regex",
+			Input:    "name =~ '^fo+' && name !~ 'bar'",
+			Expected: bsonM{"$and": bsonA{bsonM{"name": bsonM{"$regex": "^fo+"}}, bsonM{"name": bsonM{"$not": bsonM{"$regex": "bar"}}}}},
+		},
+		MongoQueryTest{
+
+			Name:     "In",
+			Input:    "tag in ('a', 'b', 'c')",
+			Expected: bsonM{"tag": bsonM{"$in": bsonA{"a", "b", "c"}}},
+		},
+		MongoQueryTest{
+
+			Name:     "Inverted comparison",
+			Input:    "!(age > 18)",
+			Expected: bsonM{"age": bsonM{"$not": bsonM{"$gt": 18.0}}},
+		},
+		MongoQueryTest{
+
+			Name:     "Inverted equality",
+			Input:    "!(name == 'foo')",
+			Expected: bsonM{"name": bsonM{"$ne": "foo"}},
+		},
+		MongoQueryTest{
+
+			Name:     "Inverted negative regex",
+			Input:    "!(name !~ 'foo')",
+			Expected: bsonM{"name": bsonM{"$regex": "foo"}},
+		},
+		MongoQueryTest{
+
+			Name:     "Inverted group",
+			Input:    "!(a == 1 || b == 2)",
+			Expected: bsonM{"$nor": bsonA{bsonM{"$or": bsonA{bsonM{"a": 1.0}, bsonM{"b": 2.0}}}}},
+		},
+		MongoQueryTest{
+
+			Name:     "Coalesce which matches missing fields",
+			Input:    "(age ?? 0) < 18",
+			Expected: bsonM{"$or": bsonA{bsonM{"age": bsonM{"$lt": 18.0}}, bsonM{"age": bsonM{"$exists": false}}}},
+		},
+		MongoQueryTest{
+
+			Name:     "Coalesce which excludes missing fields",
+			Input:    "(age ?? 0) >= 18",
+			Expected: bsonM{"age": bsonM{"$gte": 18.0, "$exists": true}},
+		},
+		MongoQueryTest{
+
+			Name:     "Coalesce with literal on the left",
+			Input:    "18 > (age ?? 0)",
+			Expected: bsonM{"$or": bsonA{bsonM{"age": bsonM{"$lt": 18.0}}, bsonM{"age": bsonM{"$exists": false}}}},
+		},
+		MongoQueryTest{
+
+			Name:     "Coalesced boolean",
+			Input:    "(active ?? true) && (deleted ?? false)",
+			Expected: bsonM{"$and": bsonA{bsonM{"$or": bsonA{bsonM{"active": true}, bsonM{"active": bsonM{"$exists": false}}}}, bsonM{"deleted": bsonM{"$eq": true, "$exists": true}}}},
+		},
+		MongoQueryTest{
+
+			Name:     "Time",
+			Input:    "created > '2014-01-02T00:00:00Z'",
+			Expected: bsonM{"created": bsonM{"$gt": time.Date(2014, 1, 2, 0, 0, 0, 0, time.UTC)}},
+		},
+		MongoQueryTest{
+
+			Name:     "Constants",
+			Input:    "1 < 2",
+			Expected: bsonM{},
+		},
+	}
+
+	test.Logf("Running %d Mongo query test cases", len(testCases))
+
+	for _, testCase := range testCases {
+
+		expression, err := NewEvaluableExpression(testCase.Input)
+		if err != nil {
+
+			test.Logf("Test '%s' failed to parse: %s", testCase.Name, err)
+			test.Fail()
+			continue
+		}
+
+		actual, err := expression.ToMongoQuery()
+		if err != nil {
+
+			test.Logf("Test '%s' failed to create query: %s", testCase.Name, err)
+			test.Fail()
+			continue
+		}
+
+		if !reflect.DeepEqual(normalizeMongoQuery(actual), normalizeMongoQuery(testCase.Expected)) {
+
+			test.Logf("Test '%s' did not create expected query.", testCase.Name)
+			test.Logf("Actual: '%v', expected '%v'", actual, testCase.Expected)
+			test.Fail()
+		}
+	}
+}
+
+func TestMongoQueryFailure(test *testing.T) {
+
+	functions := map[string]ExpressionFunction{
+		"foo": func(arguments ...interface{}) (interface{}, error) {
+			return nil, nil
+		},
+	}
+
+	inputs := []string{
+		"a + 1 > 2",
+		"a > b",
+		"a ? b : c",
+		"foo() == 1",
+		"a.Method() == 1",
+		"a in b",
+		"'foo' =~ a",
+		"1 + 2",
+	}
+
+	test.Logf("Running %d Mongo query failure test cases", len(inputs))
+
+	for _, input := range inputs {
+
+		expression, err := NewEvaluableExpressionWithFunctions(input, functions)
+		if err != nil {
+
+			test.Logf("'%s' failed to parse: %s", input, err)
+			test.Fail()
+			continue
+		}
+
+		_, err = expression.ToMongoQuery()
+		if err == nil {
+
+			test.Logf("'%s' was expected to fail to create a query", input)
+			test.Fail()
+		}
+	}
+}
+
+/*
+	Converts the shorthand types used by these tests into the types given by `ToMongoQuery`, so they can be compared.
+*/
+func normalizeMongoQuery(value interface{}) interface{} {
+
+	switch value.(type) {
+
+	case bsonM:
+		return normalizeMongoQuery(map[string]interface{}(value.(bsonM)))
+
+	case map[string]interface{}:
+		ret := make(map[string]interface{})
+		for key, element := range value.(map[string]interface{}) {
+			ret[key] = normalizeMongoQuery(element)
+		}
+		return ret
+
+	case bsonA:
+		return normalizeMongoQuery([]interface{}(value.(bsonA)))
+
+	case []interface{}:
+		ret := make([]interface{}, 0)
+		for _, element := range value.([]interface{}) {
+			ret = append(ret, normalizeMongoQuery(element))
+		}
+		return ret
+	}
+	return value
+}
@@ -0,0 +1,306 @@
+package govaluate
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+/*
+	Represents a test of an expression compiled from the given [Input] tree.
+*/
+type ASTCompileTest struct {
+	Name       string
+	Input      Node
+	Options    []Option
+	Parameters map[string]interface{}
+	Expected   interface{}
+}
+
+/*
+	Represents a test of a tree which can't be compiled.
+*/
+type ASTCompileFailureTest struct {
+	Name     string
+	Input    Node
+	Expected string
+}
+
+func TestCompileAST(test *testing.T) {
+
+	strlen := func(arguments ...interface{}) (interface{}, error) {
+		return float64(len(arguments[0].(string))), nil
+	}
+
+	compileTests := []ASTCompileTest{
+
+		ASTCompileTest{
+
+			Name:       "Builder",
+			Input:      And(Gt(Var("x"), Num(3)), Eq(Var("name"), Str("foo"))),
+			Parameters: map[string]interface{}{"x": 4, "name": "foo"},
+			Expected:   true,
+		},
+		ASTCompileTest{
+
+			Name:       "Chained logical operators",
+			Input:      Or(Var("a"), Var("b"), Not(Var("c"))),
+			Parameters: map[string]interface{}{"a": false, "b": false, "c": false},
+			Expected:   true,
+		},
+		ASTCompileTest{
+
+			Name:       "Precedence kept by parentheses",
+			Input:      Mul(Add(Var("a"), Num(2)), Num(3)),
+			Parameters: map[string]interface{}{"a": 1},
+			Expected:   9.0,
+		},
+		ASTCompileTest{
+
+			Name:       "Right operand of the same precedence",
+			Input:      Sub(Num(10), Sub(Var("a"), Num(3))),
+			Parameters: map[string]interface{}{"a": 5},
+			Expected:   8.0,
+		},
+		ASTCompileTest{
+
+			Name:       "Negated operator",
+			Input:      Neg(Add(Var("a"), Num(1))),
+			Parameters: map[string]interface{}{"a": 1},
+			Expected:   -2.0,
+		},
+		ASTCompileTest{
+
+			Name:       "Nested ternary",
+			Input:      Ternary(Var("a"), Ternary(Var("b"), Str("ab"), Str("a")), Str("none")),
+			Parameters: map[string]interface{}{"a": true, "b": false},
+			Expected:   "a",
+		},
+		ASTCompileTest{
+
+			Name:       "Logical condition of a ternary",
+			Input:      Ternary(Or(Var("a"), Var("b")), Num(1), Num(2)),
+			Parameters: map[string]interface{}{"a": false, "b": true},
+			Expected:   1.0,
+		},
+		ASTCompileTest{
+
+			Name:       "In array",
+			Input:      In(Var("a"), Array(Str("foo"), Str("bar"))),
+			Parameters: map[string]interface{}{"a": "bar"},
+			Expected:   true,
+		},
+		ASTCompileTest{
+
+			Name:       "Regex",
+			Input:      Match(Var("a"), Str("^fo+$")),
+			Parameters: map[string]interface{}{"a": "fooo"},
+			Expected:   true,
+		},
+		ASTCompileTest{
+
+			Name:       "Coalesce",
+			Input:      Coalesce(Var("a"), Str("default")),
+			Parameters: map[string]interface{}{"a": nil},
+			Expected:   "default",
+		},
+		ASTCompileTest{
+
+			Name:       "Function",
+			Input:      Add(Call("strlen", strlen, Var("a")), Num(1)),
+			Parameters: map[string]interface{}{"a": "four"},
+			Expected:   5.0,
+		},
+		ASTCompileTest{
+
+			Name:       "Accessor",
+			Input:      Eq(Field("foo", "String"), Str("string!")),
+			Parameters: map[string]interface{}{"foo": dummyParameter{String: "string!"}},
+			Expected:   true,
+		},
+		ASTCompileTest{
+
+			Name:       "Method",
+			Input:      Method([]string{"foo", "FuncArgStr"}, Str("bar")),
+			Parameters: map[string]interface{}{"foo": dummyParameter{}},
+			Expected:   "bar",
+		},
+		ASTCompileTest{
+
+			Name:       "Integer numerics",
+			Input:      Div(Int(7), Var("a")),
+			Options:    []Option{WithNumericMode(INTEGER_NUMERICS)},
+			Parameters: map[string]interface{}{"a": 2},
+			Expected:   int64(3),
+		},
+		ASTCompileTest{
+
+			Name:       "Numbers converted to the numeric mode",
+			Input:      Add(Lit(1), Lit(int32(2))),
+			Options:    []Option{WithNumericMode(INTEGER_NUMERICS)},
+			Parameters: map[string]interface{}{},
+			Expected:   int64(3),
+		},
+	}
+
+	fmt.Printf("Running %d AST compile test cases...\n", len(compileTests))
+
+	for _, compileTest := range compileTests {
+
+		expression, err := CompileAST(compileTest.Input, compileTest.Options...)
+		if err != nil {
+
+			test.Logf("Test '%s' failed to compile: '%s'", compileTest.Name, err)
+			test.Fail()
+			continue
+		}
+
+		result, err := expression.Evaluate(compileTest.Parameters)
+		if err != nil {
+
+			test.Logf("Test '%s' failed to evaluate: '%s'", compileTest.Name, err)
+			test.Fail()
+			continue
+		}
+
+		if !reflect.DeepEqual(result, compileTest.Expected) {
+
+			test.Logf("Test '%s' failed", compileTest.Name)
+			test.Logf("Evaluation result '%v' (%T) does not match expected: '%v' (%T)", result, result, compileTest.Expected, compileTest.Expected)
+			test.Fail()
+		}
+	}
+}
+
+func TestCompileASTFailure(test *testing.T) {
+
+	compileTests := []ASTCompileFailureTest{
+
+		ASTCompileFailureTest{
+
+			Name:     "Missing operand",
+			Input:    Gt(Var("a"), nil),
+			Expected: "Missing operand",
+		},
+		ASTCompileFailureTest{
+
+			Name:     "Empty chain",
+			Input:    And(),
+			Expected: "Missing operand",
+		},
+		ASTCompileFailureTest{
+
+			Name:     "Prefix operator used as binary",
+			Input:    &BinaryNode{Operator: INVERT, Left: Var("a"), Right: Var("b")},
+			Expected: "not a binary operator",
+		},
+		ASTCompileFailureTest{
+
+			Name:     "Binary operator used as prefix",
+			Input:    &UnaryNode{Operator: PLUS, Operand: Var("a")},
+			Expected: "not a prefix operator",
+		},
+		ASTCompileFailureTest{
+
+			Name:     "Function without implementation",
+			Input:    &FunctionNode{Name: "foo"},
+			Expected: "has no implementation",
+		},
+		ASTCompileFailureTest{
+
+			Name:     "Single element array",
+			Input:    In(Var("a"), Array(Num(1))),
+			Expected: "at least two elements",
+		},
+		ASTCompileFailureTest{
+
+			Name:     "Unrepresentable literal",
+			Input:    Lit(struct{}{}),
+			Expected: "Unable to represent literal",
+		},
+		ASTCompileFailureTest{
+
+			Name:     "Invalid pattern",
+			Input:    Match(Var("a"), Str("[")),
+			Expected: "missing closing ]",
+		},
+	}
+
+	fmt.Printf("Running %d AST compile failure test cases...\n", len(compileTests))
+
+	for _, compileTest := range compileTests {
+
+		_, err := CompileAST(compileTest.Input)
+		if err == nil || !strings.Contains(err.Error(), compileTest.Expected) {
+
+			test.Logf("Test '%s' failed", compileTest.Name)
+			test.Logf("Error '%v' does not contain: '%s'", err, compileTest.Expected)
+			test.Fail()
+		}
+	}
+}
+
+/*
+	Tests that every tree given by `AST()` compiles back into an expression with the same tree and the same result.
+*/
+func TestCompileASTRoundTrip(test *testing.T) {
+
+	inputs := []string{
+		"(a + b) * c",
+		"a - (b - c)",
+		"a ** (b ** c)",
+		"-(a + b) * -c",
+		"!(a > b) || c < 2",
+		"(a || b) && !(b && a)",
+		"a > 1 ? (b > 1 ? 'x' : 'y') : 'z'",
+		"a > 1 ? 'x' : b > 1 ? 'y' : 'z'",
+		"(a ?? b) + (c ?? 1)",
+		"(a & 3) << (b | 1)",
+		"a in (1, b + 1, (2, 3))",
+		"foo.Int + 1 > a ? 'x' : foo.FuncArgStr(e)",
+		"'2014-01-02' < d && e =~ '^a'",
+	}
+
+	parameters := map[string]interface{}{
+		"a":   2,
+		"b":   3,
+		"c":   4,
+		"d":   "2015-01-02",
+		"e":   "abc",
+		"foo": dummyParameter{Int: 101},
+	}
+
+	fmt.Printf("Running %d AST round trip test cases...\n", len(inputs))
+
+	for _, input := range inputs {
+
+		parsed, err := NewEvaluableExpression(input)
+		if err != nil {
+			test.Logf("'%s' failed to parse: '%s'", input, err)
+			test.Fail()
+			continue
+		}
+
+		compiled, err := CompileAST(parsed.AST())
+		if err != nil {
+			test.Logf("'%s' failed to compile: '%s'", input, err)
+			test.Fail()
+			continue
+		}
+
+		if !reflect.DeepEqual(compiled.AST(), parsed.AST()) {
+			test.Logf("'%s' compiled into tree '%#v', expected '%#v'", input, compiled.AST(), parsed.AST())
+			test.Fail()
+			continue
+		}
+
+		expected, expectedErr := parsed.Evaluate(parameters)
+		result, err := compiled.Evaluate(parameters)
+
+		if !reflect.DeepEqual(result, expected) || fmt.Sprint(err) != fmt.Sprint(expectedErr) {
+			test.Logf("'%s' evaluated to '%v' (%v), expected '%v' (%v)", input, result, err, expected, expectedErr)
+			test.Fail()
+		}
+	}
+}
@@ -0,0 +1,478 @@
+package govaluate
+
+import (
+	"math/big"
+	"reflect"
+)
+
+/*
+	Applies algebraic and boolean identities to a syntax tree. See `WithSimplification`.
+*/
+type nodeSimplifier struct {
+	options *compileOptions
+}
+
+/*
+	Replaces the stages and tokens of the given [expression] with those of its simplified syntax tree.
+*/
+func simplifyExpression(expression *EvaluableExpression, options *compileOptions) error {
+
+	var simplifier *nodeSimplifier
+	var root Node
+	var tokens []ExpressionToken
+	var stages *evaluationStage
+	var err error
+
+	if expression.evaluationStages == nil {
+		return nil
+	}
+
+	simplifier = &nodeSimplifier{options: options}
+	root = simplifier.simplify(buildNode(expression.evaluationStages))
+
+	// some trees can't be written as tokens (such as those holding a nil literal). These are left as they were.
+	tokens, err = nodeTokens(root, options.numericMode)
+	if err != nil {
+		return nil
+	}
+
+	stages, err = planStages(tokens, options)
+	if err != nil {
+		return err
+	}
+
+	expression.tokens = tokens
+	expression.evaluationStages = stages
+	return nil
+}
+
+/*
+	Returns the simplified form of the tree under the given [node].
+	The given tree is never changed, since it may be shared; any node which changes is replaced by a new one.
+*/
+func (this *nodeSimplifier) simplify(node Node) Node {
+
+	switch node.(type) {
+
+	case *AccessorNode:
+
+		accessor := *node.(*AccessorNode)
+		accessor.Arguments = this.simplifyAll(accessor.Arguments)
+		return &accessor
+
+	case *FunctionNode:
+
+		function := *node.(*FunctionNode)
+		function.Arguments = this.simplifyAll(function.Arguments)
+		return &function
+
+	case *ArrayNode:
+		return &ArrayNode{Elements: this.simplifyAll(node.(*ArrayNode).Elements)}
+
+	case *UnaryNode:
+
+		unary := &UnaryNode{Operator: node.(*UnaryNode).Operator, Operand: this.simplify(node.(*UnaryNode).Operand)}
+
+		if unary.Operator == INVERT && !this.isReplaced(INVERT) {
+			return this.simplifyInversion(unary)
+		}
+		return unary
+
+	case *BinaryNode:
+
+		binary := node.(*BinaryNode)
+		return this.simplifyBinary(&BinaryNode{Operator: binary.Operator, Left: this.simplify(binary.Left), Right: this.simplify(binary.Right)})
+
+	case *TernaryNode:
+
+		ternary := node.(*TernaryNode)
+		return this.simplifyTernary(&TernaryNode{
+			Condition: this.simplify(ternary.Condition),
+			Then:      this.simplify(ternary.Then),
+			Else:      this.simplifyOptional(ternary.Else),
+		})
+	}
+
+	return node
+}
+
+/*
+	Returns the simplified form of each of the given [nodes], in a new slice.
+*/
+func (this *nodeSimplifier) simplifyAll(nodes []Node) []Node {
+
+	var ret []Node
+
+	if nodes == nil {
+		return nil
+	}
+
+	ret = make([]Node, len(nodes))
+	for i, node := range nodes {
+		ret[i] = this.simplify(node)
+	}
+	return ret
+}
+
+/*
+	The same as `simplify`, except that a nil [node] (such as the missing else of a ternary) stays nil.
+*/
+func (this *nodeSimplifier) simplifyOptional(node Node) Node {
+
+	if node == nil {
+		return nil
+	}
+	return this.simplify(node)
+}
+
+/*
+	Simplifies "!x", whose operand is already simplified.
+	Double negations cancel out, and negations are pushed inward with de Morgan's laws,
+	so that "!(a && b)" becomes "!a || !b" and "!(a == b)" becomes "a != b".
+*/
+func (this *nodeSimplifier) simplifyInversion(node *UnaryNode) Node {
+
+	switch node.Operand.(type) {
+
+	case *LiteralNode:
+
+		value, isBool := node.Operand.(*LiteralNode).Value.(bool)
+		if isBool {
+			return &LiteralNode{Value: !value}
+		}
+
+	case *UnaryNode:
+
+		if node.Operand.(*UnaryNode).Operator == INVERT {
+			return node.Operand.(*UnaryNode).Operand
+		}
+
+	case *BinaryNode:
+
+		operand := node.Operand.(*BinaryNode)
+
+		switch operand.Operator {
+		case AND:
+			return this.simplifyBinary(&BinaryNode{Operator: OR, Left: this.invert(operand.Left), Right: this.invert(operand.Right)})
+		case OR:
+			return this.simplifyBinary(&BinaryNode{Operator: AND, Left: this.invert(operand.Left), Right: this.invert(operand.Right)})
+		}
+
+		if this.isReplaced(EQ) || this.isReplaced(NEQ) {
+			break
+		}
+
+		switch operand.Operator {
+		case EQ:
+			return &BinaryNode{Operator: NEQ, Left: operand.Left, Right: operand.Right}
+		case NEQ:
+			return &BinaryNode{Operator: EQ, Left: operand.Left, Right: operand.Right}
+		}
+	}
+	return node
+}
+
+/*
+	Returns the simplified negation of the given (already simplified) [node].
+*/
+func (this *nodeSimplifier) invert(node Node) Node {
+	return this.simplifyInversion(&UnaryNode{Operator: INVERT, Operand: node})
+}
+
+/*
+	Simplifies an operator between two operands, which are already simplified.
+*/
+func (this *nodeSimplifier) simplifyBinary(node *BinaryNode) Node {
+
+	if this.isReplaced(node.Operator) {
+		return node
+	}
+
+	switch node.Operator {
+
+	case AND:
+		return this.simplifyLogical(node, true)
+
+	case OR:
+		return this.simplifyLogical(node, false)
+
+	case MULTIPLY:
+
+		if this.isIdentityLiteral(node.Right, 1) {
+			return node.Left
+		}
+		if this.isIdentityLiteral(node.Left, 1) {
+			return node.Right
+		}
+
+	// strings can be added to numbers, so adding zero is only an identity for numbers.
+	case PLUS:
+
+		if this.isIdentityLiteral(node.Right, 0) && this.isNumberNode(node.Left) {
+			return node.Left
+		}
+		if this.isIdentityLiteral(node.Left, 0) && this.isNumberNode(node.Right) {
+			return node.Right
+		}
+
+	case MINUS:
+
+		if this.isIdentityLiteral(node.Right, 0) {
+			return node.Left
+		}
+
+	case COALESCE:
+
+		if this.isNeverNil(node.Left) {
+			return node.Left
+		}
+	}
+	return node
+}
+
+/*
+	Simplifies a chain of "&&" (if [isAnd]) or "||" operators under the given [node].
+	Terms which can't change the result are removed, as are terms which already appear earlier in the chain.
+	If any term decides the result by itself, the whole chain becomes that result.
+*/
+func (this *nodeSimplifier) simplifyLogical(node *BinaryNode, isAnd bool) Node {
+
+	var terms, kept []Node
+	var ret Node
+
+	terms = flattenLogical(node, node.Operator)
+
+	for _, term := range terms {
+
+		literal, isLiteral := term.(*LiteralNode)
+		if isLiteral && literal.Value == isAnd {
+			continue
+		}
+		if isLiteral && literal.Value == !isAnd {
+			return &LiteralNode{Value: !isAnd}
+		}
+
+		if containsDuplicate(kept, term) {
+			continue
+		}
+		kept = append(kept, term)
+	}
+
+	if len(kept) == 0 {
+		return &LiteralNode{Value: isAnd}
+	}
+
+	ret = kept[0]
+	for _, term := range kept[1:] {
+		ret = &BinaryNode{Operator: node.Operator, Left: ret, Right: term}
+	}
+	return ret
+}
+
+/*
+	Simplifies a ternary whose parts are already simplified. A ternary whose condition is known chooses one side ahead of time.
+*/
+func (this *nodeSimplifier) simplifyTernary(node *TernaryNode) Node {
+
+	var literal *LiteralNode
+	var isLiteral bool
+
+	literal, isLiteral = node.Condition.(*LiteralNode)
+	if !isLiteral {
+		return node
+	}
+
+	switch literal.Value {
+
+	case true:
+
+		if node.Else == nil {
+			return node.Then
+		}
+
+		// a true condition gives [Then], unless [Then] is nil, in which case [Else] is still given.
+		return this.simplifyBinary(&BinaryNode{Operator: COALESCE, Left: node.Then, Right: node.Else})
+
+	case false:
+
+		if node.Else != nil {
+			return node.Else
+		}
+	}
+	return node
+}
+
+/*
+	Returns true if the operator with the given [symbol] has been replaced with `WithOperator`,
+	in which case none of its usual identities can be relied upon.
+*/
+func (this *nodeSimplifier) isReplaced(symbol OperatorSymbol) bool {
+
+	_, found := this.options.operators[symbol]
+	return found
+}
+
+/*
+	Returns true if the given [node] gives a number whenever it gives a result.
+*/
+func (this *nodeSimplifier) isNumberNode(node Node) bool {
+
+	switch node.(type) {
+
+	case *LiteralNode:
+		return isDecimalNumber(node.(*LiteralNode).Value)
+
+	case *UnaryNode:
+
+		switch node.(*UnaryNode).Operator {
+		case NEGATE:
+			fallthrough
+		case BITWISE_NOT:
+			return !this.isReplaced(node.(*UnaryNode).Operator)
+		}
+
+	case *BinaryNode:
+
+		binary := node.(*BinaryNode)
+		if this.isReplaced(binary.Operator) {
+			return false
+		}
+
+		switch binary.Operator {
+		case PLUS:
+			return this.isNumberNode(binary.Left) && this.isNumberNode(binary.Right)
+		case MINUS:
+			fallthrough
+		case MULTIPLY:
+			fallthrough
+		case DIVIDE:
+			fallthrough
+		case MODULUS:
+			fallthrough
+		case EXPONENT:
+			fallthrough
+		case BITWISE_AND:
+			fallthrough
+		case BITWISE_OR:
+			fallthrough
+		case BITWISE_XOR:
+			fallthrough
+		case BITWISE_LSHIFT:
+			fallthrough
+		case BITWISE_RSHIFT:
+			return true
+		}
+	}
+	return false
+}
+
+/*
+	Returns true if the given [node] never gives nil as its result.
+*/
+func (this *nodeSimplifier) isNeverNil(node Node) bool {
+
+	switch node.(type) {
+
+	case *LiteralNode:
+		return node.(*LiteralNode).Value != nil
+
+	case *UnaryNode:
+		return !this.isReplaced(node.(*UnaryNode).Operator)
+
+	case *BinaryNode:
+
+		binary := node.(*BinaryNode)
+		if this.isReplaced(binary.Operator) {
+			return false
+		}
+
+		switch binary.Operator {
+		case COALESCE:
+			return this.isNeverNil(binary.Left) || this.isNeverNil(binary.Right)
+		case TERNARY_TRUE:
+			fallthrough
+		case TERNARY_FALSE:
+			return false
+		}
+		return true
+	}
+	return false
+}
+
+/*
+	Returns every operand of the chain of [symbol] operators under [node], from left to right.
+*/
+func flattenLogical(node Node, symbol OperatorSymbol) []Node {
+
+	binary, isBinary := node.(*BinaryNode)
+	if !isBinary || binary.Operator != symbol {
+		return []Node{node}
+	}
+
+	return append(flattenLogical(binary.Left, symbol), flattenLogical(binary.Right, symbol)...)
+}
+
+/*
+	Returns true if the given [node] is the same as one of [nodes], and evaluating it again can't give a different result.
+*/
+func containsDuplicate(nodes []Node, node Node) bool {
+
+	if containsCall(node) {
+		return false
+	}
+
+	for _, existing := range nodes {
+		if reflect.DeepEqual(existing, node) {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+	Returns true if the tree under the given [node] calls any function or method, which might not give the same result each time.
+*/
+func containsCall(node Node) bool {
+
+	switch node.(type) {
+	case *FunctionNode:
+		return true
+	case *AccessorNode:
+		if node.(*AccessorNode).IsMethodCall {
+			return true
+		}
+	}
+
+	for _, child := range node.Children() {
+		if child != nil && containsCall(child) {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+	Returns true if the given [node] is a literal number equal to [number], which can be used as an identity.
+	The literal must have the native type of the numeric mode (such as int64 for `INTEGER_NUMERICS`);
+	otherwise the operator may change the type of the other operand (as with "x * 1.0" for an integer "x"), so is kept.
+*/
+func (this *nodeSimplifier) isIdentityLiteral(node Node, number int64) bool {
+
+	var native bool
+
+	literal, isLiteral := node.(*LiteralNode)
+	if !isLiteral {
+		return false
+	}
+
+	switch literal.Value.(type) {
+	case float64:
+		native = this.options.numericMode == FLOAT_NUMERICS
+	case int64:
+		native = this.options.numericMode == INTEGER_NUMERICS
+	case *big.Rat:
+		native = this.options.numericMode == DECIMAL_NUMERICS
+	}
+
+	return native && toRat(literal.Value).Cmp(big.NewRat(number, 1)) == 0
+}
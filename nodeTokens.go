@@ -0,0 +1,325 @@
+package govaluate
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+/*
+	Creates the tokens which, when parsed, produce the tree under the given [root].
+	Numeric literals are represented according to the given [mode].
+	Parentheses are only added where the structure of the tree would otherwise be lost to operator precedence.
+*/
+func nodeTokens(root Node, mode NumericMode) ([]ExpressionToken, error) {
+
+	var ret []ExpressionToken
+	var err error
+
+	ret, err = appendNodeTokens(ret, root, mode)
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+func appendNodeTokens(tokens []ExpressionToken, node Node, mode NumericMode) ([]ExpressionToken, error) {
+
+	var token ExpressionToken
+	var err error
+
+	switch node.(type) {
+
+	case nil:
+		return tokens, errors.New("Missing operand")
+
+	case *LiteralNode:
+
+		token, err = literalToken(node.(*LiteralNode).Value, mode)
+		if err != nil {
+			return tokens, err
+		}
+		return append(tokens, token), nil
+
+	case *VariableNode:
+		return append(tokens, ExpressionToken{Kind: VARIABLE, Value: node.(*VariableNode).Name}), nil
+
+	case *AccessorNode:
+
+		accessor := node.(*AccessorNode)
+		if len(accessor.Path) < 2 {
+			return tokens, errors.New(fmt.Sprintf("Accessor '%v' must have a parameter and at least one field", accessor.Path))
+		}
+
+		tokens = append(tokens, ExpressionToken{Kind: ACCESSOR, Value: append([]string{}, accessor.Path...)})
+		if !accessor.IsMethodCall {
+			return tokens, nil
+		}
+		return appendArgumentTokens(tokens, accessor.Arguments, mode)
+
+	case *FunctionNode:
+
+		function := node.(*FunctionNode)
+		token = ExpressionToken{Kind: FUNCTION, Text: function.Name}
+
+		if function.ContextFunction != nil {
+			token.Value = function.ContextFunction
+		} else if function.Function != nil {
+			token.Value = function.Function
+		} else {
+			return tokens, errors.New(fmt.Sprintf("Function '%s' has no implementation", function.Name))
+		}
+
+		tokens = append(tokens, token)
+		return appendArgumentTokens(tokens, function.Arguments, mode)
+
+	case *ArrayNode:
+
+		array := node.(*ArrayNode)
+		if len(array.Elements) < 2 {
+			return tokens, errors.New("Arrays must have at least two elements")
+		}
+		return appendArgumentTokens(tokens, array.Elements, mode)
+
+	case *UnaryNode:
+
+		unary := node.(*UnaryNode)
+		text, found := findSymbolText(prefixSymbols, unary.Operator)
+		if !found {
+			return tokens, errors.New(fmt.Sprintf("'%v' is not a prefix operator", unary.Operator))
+		}
+
+		tokens = append(tokens, ExpressionToken{Kind: PREFIX, Value: text})
+		return appendOperandTokens(tokens, unary.Operand, needsPrefixParentheses(unary.Operand), mode)
+
+	case *BinaryNode:
+
+		binary := node.(*BinaryNode)
+		token, err = operatorToken(binary.Operator)
+		if err != nil {
+			return tokens, err
+		}
+
+		precedence := findPlannedPrecedence(binary.Operator)
+
+		tokens, err = appendOperandTokens(tokens, binary.Left, findNodePrecedence(binary.Left) > precedence, mode)
+		if err != nil {
+			return tokens, err
+		}
+
+		tokens = append(tokens, token)
+		return appendOperandTokens(tokens, binary.Right, findNodePrecedence(binary.Right) >= precedence, mode)
+
+	case *TernaryNode:
+
+		ternary := node.(*TernaryNode)
+		precedence := findPlannedPrecedence(TERNARY_TRUE)
+
+		tokens, err = appendOperandTokens(tokens, ternary.Condition, findNodePrecedence(ternary.Condition) > precedence, mode)
+		if err != nil {
+			return tokens, err
+		}
+
+		tokens = append(tokens, ExpressionToken{Kind: TERNARY, Value: "?"})
+		tokens, err = appendOperandTokens(tokens, ternary.Then, findNodePrecedence(ternary.Then) >= precedence, mode)
+		if err != nil || ternary.Else == nil {
+			return tokens, err
+		}
+
+		tokens = append(tokens, ExpressionToken{Kind: TERNARY, Value: ":"})
+		return appendOperandTokens(tokens, ternary.Else, findNodePrecedence(ternary.Else) >= precedence, mode)
+	}
+
+	return tokens, errors.New(fmt.Sprintf("Unknown node type %T", node))
+}
+
+/*
+	Appends the tokens for [node], wrapping them in parentheses if [parenthesize] is true.
+*/
+func appendOperandTokens(tokens []ExpressionToken, node Node, parenthesize bool, mode NumericMode) ([]ExpressionToken, error) {
+
+	var err error
+
+	if !parenthesize {
+		return appendNodeTokens(tokens, node, mode)
+	}
+
+	tokens = append(tokens, ExpressionToken{Kind: CLAUSE, Value: '('})
+	tokens, err = appendNodeTokens(tokens, node, mode)
+	if err != nil {
+		return tokens, err
+	}
+	return append(tokens, ExpressionToken{Kind: CLAUSE_CLOSE, Value: ')'}), nil
+}
+
+/*
+	Appends a parenthesized, separated list of the given [nodes], as used for arguments and arrays.
+*/
+func appendArgumentTokens(tokens []ExpressionToken, nodes []Node, mode NumericMode) ([]ExpressionToken, error) {
+
+	var err error
+
+	tokens = append(tokens, ExpressionToken{Kind: CLAUSE, Value: '('})
+
+	for i, node := range nodes {
+
+		if i > 0 {
+			tokens = append(tokens, ExpressionToken{Kind: SEPARATOR, Value: ","})
+		}
+
+		tokens, err = appendNodeTokens(tokens, node, mode)
+		if err != nil {
+			return tokens, err
+		}
+	}
+
+	return append(tokens, ExpressionToken{Kind: CLAUSE_CLOSE, Value: ')'}), nil
+}
+
+/*
+	Returns how tightly the operator at the top of the given [node] binds its operands, as used to decide whether it needs parentheses.
+	See `findPlannedPrecedence`.
+*/
+func findNodePrecedence(node Node) int {
+
+	switch node.(type) {
+	case *UnaryNode:
+		return findPlannedPrecedence(node.(*UnaryNode).Operator)
+	case *BinaryNode:
+		return findPlannedPrecedence(node.(*BinaryNode).Operator)
+	case *TernaryNode:
+		return findPlannedPrecedence(TERNARY_TRUE)
+	}
+	return -1
+}
+
+/*
+	Returns how tightly the operator with the given [symbol] binds its operands, lower numbers binding tighter.
+	This follows the order in which `planStages` plans each kind of operator, which is not the order of the operatorPrecedence constants.
+*/
+func findPlannedPrecedence(symbol OperatorSymbol) int {
+
+	switch findOperatorPrecedenceForSymbol(symbol) {
+	case prefixPrecedence:
+		return 0
+	case exponentialPrecedence:
+		return 1
+	case multiplicativePrecedence:
+		return 2
+	case additivePrecedence:
+		return 3
+	case bitwiseShiftPrecedence:
+		return 4
+	case bitwisePrecedence:
+		return 5
+	case comparatorPrecedence:
+		return 6
+	case logicalAndPrecedence:
+		return 7
+	case logicalOrPrecedence:
+		return 8
+	case ternaryPrecedence:
+		return 9
+	case separatePrecedence:
+		return 10
+	}
+	return -1
+}
+
+/*
+	Returns true if the given [operand] of a prefix operator must be parenthesized.
+	Besides operators, prefixes can't be directly followed by strings, times, patterns, negative numbers, or other prefixes.
+*/
+func needsPrefixParentheses(operand Node) bool {
+
+	switch operand.(type) {
+	case *VariableNode:
+		return false
+	case *AccessorNode:
+		return false
+	case *FunctionNode:
+		return false
+	case *ArrayNode:
+		return false
+	case *LiteralNode:
+
+		value := operand.(*LiteralNode).Value
+		if isBool(value) {
+			return false
+		}
+
+		// negative numbers are written with a minus sign, which would be parsed as another prefix.
+		rat := toRat(value)
+		return rat == nil || rat.Sign() < 0
+	}
+	return true
+}
+
+/*
+	Returns the token for the given binary operator [symbol].
+*/
+func operatorToken(symbol OperatorSymbol) (ExpressionToken, error) {
+
+	var text string
+	var found bool
+
+	text, found = findSymbolText(comparatorSymbols, symbol)
+	if found {
+		return ExpressionToken{Kind: COMPARATOR, Value: text}, nil
+	}
+
+	text, found = findSymbolText(logicalSymbols, symbol)
+	if found {
+		return ExpressionToken{Kind: LOGICALOP, Value: text}, nil
+	}
+
+	text, found = findSymbolText(modifierSymbols, symbol)
+	if found {
+		return ExpressionToken{Kind: MODIFIER, Value: text}, nil
+	}
+
+	text, found = findSymbolText(ternarySymbols, symbol)
+	if found {
+		return ExpressionToken{Kind: TERNARY, Value: text}, nil
+	}
+
+	return ExpressionToken{}, errors.New(fmt.Sprintf("'%v' is not a binary operator", symbol))
+}
+
+/*
+	Returns the text which the given [symbol] is parsed from, according to the given map of [symbols].
+*/
+func findSymbolText(symbols map[string]OperatorSymbol, symbol OperatorSymbol) (string, bool) {
+
+	for text, candidate := range symbols {
+		if candidate == symbol {
+			return text, true
+		}
+	}
+	return "", false
+}
+
+/*
+	Returns the token for the given literal [value], with numbers represented according to the given [mode].
+*/
+func literalToken(value interface{}, mode NumericMode) (ExpressionToken, error) {
+
+	switch value.(type) {
+	case string:
+		return ExpressionToken{Kind: STRING, Value: value}, nil
+	case bool:
+		return ExpressionToken{Kind: BOOLEAN, Value: value}, nil
+	case time.Time:
+		return ExpressionToken{Kind: TIME, Value: value}, nil
+	case *regexp.Regexp:
+		return ExpressionToken{Kind: PATTERN, Value: value}, nil
+	}
+
+	value = sanitizedParameters{numericMode: mode}.sanitize(value)
+	if isNumber(value) || (mode == DECIMAL_NUMERICS && isDecimalNumber(value)) {
+		return ExpressionToken{Kind: NUMERIC, Value: value}, nil
+	}
+
+	return ExpressionToken{}, errors.New(fmt.Sprintf("Unable to represent literal of type %T", value))
+}
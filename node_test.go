@@ -0,0 +1,266 @@
+package govaluate
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+/*
+	Represents a test of the syntax tree built for the given [Input].
+*/
+type NodeTest struct {
+	Name     string
+	Input    string
+	Expected Node
+}
+
+func TestAST(test *testing.T) {
+
+	nodeTests := []NodeTest{
+
+		NodeTest{
+
+			Name:     "Literal",
+			Input:    "'foo'",
+			Expected: &LiteralNode{Value: "foo"},
+		},
+		NodeTest{
+
+			Name:     "Time literal",
+			Input:    "'2014-01-02'",
+			Expected: &LiteralNode{Value: time.Date(2014, 1, 2, 0, 0, 0, 0, time.Local)},
+		},
+		NodeTest{
+
+			Name:     "Elided literals",
+			Input:    "1 + 2 * 3",
+			Expected: &LiteralNode{Value: 7.0},
+		},
+		NodeTest{
+
+			Name:     "Variable",
+			Input:    "[foo bar]",
+			Expected: &VariableNode{Name: "foo bar"},
+		},
+		NodeTest{
+
+			Name:  "Accessor field",
+			Input: "foo.Bar",
+			Expected: &AccessorNode{
+				Path:      []string{"foo", "Bar"},
+				Arguments: []Node{},
+			},
+		},
+		NodeTest{
+
+			Name:  "Accessor method",
+			Input: "foo.Bar(1, baz)",
+			Expected: &AccessorNode{
+				Path:         []string{"foo", "Bar"},
+				Arguments:    []Node{&LiteralNode{Value: 1.0}, &VariableNode{Name: "baz"}},
+				IsMethodCall: true,
+			},
+		},
+		NodeTest{
+
+			Name:  "Unary",
+			Input: "!(foo && bar)",
+			Expected: &UnaryNode{
+				Operator: INVERT,
+				Operand: &BinaryNode{
+					Operator: AND,
+					Left:     &VariableNode{Name: "foo"},
+					Right:    &VariableNode{Name: "bar"},
+				},
+			},
+		},
+		NodeTest{
+
+			Name:  "Binary operators are left associative",
+			Input: "foo - bar - 1",
+			Expected: &BinaryNode{
+				Operator: MINUS,
+				Left: &BinaryNode{
+					Operator: MINUS,
+					Left:     &VariableNode{Name: "foo"},
+					Right:    &VariableNode{Name: "bar"},
+				},
+				Right: &LiteralNode{Value: 1.0},
+			},
+		},
+		NodeTest{
+
+			Name:  "Binary precedence",
+			Input: "foo + bar * 2",
+			Expected: &BinaryNode{
+				Operator: PLUS,
+				Left:     &VariableNode{Name: "foo"},
+				Right: &BinaryNode{
+					Operator: MULTIPLY,
+					Left:     &VariableNode{Name: "bar"},
+					Right:    &LiteralNode{Value: 2.0},
+				},
+			},
+		},
+		NodeTest{
+
+			Name:  "Ternary",
+			Input: "foo > 1 ? 'big' : 'small'",
+			Expected: &TernaryNode{
+				Condition: &BinaryNode{
+					Operator: GT,
+					Left:     &VariableNode{Name: "foo"},
+					Right:    &LiteralNode{Value: 1.0},
+				},
+				Then: &LiteralNode{Value: "big"},
+				Else: &LiteralNode{Value: "small"},
+			},
+		},
+		NodeTest{
+
+			Name:  "Ternary without else",
+			Input: "foo ? 'yes'",
+			Expected: &TernaryNode{
+				Condition: &VariableNode{Name: "foo"},
+				Then:      &LiteralNode{Value: "yes"},
+			},
+		},
+		NodeTest{
+
+			Name:  "Coalesce",
+			Input: "foo ?? 'default'",
+			Expected: &BinaryNode{
+				Operator: COALESCE,
+				Left:     &VariableNode{Name: "foo"},
+				Right:    &LiteralNode{Value: "default"},
+			},
+		},
+		NodeTest{
+
+			Name:  "Array",
+			Input: "foo in (1, bar, 'baz')",
+			Expected: &BinaryNode{
+				Operator: IN,
+				Left:     &VariableNode{Name: "foo"},
+				Right: &ArrayNode{
+					Elements: []Node{
+						&LiteralNode{Value: 1.0},
+						&VariableNode{Name: "bar"},
+						&LiteralNode{Value: "baz"},
+					},
+				},
+			},
+		},
+	}
+
+	fmt.Printf("Running %d AST test cases...\n", len(nodeTests))
+
+	for _, nodeTest := range nodeTests {
+
+		expression, err := NewEvaluableExpression(nodeTest.Input)
+		if err != nil {
+
+			test.Logf("Test '%s' failed to parse: '%s'", nodeTest.Name, err)
+			test.Fail()
+			continue
+		}
+
+		node := expression.AST()
+		if !reflect.DeepEqual(node, nodeTest.Expected) {
+
+			test.Logf("Test '%s' failed", nodeTest.Name)
+			test.Logf("Tree '%#v' does not match expected: '%#v'", node, nodeTest.Expected)
+			test.Fail()
+		}
+	}
+}
+
+func TestASTFunctions(test *testing.T) {
+
+	functions := map[string]ExpressionFunction{
+		"foo": func(arguments ...interface{}) (interface{}, error) {
+			return len(arguments), nil
+		},
+	}
+
+	expression, err := NewEvaluableExpressionWithFunctions("foo(1, bar)", functions)
+	if err != nil {
+		test.Logf("Failed to parse: %v", err)
+		test.Fail()
+		return
+	}
+
+	function, ok := expression.AST().(*FunctionNode)
+	if !ok {
+		test.Logf("Expected a function node, got '%#v'", expression.AST())
+		test.Fail()
+		return
+	}
+
+	if function.Name != "foo" || function.Function == nil || function.ContextFunction != nil {
+		test.Logf("Unexpected function node: '%#v'", function)
+		test.Fail()
+	}
+
+	expected := []Node{&LiteralNode{Value: 1.0}, &VariableNode{Name: "bar"}}
+	if !reflect.DeepEqual(function.Arguments, expected) {
+		test.Logf("Arguments '%#v' do not match expected: '%#v'", function.Arguments, expected)
+		test.Fail()
+	}
+
+	result, _ := function.Function()
+	if result != 0 {
+		test.Logf("Expected the function given to the expression, got one returning '%v'", result)
+		test.Fail()
+	}
+}
+
+func TestWalk(test *testing.T) {
+
+	var visited []string
+
+	expression, err := NewEvaluableExpression("a > 1 && (b.C(d) || !e) ? f : g")
+	if err != nil {
+		test.Logf("Failed to parse: %v", err)
+		test.Fail()
+		return
+	}
+
+	Inspect(expression.AST(), func(node Node) bool {
+
+		switch node.(type) {
+		case *VariableNode:
+			visited = append(visited, node.(*VariableNode).Name)
+		case *AccessorNode:
+			visited = append(visited, node.(*AccessorNode).Path[0])
+		}
+		return true
+	})
+
+	expected := []string{"a", "b", "d", "e", "f", "g"}
+	if !reflect.DeepEqual(visited, expected) {
+		test.Logf("Visited '%v', expected '%v'", visited, expected)
+		test.Fail()
+	}
+
+	// children of skipped nodes aren't visited.
+	visited = nil
+	Inspect(expression.AST(), func(node Node) bool {
+
+		switch node.(type) {
+		case *VariableNode:
+			visited = append(visited, node.(*VariableNode).Name)
+		case *BinaryNode:
+			return node.(*BinaryNode).Operator != OR
+		}
+		return true
+	})
+
+	expected = []string{"a", "f", "g"}
+	if !reflect.DeepEqual(visited, expected) {
+		test.Logf("Visited '%v', expected '%v'", visited, expected)
+		test.Fail()
+	}
+}
@@ -0,0 +1,154 @@
+package govaluate
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+/*
+	Represents a test of parsing an expression under ParseLimits.
+	If [Expected] is empty, the expression is expected to parse. Otherwise, parsing must fail with an error containing [Expected].
+*/
+type ParseLimitTest struct {
+	Name     string
+	Input    string
+	Limits   ParseLimits
+	Expected string
+}
+
+func TestParseLimits(test *testing.T) {
+
+	limitTests := []ParseLimitTest{
+
+		ParseLimitTest{
+
+			Name:   "No limits",
+			Input:  strings.Repeat("(", 200) + "1" + strings.Repeat(")", 200),
+			Limits: ParseLimits{},
+		},
+		ParseLimitTest{
+
+			Name:   "Within length",
+			Input:  "1 + 1",
+			Limits: ParseLimits{MaxLength: 5},
+		},
+		ParseLimitTest{
+
+			Name:     "Exceeds length",
+			Input:    "1 + 10",
+			Limits:   ParseLimits{MaxLength: 5},
+			Expected: "exceeds the maximum of 5",
+		},
+		ParseLimitTest{
+
+			Name:   "Within token count",
+			Input:  "1 + 1",
+			Limits: ParseLimits{MaxTokens: 3},
+		},
+		ParseLimitTest{
+
+			Name:     "Exceeds token count",
+			Input:    "1 + 1 + 1",
+			Limits:   ParseLimits{MaxTokens: 3},
+			Expected: "Expression has 5 tokens",
+		},
+		ParseLimitTest{
+
+			Name:   "Within nesting depth",
+			Input:  "((1)) + ((1))",
+			Limits: ParseLimits{MaxNestingDepth: 2},
+		},
+		ParseLimitTest{
+
+			Name:     "Exceeds nesting depth",
+			Input:    "((1)) + (((1)))",
+			Limits:   ParseLimits{MaxNestingDepth: 2},
+			Expected: "nested more than 2 deep",
+		},
+		ParseLimitTest{
+
+			Name:     "Function calls count towards nesting",
+			Input:    "(foo(1))",
+			Limits:   ParseLimits{MaxNestingDepth: 1},
+			Expected: "nested more than 1 deep",
+		},
+		ParseLimitTest{
+
+			Name:     "Pathological nesting",
+			Input:    strings.Repeat("(", 100000) + "1" + strings.Repeat(")", 100000),
+			Limits:   ParseLimits{MaxNestingDepth: 100},
+			Expected: "nested more than 100 deep",
+		},
+		ParseLimitTest{
+
+			Name:   "Within stage depth",
+			Input:  "a + b",
+			Limits: ParseLimits{MaxStageDepth: 2},
+		},
+		ParseLimitTest{
+
+			Name:     "Exceeds stage depth",
+			Input:    "a + b + c",
+			Limits:   ParseLimits{MaxStageDepth: 2},
+			Expected: "nested more than 2 stages deep",
+		},
+		ParseLimitTest{
+
+			Name:   "Elided literals do not count",
+			Input:  "a + (1 + 2)",
+			Limits: ParseLimits{MaxStageDepth: 3},
+		},
+		ParseLimitTest{
+
+			Name:     "Literals count while planning",
+			Input:    "a + (1 + 2 + 3)",
+			Limits:   ParseLimits{MaxStageDepth: 3},
+			Expected: "nested more than 3 stages deep",
+		},
+		ParseLimitTest{
+
+			Name:     "Pathological stage depth",
+			Input:    strings.Repeat("x + ", 100000) + "x",
+			Limits:   ParseLimits{MaxStageDepth: 50},
+			Expected: "nested more than 50 stages deep",
+		},
+		ParseLimitTest{
+
+			Name:     "Pathological negations",
+			Input:    strings.Repeat("-(", 100000) + "x" + strings.Repeat(")", 100000),
+			Limits:   ParseLimits{MaxStageDepth: 50},
+			Expected: "nested more than 50 stages deep",
+		},
+	}
+
+	functions := map[string]ExpressionFunction{
+		"foo": func(arguments ...interface{}) (interface{}, error) {
+			return nil, nil
+		},
+	}
+
+	fmt.Printf("Running %d parse limit test cases...\n", len(limitTests))
+
+	for _, limitTest := range limitTests {
+
+		_, err := NewEvaluableExpressionWithLimits(limitTest.Input, functions, limitTest.Limits)
+
+		if limitTest.Expected == "" {
+
+			if err != nil {
+				test.Logf("Test '%s' failed", limitTest.Name)
+				test.Logf("Encountered error: %s", err.Error())
+				test.Fail()
+			}
+			continue
+		}
+
+		if err == nil || !strings.Contains(err.Error(), limitTest.Expected) {
+
+			test.Logf("Test '%s' failed", limitTest.Name)
+			test.Logf("Got error: '%v', expected '%s'", err, limitTest.Expected)
+			test.Fail()
+		}
+	}
+}
@@ -3,7 +3,8 @@ package govaluate
 import (
 	"bytes"
 	"errors"
-	"fmt"
+	"math"
+	"math/big"
 	"regexp"
 	"strconv"
 	"strings"
@@ -11,12 +12,39 @@ import (
 	"unicode"
 )
 
-func parseTokens(expression string, functions map[string]ExpressionFunction) ([]ExpressionToken, error) {
+func parseTokens(expression string, options *compileOptions) ([]ExpressionToken, error) {
 
 	var ret []ExpressionToken
+	var errs ParseErrors
+	var err error
+
+	ret, errs = lexTokens(expression, options, false)
+	if len(errs) > 0 {
+		return ret, errs[0]
+	}
+
+	err = checkBalance(ret)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+/*
+	Reads every token from the given [expression].
+	If [recovering] is false, this stops at the first invalid token and returns only that error.
+	Otherwise, each invalid token is recorded as an UNKNOWN token, the lexer skips ahead to the next boundary
+	(whitespace, separator, or parenthesis), and every error found in the expression is returned.
+*/
+func lexTokens(expression string, options *compileOptions, recovering bool) ([]ExpressionToken, ParseErrors) {
+
+	var ret []ExpressionToken
+	var errs ParseErrors
 	var token ExpressionToken
 	var stream *lexerStream
 	var state lexerState
+	var start int
 	var err error
 	var found bool
 
@@ -25,10 +53,21 @@ func parseTokens(expression string, functions map[string]ExpressionFunction) ([]
 
 	for stream.canRead() {
 
-		token, err, found = readToken(stream, state, functions)
+		start = stream.position
+		token, err, found = readToken(stream, state, options)
 
 		if err != nil {
-			return ret, err
+
+			errs = append(errs, asParseError(err))
+			if !recovering {
+				return ret, errs
+			}
+
+			// keep a placeholder for the invalid token, so that later checks know not to report it again.
+			ret = append(ret, token)
+			skipToBoundary(stream, start)
+			state = validLexerStates[0]
+			continue
 		}
 
 		if !found {
@@ -37,30 +76,51 @@ func parseTokens(expression string, functions map[string]ExpressionFunction) ([]
 
 		state, err = getLexerStateForToken(token.Kind)
 		if err != nil {
-			return ret, err
+			return ret, append(errs, newTokenParseError(token, nil, "%s", err.Error()))
 		}
 
 		// append this valid token
 		ret = append(ret, token)
 	}
 
-	err = checkBalance(ret)
-	if err != nil {
-		return nil, err
+	return ret, errs
+}
+
+/*
+	Used when recovering from an invalid token which started at [start];
+	advances the [stream] past the rest of that token, up to the next whitespace, separator, or parenthesis.
+*/
+func skipToBoundary(stream *lexerStream, start int) {
+
+	var character rune
+
+	// always make progress, even if the invalid token didn't consume anything.
+	if stream.position <= start {
+		stream.position = start + 1
 	}
 
-	return ret, nil
+	for stream.canRead() {
+
+		character = stream.readCharacter()
+
+		if unicode.IsSpace(character) || character == ',' || character == '(' || character == ')' {
+			stream.rewind(1)
+			return
+		}
+	}
 }
 
-func readToken(stream *lexerStream, state lexerState, functions map[string]ExpressionFunction) (ExpressionToken, error, bool) {
+func readToken(stream *lexerStream, state lexerState, options *compileOptions) (ExpressionToken, error, bool) {
 
 	var function ExpressionFunction
+	var contextFunction ContextExpressionFunction
 	var ret ExpressionToken
 	var tokenValue interface{}
 	var tokenTime time.Time
 	var tokenString string
 	var kind TokenKind
 	var character rune
+	var start int
 	var found bool
 	var completed bool
 	var err error
@@ -79,6 +139,7 @@ func readToken(stream *lexerStream, state lexerState, functions map[string]Expre
 			continue
 		}
 
+		start = stream.position - 1
 		kind = UNKNOWN
 
 		// numeric constant
@@ -92,12 +153,12 @@ func readToken(stream *lexerStream, state lexerState, functions map[string]Expre
 					tokenValueInt, err := strconv.ParseUint(tokenString, 16, 64)
 
 					if err != nil {
-						errorMsg := fmt.Sprintf("Unable to parse hex value '%v' to uint64\n", tokenString)
-						return ExpressionToken{}, errors.New(errorMsg), false
+						ret = locateToken(ret, stream, start)
+						return ret, newTokenParseError(ret, nil, "Unable to parse hex value '%v' to uint64", tokenString), false
 					}
 
 					kind = NUMERIC
-					tokenValue = float64(tokenValueInt)
+					tokenValue = makeIntegerLiteral(tokenValueInt, options.numericMode)
 					break
 				} else {
 					stream.rewind(1)
@@ -105,11 +166,11 @@ func readToken(stream *lexerStream, state lexerState, functions map[string]Expre
 			}
 
 			tokenString = readTokenUntilFalse(stream, isNumeric)
-			tokenValue, err = strconv.ParseFloat(tokenString, 64)
+			tokenValue, err = parseNumericLiteral(tokenString, options.numericMode)
 
 			if err != nil {
-				errorMsg := fmt.Sprintf("Unable to parse numeric value '%v' to float64\n", tokenString)
-				return ExpressionToken{}, errors.New(errorMsg), false
+				ret = locateToken(ret, stream, start)
+				return ret, newTokenParseError(ret, nil, "Unable to parse numeric value '%v' to %s", tokenString, numericLiteralType(tokenString, options.numericMode)), false
 			}
 			kind = NUMERIC
 			break
@@ -130,7 +191,8 @@ func readToken(stream *lexerStream, state lexerState, functions map[string]Expre
 			kind = VARIABLE
 
 			if !completed {
-				return ExpressionToken{}, errors.New("Unclosed parameter bracket"), false
+				ret = locateToken(ret, stream, start)
+				return ret, newTokenParseError(ret, []TokenKind{VARIABLE}, "Unclosed parameter bracket"), false
 			}
 
 			// above method normally rewinds us to the closing bracket, which we want to skip.
@@ -169,20 +231,26 @@ func readToken(stream *lexerStream, state lexerState, functions map[string]Expre
 			}
 
 			// function?
-			function, found = functions[tokenString]
+			function, found = options.functions[tokenString]
 			if found {
 				kind = FUNCTION
 				tokenValue = function
 			}
 
+			contextFunction, found = options.contextFunctions[tokenString]
+			if found {
+				kind = FUNCTION
+				tokenValue = contextFunction
+			}
+
 			// accessor?
 			accessorIndex := strings.Index(tokenString, ".")
 			if accessorIndex > 0 {
 
 				// check that it doesn't end with a hanging period
 				if tokenString[len(tokenString)-1] == '.' {
-					errorMsg := fmt.Sprintf("Hanging accessor on token '%s'", tokenString)
-					return ExpressionToken{}, errors.New(errorMsg), false
+					ret = locateToken(ret, stream, start)
+					return ret, newTokenParseError(ret, nil, "Hanging accessor on token '%s'", tokenString), false
 				}
 
 				kind = ACCESSOR
@@ -195,8 +263,8 @@ func readToken(stream *lexerStream, state lexerState, functions map[string]Expre
 					firstCharacter := getFirstRune(splits[i])
 
 					if unicode.ToUpper(firstCharacter) != firstCharacter {
-						errorMsg := fmt.Sprintf("Unable to access unexported field '%s' in token '%s'", splits[i], tokenString)
-						return ExpressionToken{}, errors.New(errorMsg), false
+						ret = locateToken(ret, stream, start)
+						return ret, newTokenParseError(ret, nil, "Unable to access unexported field '%s' in token '%s'", splits[i], tokenString), false
 					}
 				}
 			}
@@ -207,14 +275,15 @@ func readToken(stream *lexerStream, state lexerState, functions map[string]Expre
 			tokenValue, completed = readUntilFalse(stream, true, false, true, isNotQuote)
 
 			if !completed {
-				return ExpressionToken{}, errors.New("Unclosed string literal"), false
+				ret = locateToken(ret, stream, start)
+				return ret, newTokenParseError(ret, []TokenKind{STRING}, "Unclosed string literal"), false
 			}
 
 			// advance the stream one position, since reading until false assumes the terminator is a real token
 			stream.rewind(-1)
 
 			// check to see if this can be parsed as a time.
-			tokenTime, found = tryParseTime(tokenValue.(string))
+			tokenTime, found = tryParseTime(tokenValue.(string), options.timeFormats)
 			if found {
 				kind = TIME
 				tokenValue = tokenTime
@@ -278,16 +347,110 @@ func readToken(stream *lexerStream, state lexerState, functions map[string]Expre
 			break
 		}
 
-		errorMessage := fmt.Sprintf("Invalid token: '%s'", tokenString)
-		return ret, errors.New(errorMessage), false
+		// a stray character (such as a closing bracket) may not have been read as part of any symbol.
+		if tokenString == "" {
+			stream.position = start + 1
+			tokenString = string(character)
+		}
+
+		ret = locateToken(ret, stream, start)
+		return ret, newTokenParseError(ret, state.validNextKinds, "Invalid token: '%s'", tokenString), false
 	}
 
 	ret.Kind = kind
 	ret.Value = tokenValue
 
+	if kind != UNKNOWN {
+		ret = locateToken(ret, stream, start)
+	}
+
 	return ret, nil, (kind != UNKNOWN)
 }
 
+/*
+	Fills in the source position and text of a [token] which was read from [stream], starting at the character index [start].
+	Any unescaped whitespace which was consumed after the token is not considered part of it.
+*/
+func locateToken(token ExpressionToken, stream *lexerStream, start int) ExpressionToken {
+
+	var end int
+
+	end = stream.position
+	for end > start+1 && unicode.IsSpace(stream.source[end-1]) && stream.source[end-2] != '\\' {
+		end--
+	}
+
+	token.Start = stream.positionOf(start)
+	token.End = stream.positionOf(end)
+	token.Text = string(stream.source[start:end])
+	return token
+}
+
+/*
+	Parses a decimal numeric literal into the representation used by the given numeric [mode].
+*/
+func parseNumericLiteral(candidate string, mode NumericMode) (interface{}, error) {
+
+	var unsigned uint64
+	var err error
+
+	switch numericLiteralType(candidate, mode) {
+	case "float64":
+		return strconv.ParseFloat(candidate, 64)
+	case "decimal":
+		return parseDecimalLiteral(candidate)
+	}
+
+	unsigned, err = strconv.ParseUint(candidate, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	return makeIntegerLiteral(unsigned, mode), nil
+}
+
+/*
+	Returns the name of the type that the given numeric literal [candidate] will be parsed as.
+*/
+func numericLiteralType(candidate string, mode NumericMode) string {
+
+	if mode == DECIMAL_NUMERICS {
+		return "decimal"
+	}
+	if mode == INTEGER_NUMERICS && !strings.Contains(candidate, ".") {
+		return "integer"
+	}
+	return "float64"
+}
+
+func parseDecimalLiteral(candidate string) (*big.Rat, error) {
+
+	ret, ok := new(big.Rat).SetString(candidate)
+	if !ok {
+		return nil, errors.New("Invalid decimal")
+	}
+	return ret, nil
+}
+
+/*
+	Converts an integer literal (such as a hex literal) into the representation used by the given numeric [mode].
+	For INTEGER_NUMERICS, this is an int64 unless the value is too large, in which case it's a uint64.
+	For DECIMAL_NUMERICS, this is always a *big.Rat.
+*/
+func makeIntegerLiteral(value uint64, mode NumericMode) interface{} {
+
+	if mode == DECIMAL_NUMERICS {
+		return new(big.Rat).SetUint64(value)
+	}
+	if mode != INTEGER_NUMERICS {
+		return float64(value)
+	}
+
+	if value <= math.MaxInt64 {
+		return int64(value)
+	}
+	return value
+}
+
 func readTokenUntilFalse(stream *lexerStream, condition func(rune) bool) string {
 
 	var ret string
@@ -350,15 +513,30 @@ func readUntilFalse(stream *lexerStream, includeWhitespace bool, breakWhitespace
 */
 func optimizeTokens(tokens []ExpressionToken) ([]ExpressionToken, error) {
 
+	var errs ParseErrors
+
+	tokens, errs = compileConstantPatterns(tokens, false)
+	if len(errs) > 0 {
+		return tokens, errs[0]
+	}
+	return tokens, nil
+}
+
+/*
+	If a regex operator's right-hand value is a constant, precompiles it and replaces it with a pattern.
+	If [recovering] is true, every pattern which fails to compile is reported, instead of only the first.
+*/
+func compileConstantPatterns(tokens []ExpressionToken, recovering bool) ([]ExpressionToken, ParseErrors) {
+
 	var token ExpressionToken
 	var symbol OperatorSymbol
+	var errs ParseErrors
 	var err error
 	var index int
 
 	for index, token = range tokens {
 
-		// if we find a regex operator, and the right-hand value is a constant, precompile and replace with a pattern.
-		if token.Kind != COMPARATOR {
+		if token.Kind != COMPARATOR || !isString(token.Value) {
 			continue
 		}
 
@@ -368,6 +546,10 @@ func optimizeTokens(tokens []ExpressionToken) ([]ExpressionToken, error) {
 		}
 
 		index++
+		if index >= len(tokens) {
+			break
+		}
+
 		token = tokens[index]
 		if token.Kind == STRING {
 
@@ -375,13 +557,18 @@ func optimizeTokens(tokens []ExpressionToken) ([]ExpressionToken, error) {
 			token.Value, err = regexp.Compile(token.Value.(string))
 
 			if err != nil {
-				return tokens, err
+
+				errs = append(errs, newTokenParseError(tokens[index], nil, "%s", err.Error()))
+				if !recovering {
+					return tokens, errs
+				}
+				continue
 			}
 
 			tokens[index] = token
 		}
 	}
-	return tokens, nil
+	return tokens, errs
 }
 
 /*
@@ -389,8 +576,24 @@ func optimizeTokens(tokens []ExpressionToken) ([]ExpressionToken, error) {
 */
 func checkBalance(tokens []ExpressionToken) error {
 
+	var errs ParseErrors
+
+	errs = findUnbalancedTokens(tokens)
+	if len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+/*
+	Returns an error for every parenthesis in [tokens] which is left unmatched, if the parenthesis are unbalanced.
+*/
+func findUnbalancedTokens(tokens []ExpressionToken) ParseErrors {
+
 	var stream *tokenStream
 	var token ExpressionToken
+	var opened, unopened []ExpressionToken
+	var errs ParseErrors
 	var parens int
 
 	stream = newTokenStream(tokens)
@@ -399,19 +602,34 @@ func checkBalance(tokens []ExpressionToken) error {
 
 		token = stream.next()
 		if token.Kind == CLAUSE {
+			opened = append(opened, token)
 			parens++
 			continue
 		}
 		if token.Kind == CLAUSE_CLOSE {
+
+			// keep track of which parens are unmatched, so that errors can point to them.
+			if len(opened) > 0 {
+				opened = opened[:len(opened)-1]
+			} else {
+				unopened = append(unopened, token)
+			}
 			parens--
 			continue
 		}
 	}
 
-	if parens != 0 {
-		return errors.New("Unbalanced parenthesis")
+	if parens > 0 {
+		for _, token = range opened {
+			errs = append(errs, newTokenParseError(token, []TokenKind{CLAUSE_CLOSE}, "Unbalanced parenthesis"))
+		}
 	}
-	return nil
+	if parens < 0 {
+		for _, token = range unopened {
+			errs = append(errs, newTokenParseError(token, nil, "Unbalanced parenthesis"))
+		}
+	}
+	return errs
 }
 
 func isDigit(character rune) bool {
@@ -465,33 +683,36 @@ func isNotClosingBracket(character rune) bool {
 	return character != ']'
 }
 
+/*
+	The formats which string literals are parsed as times with, unless `WithTimeFormats` says otherwise.
+*/
+var defaultTimeFormats = []string{
+	time.ANSIC,
+	time.UnixDate,
+	time.RubyDate,
+	time.Kitchen,
+	time.RFC3339,
+	time.RFC3339Nano,
+	"2006-01-02",                         // RFC 3339
+	"2006-01-02 15:04",                   // RFC 3339 with minutes
+	"2006-01-02 15:04:05",                // RFC 3339 with seconds
+	"2006-01-02 15:04:05-07:00",          // RFC 3339 with seconds and timezone
+	"2006-01-02T15Z0700",                 // ISO8601 with hour
+	"2006-01-02T15:04Z0700",              // ISO8601 with minutes
+	"2006-01-02T15:04:05Z0700",           // ISO8601 with seconds
+	"2006-01-02T15:04:05.999999999Z0700", // ISO8601 with nanoseconds
+}
+
 /*
 	Attempts to parse the [candidate] as a Time.
-	Tries a series of standardized date formats, returns the Time if one applies,
+	Tries each of the given [timeFormats], returns the Time if one applies,
 	otherwise returns false through the second return.
 */
-func tryParseTime(candidate string) (time.Time, bool) {
+func tryParseTime(candidate string, timeFormats []string) (time.Time, bool) {
 
 	var ret time.Time
 	var found bool
 
-	timeFormats := [...]string{
-		time.ANSIC,
-		time.UnixDate,
-		time.RubyDate,
-		time.Kitchen,
-		time.RFC3339,
-		time.RFC3339Nano,
-		"2006-01-02",                         // RFC 3339
-		"2006-01-02 15:04",                   // RFC 3339 with minutes
-		"2006-01-02 15:04:05",                // RFC 3339 with seconds
-		"2006-01-02 15:04:05-07:00",          // RFC 3339 with seconds and timezone
-		"2006-01-02T15Z0700",                 // ISO8601 with hour
-		"2006-01-02T15:04Z0700",              // ISO8601 with minutes
-		"2006-01-02T15:04:05Z0700",           // ISO8601 with seconds
-		"2006-01-02T15:04:05.999999999Z0700", // ISO8601 with nanoseconds
-	}
-
 	for _, format := range timeFormats {
 
 		ret, found = tryParseExactTime(candidate, format)
@@ -2,6 +2,7 @@ package govaluate
 
 import (
 	"fmt"
+	"reflect"
 	"regexp/syntax"
 	"strings"
 	"testing"
@@ -210,6 +211,164 @@ func TestParsingFailure(test *testing.T) {
 	runParsingFailureTests(parsingTests, test)
 }
 
+/*
+	Represents a test that a parsing failure is reported at the right place in the expression.
+*/
+type ParseErrorPositionTest struct {
+	Name     string
+	Input    string
+	Line     int
+	Column   int
+	Text     string
+	Expected []TokenKind
+}
+
+func TestParseErrorPositions(test *testing.T) {
+
+	comparatorState, _ := getLexerStateForToken(COMPARATOR)
+
+	parsingTests := []ParseErrorPositionTest{
+
+		ParseErrorPositionTest{
+
+			Name:   "Invalid token",
+			Input:  "1 === 1",
+			Line:   1,
+			Column: 3,
+			Text:   "===",
+		},
+		ParseErrorPositionTest{
+
+			Name:     "Invalid transition",
+			Input:    "foo > < 10",
+			Line:     1,
+			Column:   7,
+			Text:     "<",
+			Expected: comparatorState.validNextKinds,
+		},
+		ParseErrorPositionTest{
+
+			Name:   "Unbalanced parenthesis",
+			Input:  "10 > ((1 + 50)",
+			Line:   1,
+			Column: 6,
+			Text:   "(",
+		},
+		ParseErrorPositionTest{
+
+			Name:   "Unclosed quote on second line",
+			Input:  "foo == 1 &&\n  bar == 'responseTime",
+			Line:   2,
+			Column: 10,
+		},
+		ParseErrorPositionTest{
+
+			Name:   "Undefined function",
+			Input:  "1 + foobar()",
+			Line:   1,
+			Column: 5,
+			Text:   "foobar",
+		},
+		ParseErrorPositionTest{
+
+			Name:   "Unexpected end",
+			Input:  "10 > 5 +",
+			Line:   1,
+			Column: 9,
+		},
+		ParseErrorPositionTest{
+
+			Name:   "Unicode before invalid token",
+			Input:  "'héllo' ~= foo",
+			Line:   1,
+			Column: 9,
+			Text:   "~=",
+		},
+	}
+
+	for _, testCase := range parsingTests {
+
+		_, err := NewEvaluableExpression(testCase.Input)
+
+		parseError, ok := err.(*ParseError)
+		if !ok {
+
+			test.Logf("Test '%s' failed", testCase.Name)
+			test.Logf("Expected a *ParseError, got '%v'", err)
+			test.Fail()
+			continue
+		}
+
+		if parseError.Start.Line != testCase.Line || parseError.Start.Column != testCase.Column {
+
+			test.Logf("Test '%s' failed", testCase.Name)
+			test.Logf("Expected error at %d:%d, got %d:%d", testCase.Line, testCase.Column, parseError.Start.Line, parseError.Start.Column)
+			test.Fail()
+			continue
+		}
+
+		if testCase.Text != "" && (parseError.Token == nil || parseError.Token.Text != testCase.Text) {
+
+			test.Logf("Test '%s' failed", testCase.Name)
+			test.Logf("Expected offending token '%s', got '%v'", testCase.Text, parseError.Token)
+			test.Fail()
+			continue
+		}
+
+		if testCase.Expected != nil && !reflect.DeepEqual(parseError.Expected, testCase.Expected) {
+
+			test.Logf("Test '%s' failed", testCase.Name)
+			test.Logf("Expected kinds '%v', got '%v'", testCase.Expected, parseError.Expected)
+			test.Fail()
+		}
+	}
+}
+
+/*
+	Tests that diagnostics mode reports every problem in an expression, in order.
+*/
+func TestParsingDiagnostics(test *testing.T) {
+
+	expression, err := NewEvaluableExpressionWithDiagnostics("foo(1) || (x === 2 || y ~~ 3 && z =~ '[abc'", nil)
+	errs, _ := err.(ParseErrors)
+
+	expected := []ParseErrorPositionTest{
+		ParseErrorPositionTest{Input: UNDEFINED_FUNCTION, Column: 1},
+		ParseErrorPositionTest{Input: UNBALANCED_PARENTHESIS, Column: 11},
+		ParseErrorPositionTest{Input: INVALID_TOKEN_KIND, Column: 14},
+		ParseErrorPositionTest{Input: INVALID_TOKEN_KIND, Column: 25},
+		ParseErrorPositionTest{Input: string(syntax.ErrMissingBracket), Column: 38},
+	}
+
+	if expression != nil {
+		test.Logf("Expected no expression to be returned when errors are found")
+		test.Fail()
+	}
+
+	if len(errs) != len(expected) {
+
+		test.Logf("Expected %d errors, found %d: %v", len(expected), len(errs), errs)
+		test.Fail()
+		return
+	}
+
+	for i, err := range errs {
+
+		if !strings.Contains(err.Message, expected[i].Input) || err.Start.Column != expected[i].Column {
+
+			test.Logf("Error %d: expected '%s' at column %d, got '%s' at column %d", i, expected[i].Input, expected[i].Column, err.Message, err.Start.Column)
+			test.Fail()
+		}
+	}
+
+	expression, err = NewEvaluableExpressionWithDiagnostics("foo > 1 && bar", nil)
+	if err != nil || expression == nil {
+
+		test.Logf("Expected a valid expression to produce no diagnostics, got: %v", err)
+		test.Fail()
+	}
+}
+
 func runParsingFailureTests(parsingTests []ParsingFailureTest, test *testing.T) {
 
 	var err error
@@ -1516,6 +1516,56 @@ func TestOriginalVars(test *testing.T) {
 	}
 }
 
+/*
+	Tests that tokens record where in the original expression they were read from.
+*/
+func TestTokenPositions(test *testing.T) {
+
+	expressionString := "[foo bar] >= 10 &&\n\tbaz.Qux('héllo')"
+
+	expected := []ExpressionToken{
+		ExpressionToken{Text: "[foo bar]", Start: Position{0, 1, 1}, End: Position{9, 1, 10}},
+		ExpressionToken{Text: ">=", Start: Position{10, 1, 11}, End: Position{12, 1, 13}},
+		ExpressionToken{Text: "10", Start: Position{13, 1, 14}, End: Position{15, 1, 16}},
+		ExpressionToken{Text: "&&", Start: Position{16, 1, 17}, End: Position{18, 1, 19}},
+		ExpressionToken{Text: "baz.Qux", Start: Position{20, 2, 2}, End: Position{27, 2, 9}},
+		ExpressionToken{Text: "(", Start: Position{27, 2, 9}, End: Position{28, 2, 10}},
+		ExpressionToken{Text: "'héllo'", Start: Position{28, 2, 10}, End: Position{36, 2, 17}},
+		ExpressionToken{Text: ")", Start: Position{36, 2, 17}, End: Position{37, 2, 18}},
+	}
+
+	expression, err := NewEvaluableExpression(expressionString)
+	if err != nil {
+
+		test.Logf("failed to parse token position test: %v", err)
+		test.Fail()
+		return
+	}
+
+	tokens := expression.Tokens()
+	if len(tokens) != len(expected) {
+
+		test.Logf("Expected %d tokens, found %d", len(expected), len(tokens))
+		test.Fail()
+		return
+	}
+
+	for i, token := range tokens {
+
+		if token.Text != expected[i].Text || token.Start != expected[i].Start || token.End != expected[i].End {
+
+			test.Logf("Token %d: expected '%s' %v-%v, found '%s' %v-%v", i, expected[i].Text, expected[i].Start, expected[i].End, token.Text, token.Start, token.End)
+			test.Fail()
+		}
+
+		if expressionString[token.Start.Offset:token.End.Offset] != token.Text {
+
+			test.Logf("Token %d: offsets do not match text '%s'", i, token.Text)
+			test.Fail()
+		}
+	}
+}
+
 func combineWhitespaceExpressions(testCases []TokenParsingTest) []TokenParsingTest {
 
 	var currentCase, strippedCase TokenParsingTest
@@ -0,0 +1,261 @@
+package govaluate
+
+import (
+	"strings"
+	"testing"
+)
+
+/*
+	Represents a test of partially evaluating an expression.
+	If [Residual] is empty, the expression is expected to be evaluated entirely, giving [Expected].
+	Otherwise, it's expected to leave the given residual expression, which gives [Expected] when evaluated with [Remaining].
+*/
+type PartialTest struct {
+	Name      string
+	Input     string
+	Options   []Option
+	Known     MapParameters
+	Remaining MapParameters
+	Residual  string
+	Expected  interface{}
+}
+
+func TestPartialEval(test *testing.T) {
+
+	var calls int
+
+	functions := map[string]ExpressionFunction{
+		"count": func(arguments ...interface{}) (interface{}, error) {
+			calls++
+			return arguments[0], nil
+		},
+	}
+
+	known := MapParameters{
+		"region": "us",
+		"limit":  10,
+		"flag":   false,
+		"tags":   []interface{}{"a", "b"},
+		"foo":    dummyParameter{String: "string!"},
+	}
+
+	partialTests := []PartialTest{
+
+		PartialTest{
+
+			Name:     "Every parameter known",
+			Input:    "region == 'us' && limit > 5",
+			Known:    known,
+			Expected: true,
+		},
+		PartialTest{
+
+			Name:      "Known comparison",
+			Input:     "region == 'us' && amount > limit * 2",
+			Known:     known,
+			Remaining: MapParameters{"amount": 30},
+			Residual:  "true && amount > 20",
+			Expected:  true,
+		},
+		PartialTest{
+
+			Name:     "Known false conjunction",
+			Input:    "flag && amount > 1",
+			Known:    known,
+			Expected: false,
+		},
+		PartialTest{
+
+			Name:     "Known true disjunction",
+			Input:    "region in ('us', 'eu') || amount > 1",
+			Known:    known,
+			Expected: true,
+		},
+		PartialTest{
+
+			Name:      "Known ternary condition",
+			Input:     "region == 'us' ? amount : limit",
+			Known:     known,
+			Remaining: MapParameters{"amount": 3},
+			Residual:  "amount ?? 10",
+			Expected:  3.0,
+		},
+		PartialTest{
+
+			Name:      "Known false ternary condition",
+			Input:     "region == 'eu' ? limit : amount + 1",
+			Known:     known,
+			Remaining: MapParameters{"amount": 3},
+			Residual:  "amount + 1",
+			Expected:  4.0,
+		},
+		PartialTest{
+
+			Name:      "Known coalescing",
+			Input:     "(missing ?? limit) + amount",
+			Known:     known,
+			Remaining: MapParameters{"amount": 3, "missing": nil},
+			Residual:  "(missing ?? 10) + amount",
+			Expected:  13.0,
+		},
+		PartialTest{
+
+			Name:     "Known coalesced value",
+			Input:    "limit ?? amount",
+			Known:    known,
+			Expected: 10.0,
+		},
+		PartialTest{
+
+			Name:      "Function arguments",
+			Input:     "count(limit + 1) > amount",
+			Known:     known,
+			Remaining: MapParameters{"amount": 3},
+			Residual:  "count(11) > amount",
+			Expected:  true,
+		},
+		PartialTest{
+
+			Name:      "Accessors",
+			Input:     "foo.String + amount",
+			Known:     known,
+			Remaining: MapParameters{"amount": "!"},
+			Residual:  "'string!' + amount",
+			Expected:  "string!!",
+		},
+		PartialTest{
+
+			Name:      "Method calls",
+			Input:     "amount + foo.Func()",
+			Known:     known,
+			Remaining: MapParameters{"amount": "!", "foo": dummyParameter{}},
+			Residual:  "amount + foo.Func()",
+			Expected:  "!funk",
+		},
+		PartialTest{
+
+			Name:      "Unrepresentable values",
+			Input:     "amount in tags",
+			Known:     known,
+			Remaining: MapParameters{"amount": "b", "tags": []interface{}{"a", "b"}},
+			Residual:  "amount in tags",
+			Expected:  true,
+		},
+		PartialTest{
+
+			Name:      "Failing parts",
+			Input:     "amount > 1 && region > 1",
+			Known:     known,
+			Remaining: MapParameters{"amount": 0},
+			Residual:  "amount > 1 && 'us' > 1",
+			Expected:  false,
+		},
+		PartialTest{
+
+			Name:      "Infinite values",
+			Input:     "amount > -limit / 0",
+			Known:     known,
+			Remaining: MapParameters{"amount": 0},
+			Residual:  "amount > (-1 / 0)",
+			Expected:  true,
+		},
+		PartialTest{
+
+			Name:      "Nothing known",
+			Input:     "amount * 2",
+			Remaining: MapParameters{"amount": 2},
+			Residual:  "amount * 2",
+			Expected:  4.0,
+		},
+		PartialTest{
+
+			Name:      "Integer numerics",
+			Input:     "amount / limit",
+			Options:   []Option{WithNumericMode(INTEGER_NUMERICS)},
+			Known:     known,
+			Remaining: MapParameters{"amount": 25},
+			Residual:  "amount / 10",
+			Expected:  int64(2),
+		},
+	}
+
+	test.Logf("Running %d partial evaluation test cases", len(partialTests))
+
+	for _, partialTest := range partialTests {
+		for _, engine := range evaluationEngines {
+
+			options := append([]Option{WithFunctions(functions), WithEngine(engine)}, partialTest.Options...)
+
+			expression, err := Compile(partialTest.Input, options...)
+			if err != nil {
+				test.Logf("Test '%s' failed to parse: %s", partialTest.Name, err)
+				test.Fail()
+				break
+			}
+
+			residual, value, err := expression.PartialEval(partialTest.Known)
+			if err != nil {
+				test.Logf("Test '%s' with %v failed: %s", partialTest.Name, engine, err)
+				test.Fail()
+				continue
+			}
+
+			if partialTest.Residual == "" {
+
+				if residual != nil || value != partialTest.Expected {
+					test.Logf("Test '%s' with %v gave '%v' (%v), expected '%v'", partialTest.Name, engine, value, residual, partialTest.Expected)
+					test.Fail()
+				}
+				continue
+			}
+
+			if residual == nil || residual.String() != partialTest.Residual {
+				test.Logf("Test '%s' with %v left '%v' (%v), expected '%s'", partialTest.Name, engine, residual, value, partialTest.Residual)
+				test.Fail()
+				continue
+			}
+
+			value, err = residual.Eval(partialTest.Remaining)
+			if err != nil || value != partialTest.Expected {
+				test.Logf("Test '%s' with %v gave '%v' (%v), expected '%v'", partialTest.Name, engine, value, err, partialTest.Expected)
+				test.Fail()
+			}
+		}
+	}
+
+	// functions are never called ahead of time.
+	calls = 0
+	expression, _ := Compile("count(1) + limit", WithFunctions(functions))
+	expression.PartialEval(known)
+
+	if calls != 0 {
+		test.Logf("Expected no function calls, got %d", calls)
+		test.Fail()
+	}
+}
+
+func TestPartialEvalSettings(test *testing.T) {
+
+	expression, _ := Compile("name + suffix", WithEngine(BYTECODE_ENGINE), WithBudget(EvaluationBudget{MaxStringLength: 4}))
+
+	residual, _, _ := expression.PartialEval(MapParameters{"suffix": "!!"})
+	if residual.program == nil || residual.Budget != expression.Budget {
+		test.Logf("Expected the residual expression to keep the engine and budget of the original")
+		test.Fail()
+	}
+
+	_, err := residual.Evaluate(map[string]interface{}{"name": "foo"})
+	if _, exceeded := err.(ErrBudgetExceeded); !exceeded {
+		test.Logf("Expected the string length budget to be exceeded, got '%v'", err)
+		test.Fail()
+	}
+
+	// an expression which fails with every parameter known returns its error.
+	expression, _ = NewEvaluableExpression("name > 1")
+
+	_, _, err = expression.PartialEval(MapParameters{"name": "foo"})
+	if err == nil || !strings.Contains(err.Error(), INVALID_COMPARATOR_TYPES) {
+		test.Logf("Expected a type error, got '%v'", err)
+		test.Fail()
+	}
+}
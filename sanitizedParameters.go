@@ -1,9 +1,30 @@
 package govaluate
 
+import (
+	"context"
+	"math/big"
+	"strconv"
+)
+
 // sanitizedParameters is a wrapper for Parameters that does sanitization as
-// parameters are accessed.
+// parameters are accessed. It also carries the context of the evaluation
+// that it was created for.
 type sanitizedParameters struct {
-	orig Parameters
+	orig        Parameters
+	numericMode NumericMode
+	ctx         context.Context
+
+	// the context's Done channel, cached since it's checked before every
+	// stage. nil if the context can never be cancelled.
+	done <-chan struct{}
+
+	// the expression's budget, or nil if it has no limits.
+	budget *EvaluationBudget
+	usage  budgetUsage
+
+	// whether the expression's ChecksTypes was set when evaluation began.
+	// only used by compiled engines, which don't have the expression itself.
+	checksTypes bool
 }
 
 func (p sanitizedParameters) Get(key string) (interface{}, error) {
@@ -12,7 +33,54 @@ func (p sanitizedParameters) Get(key string) (interface{}, error) {
 		return nil, err
 	}
 
-	return castToFloat64(value), nil
+	return p.sanitize(value), nil
+}
+
+// sanitize converts a value retrieved from (or through) these parameters into
+// the representation used by the expression's numeric mode.
+func (p sanitizedParameters) sanitize(value interface{}) interface{} {
+	switch p.numericMode {
+	case INTEGER_NUMERICS:
+		return castToInteger(value)
+	case DECIMAL_NUMERICS:
+		return castToDecimal(value)
+	}
+	return castToFloat64(value)
+}
+
+// sanitizeParameterValue sanitizes a value which was derived from the given
+// parameters, such as a field accessed on one of them.
+func sanitizeParameterValue(parameters Parameters, value interface{}) interface{} {
+	sanitized, ok := parameters.(*sanitizedParameters)
+	if ok {
+		return sanitized.sanitize(value)
+	}
+	return castToFloat64(value)
+}
+
+// cancelled returns the context's error if the evaluation's context has been
+// cancelled, or nil otherwise.
+func (p sanitizedParameters) cancelled() error {
+	if p.done == nil {
+		return nil
+	}
+
+	select {
+	case <-p.done:
+		return p.ctx.Err()
+	default:
+		return nil
+	}
+}
+
+// contextOf returns the context that the given parameters are being
+// evaluated with, or context.Background() if there isn't one.
+func contextOf(parameters Parameters) context.Context {
+	sanitized, ok := parameters.(*sanitizedParameters)
+	if ok && sanitized.ctx != nil {
+		return sanitized.ctx
+	}
+	return context.Background()
 }
 
 func castToFloat64(value interface{}) interface{} {
@@ -41,3 +109,51 @@ func castToFloat64(value interface{}) interface{} {
 
 	return value
 }
+
+func castToInteger(value interface{}) interface{} {
+	switch value.(type) {
+	case uint8:
+		return uint64(value.(uint8))
+	case uint16:
+		return uint64(value.(uint16))
+	case uint32:
+		return uint64(value.(uint32))
+	case uint:
+		return uint64(value.(uint))
+	case int8:
+		return int64(value.(int8))
+	case int16:
+		return int64(value.(int16))
+	case int32:
+		return int64(value.(int32))
+	case int:
+		return int64(value.(int))
+	case float32:
+		return float64(value.(float32))
+	}
+
+	return value
+}
+
+// castToDecimal converts any kind of number into a *big.Rat. Floats which
+// cannot be represented (NaN and infinities) are left as they are.
+func castToDecimal(value interface{}) interface{} {
+	var ret *big.Rat
+
+	switch value.(type) {
+	case *big.Rat:
+		return value
+	case *big.Int:
+		return new(big.Rat).SetInt(value.(*big.Int))
+	case float32:
+		// formatted at float32 precision, so that float32(0.1) becomes exactly 1/10.
+		ret, _ = new(big.Rat).SetString(strconv.FormatFloat(float64(value.(float32)), 'g', -1, 32))
+	default:
+		ret = toRat(castToInteger(value))
+	}
+
+	if ret == nil {
+		return value
+	}
+	return ret
+}
@@ -0,0 +1,369 @@
+package govaluate
+
+import (
+	"reflect"
+	"testing"
+)
+
+/*
+	Represents a test of simplifying an expression, which is expected to format as [Expected] once simplified.
+*/
+type SimplificationTest struct {
+	Name     string
+	Input    string
+	Options  []Option
+	Expected string
+}
+
+func TestSimplification(test *testing.T) {
+
+	functions := map[string]ExpressionFunction{
+		"now": func(arguments ...interface{}) (interface{}, error) {
+			return true, nil
+		},
+	}
+
+	simplificationTests := []SimplificationTest{
+
+		SimplificationTest{
+
+			Name:     "Conjunction with true",
+			Input:    "x && true",
+			Expected: "x",
+		},
+		SimplificationTest{
+
+			Name:     "Conjunction with false",
+			Input:    "x && (false && y)",
+			Expected: "false",
+		},
+		SimplificationTest{
+
+			Name:     "Disjunction with true",
+			Input:    "x || true",
+			Expected: "true",
+		},
+		SimplificationTest{
+
+			Name:     "Disjunction with false",
+			Input:    "false || x",
+			Expected: "x",
+		},
+		SimplificationTest{
+
+			Name:     "Double negation",
+			Input:    "!(!x)",
+			Expected: "x",
+		},
+		SimplificationTest{
+
+			Name:     "Negated literal",
+			Input:    "!true || x",
+			Expected: "x",
+		},
+		SimplificationTest{
+
+			Name:     "De Morgan conjunction",
+			Input:    "!(a && b)",
+			Expected: "!a || !b",
+		},
+		SimplificationTest{
+
+			Name:     "De Morgan disjunction",
+			Input:    "!(a || !(b && c))",
+			Expected: "!a && b && c",
+		},
+		SimplificationTest{
+
+			Name:     "Negated equality",
+			Input:    "!(a == 1) && !(b != 2)",
+			Expected: "a != 1 && b == 2",
+		},
+		SimplificationTest{
+
+			Name:     "Multiplication by one",
+			Input:    "1 * x * 1",
+			Expected: "x",
+		},
+		SimplificationTest{
+
+			Name:     "Subtraction of zero",
+			Input:    "(x - 0) > 2",
+			Expected: "x > 2",
+		},
+		SimplificationTest{
+
+			Name:     "Addition of zero to a number",
+			Input:    "0 + x * y + 0",
+			Expected: "x * y",
+		},
+		SimplificationTest{
+
+			Name:     "Addition of zero to a parameter",
+			Input:    "x + 0",
+			Expected: "x + 0",
+		},
+		SimplificationTest{
+
+			Name:     "True ternary condition",
+			Input:    "true ? x > 1 : y",
+			Expected: "x > 1",
+		},
+		SimplificationTest{
+
+			Name:     "True ternary condition with a parameter",
+			Input:    "true ? x : y",
+			Expected: "x ?? y",
+		},
+		SimplificationTest{
+
+			Name:     "False ternary condition",
+			Input:    "(true && false) ? x : y",
+			Expected: "y",
+		},
+		SimplificationTest{
+
+			Name:     "Known condition after simplification",
+			Input:    "(x || true) ? 1 : y",
+			Expected: "1",
+		},
+		SimplificationTest{
+
+			Name:     "Duplicate terms",
+			Input:    "a && b > 1 && a && (b > 1 || c)",
+			Expected: "a && b > 1 && (b > 1 || c)",
+		},
+		SimplificationTest{
+
+			Name:     "Duplicate disjunctions",
+			Input:    "(a || b) && (c || a) && (a || b)",
+			Expected: "(a || b) && (c || a)",
+		},
+		SimplificationTest{
+
+			Name:     "Duplicate function calls",
+			Input:    "now() && now()",
+			Expected: "now() && now()",
+		},
+		SimplificationTest{
+
+			Name:     "Function arguments",
+			Input:    "now(x * 1, !(!y))",
+			Expected: "now(x, y)",
+		},
+		SimplificationTest{
+
+			Name:     "Replaced operator",
+			Input:    "!(x == 1) && x * 1",
+			Options:  []Option{WithOperator(EQ, func(left interface{}, right interface{}) (interface{}, error) { return true, nil })},
+			Expected: "!(x == 1) && x",
+		},
+		SimplificationTest{
+
+			Name:     "Integer numerics",
+			Input:    "x * 1 - 0",
+			Options:  []Option{WithNumericMode(INTEGER_NUMERICS)},
+			Expected: "x",
+		},
+		SimplificationTest{
+
+			Name:     "Decimal numerics",
+			Input:    "x * 1.0 * 2 + 0.00",
+			Options:  []Option{WithNumericMode(DECIMAL_NUMERICS)},
+			Expected: "x * 2",
+		},
+		SimplificationTest{
+
+			Name:     "Integer numerics with float literals",
+			Input:    "x * 1.0 + 0.0",
+			Options:  []Option{WithNumericMode(INTEGER_NUMERICS)},
+			Expected: "x * 1 + 0",
+		},
+	}
+
+	test.Logf("Running %d simplification test cases", len(simplificationTests))
+
+	for _, simplificationTest := range simplificationTests {
+
+		options := append([]Option{WithFunctions(functions), WithSimplification()}, simplificationTest.Options...)
+
+		actual, err := Format(simplificationTest.Input, options...)
+		if err != nil {
+			test.Logf("Test '%s' failed: %s", simplificationTest.Name, err)
+			test.Fail()
+			continue
+		}
+
+		if actual != simplificationTest.Expected {
+			test.Logf("Test '%s' failed", simplificationTest.Name)
+			test.Logf("Expected '%s', got '%s'", simplificationTest.Expected, actual)
+			test.Fail()
+		}
+	}
+}
+
+/*
+	Tests that simplified expressions give the same result as the original, whenever the original gives a result.
+*/
+func TestSimplificationResults(test *testing.T) {
+
+	inputs := []string{
+		"!(a && (b || !c)) && true",
+		"a && b && !(!a) || c && false",
+		"(a ? x * 1 : y - 0) + 0",
+		"!(x == y) || !(a != b) && (a || a)",
+		"true ? a : x > y",
+		"false ? x : (y * 1 > x ? a : b)",
+	}
+
+	var parameters []MapParameters
+
+	for i := 0; i < 32; i++ {
+		parameters = append(parameters, MapParameters{
+			"a": i&1 != 0,
+			"b": i&2 != 0,
+			"c": i&4 != 0,
+			"x": i & 8,
+			"y": i & 16,
+		})
+	}
+
+	for _, input := range inputs {
+		for _, engine := range evaluationEngines {
+
+			original, err := Compile(input, WithEngine(engine))
+			if err != nil {
+				test.Logf("Failed to parse '%s': %s", input, err)
+				test.Fail()
+				break
+			}
+
+			simplified, _ := Compile(input, WithEngine(engine), WithSimplification())
+
+			for _, row := range parameters {
+
+				expected, err := original.Eval(row)
+				if err != nil {
+					continue
+				}
+
+				actual, err := simplified.Eval(row)
+				if err != nil || actual != expected {
+					test.Logf("'%s' simplified to '%s' with %v gave '%v' (%v) for %v, expected '%v'", input, simplified.String(), engine, actual, err, row, expected)
+					test.Fail()
+				}
+			}
+		}
+	}
+}
+
+/*
+	Tests that identities are only applied when the literal has the type of the numeric mode,
+	since otherwise the operator changes the type of the result.
+*/
+func TestSimplificationNumericResults(test *testing.T) {
+
+	inputs := []string{
+		"x * 1",
+		"x * 1.0",
+		"1.0 * x + 0",
+		"x - 0.0",
+	}
+
+	for _, input := range inputs {
+		for _, mode := range []NumericMode{FLOAT_NUMERICS, INTEGER_NUMERICS, DECIMAL_NUMERICS} {
+
+			original, err := Compile(input, WithNumericMode(mode))
+			if err != nil {
+				test.Logf("Failed to parse '%s': %s", input, err)
+				test.Fail()
+				break
+			}
+
+			simplified, _ := Compile(input, WithNumericMode(mode), WithSimplification())
+
+			expected, _ := original.Eval(MapParameters{"x": 3})
+			actual, err := simplified.Eval(MapParameters{"x": 3})
+
+			if err != nil || !reflect.DeepEqual(actual, expected) {
+				test.Logf("'%s' simplified to '%s' with numeric mode %v gave '%v' (%T), expected '%v' (%T)", input, simplified.String(), mode, actual, actual, expected, expected)
+				test.Fail()
+			}
+		}
+	}
+}
+
+/*
+	Tests that simplifying a tree doesn't change it, since parts of it may be shared.
+*/
+func TestSimplificationSharedTree(test *testing.T) {
+
+	shared := Eq(Var("x"), Num(1))
+	root := And(Not(shared), Or(Not(Not(shared)), Mul(Var("y"), Num(1))))
+
+	before, _ := FormatNode(root)
+
+	_, err := CompileAST(root, WithSimplification())
+	if err != nil {
+		test.Logf("Failed to compile: %s", err)
+		test.Fail()
+		return
+	}
+
+	after, _ := FormatNode(root)
+	if after != before {
+		test.Logf("Simplification changed the tree from '%s' to '%s'", before, after)
+		test.Fail()
+	}
+}
+
+func TestSimplificationTokens(test *testing.T) {
+
+	expression, _ := Compile("x && (true && !(!y))", WithSimplification())
+
+	expected := []ExpressionToken{
+		ExpressionToken{Kind: VARIABLE, Value: "x"},
+		ExpressionToken{Kind: LOGICALOP, Value: "&&"},
+		ExpressionToken{Kind: VARIABLE, Value: "y"},
+	}
+
+	tokens := expression.Tokens()
+	if len(tokens) != len(expected) {
+		test.Logf("Expected %d tokens, got %d", len(expected), len(tokens))
+		test.Fail()
+		return
+	}
+
+	for i, token := range tokens {
+		if token.Kind != expected[i].Kind || token.Value != expected[i].Value {
+			test.Logf("Expected token %d to be %v '%v', got %v '%v'", i, expected[i].Kind, expected[i].Value, token.Kind, token.Value)
+			test.Fail()
+		}
+	}
+
+	// the original expression is still what it was given as.
+	if expression.String() != "x && (true && !(!y))" {
+		test.Logf("Expected the original expression, got '%s'", expression.String())
+		test.Fail()
+	}
+
+	// expressions which can't be written out once simplified (here, because of a nil literal) are left as they were.
+	expression, err := Compile("false ? 1", WithSimplification())
+	if err != nil || len(expression.Tokens()) != 3 {
+		test.Logf("Expected an unsimplified expression, got '%v' (%v)", expression, err)
+		test.Fail()
+	}
+
+	_, errs := Compile("x && true && (", WithSimplification(), WithDiagnostics())
+	if _, isErrors := errs.(ParseErrors); !isErrors {
+		test.Logf("Expected parse errors, got '%v'", errs)
+		test.Fail()
+	}
+
+	// expressions compiled with diagnostics are simplified in the same way.
+	expression, err = Compile("x && true", WithSimplification(), WithDiagnostics())
+	if err != nil || len(expression.Tokens()) != 1 {
+		test.Logf("Expected a simplified expression, got '%v' (%v)", expression, err)
+		test.Fail()
+	}
+}
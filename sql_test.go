@@ -1,7 +1,11 @@
 package govaluate
 
 import (
+	"errors"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 /*
@@ -154,7 +158,7 @@ func TestSQLSerialization(test *testing.T) {
 
 			Name:     "Membership operator",
 			Input:    "foo IN (1, 2, 3)",
-			Expected: "[foo] in ( 1 , 2 , 3 )",
+			Expected: "[foo] IN (1, 2, 3)",
 		},
 		QueryTest{
 
@@ -162,32 +166,60 @@ func TestSQLSerialization(test *testing.T) {
 			Input:    "foo ?? bar",
 			Expected: "COALESCE([foo], [bar])",
 		},
-		/*
-			// Ternaries don't work yet, because the outputter is not yet sophisticated enough to produce them.
-			QueryTest{
+		QueryTest{
 
-				Name:     "Full ternary",
-				Input:    "[foo] == 5 ? 1 : 2",
-				Expected: "IF([foo] = 5, 1, 2)",
-			},
-			QueryTest{
+			Name:     "Full ternary",
+			Input:    "[foo] == 5 ? 1 : 2",
+			Expected: "CASE WHEN [foo] = 5 THEN 1 ELSE 2 END",
+		},
+		QueryTest{
 
-				Name:     "Half ternary",
-				Input:    "[foo] == 5 ? 1",
-				Expected: "IF([foo] = 5, 1)",
-			},
-			QueryTest{
+			Name:     "Half ternary",
+			Input:    "[foo] == 5 ? 1",
+			Expected: "CASE WHEN [foo] = 5 THEN 1 END",
+		},
+		QueryTest{
 
-				Name:     "Full ternary with implicit bool",
-				Input:    "[foo] ? 1 : 2",
-				Expected: "IF([foo] = 0, 1, 2)",
-			},
-			QueryTest{
+			Name:     "Full ternary with implicit bool",
+			Input:    "[foo] ? 1 : 2",
+			Expected: "CASE WHEN [foo] THEN 1 ELSE 2 END",
+		},
+		QueryTest{
 
-				Name:     "Half ternary with implicit bool",
-				Input:    "[foo] ? 1",
-				Expected: "IF([foo] = 0, 1)",
-			},*/
+			Name:     "Chained ternary",
+			Input:    "foo > 1 ? 'a' : foo > 0 ? 'b' : 'c'",
+			Expected: "CASE WHEN CASE WHEN [foo] > 1 THEN 'a' ELSE [foo] > 0 END THEN 'b' ELSE 'c' END",
+		},
+		QueryTest{
+
+			Name:     "Nested ternary",
+			Input:    "foo > 1 ? 'a' : (foo > 0 ? 'b' : 'c')",
+			Expected: "CASE WHEN [foo] > 1 THEN 'a' ELSE ( CASE WHEN [foo] > 0 THEN 'b' ELSE 'c' END ) END",
+		},
+		QueryTest{
+
+			Name:     "Ternary in comparison",
+			Input:    "bar == (foo ? 1 : 2)",
+			Expected: "[bar] = ( CASE WHEN [foo] THEN 1 ELSE 2 END )",
+		},
+		QueryTest{
+
+			Name:     "Membership with parameters",
+			Input:    "foo in (bar, 'baz', 1 + 2)",
+			Expected: "[foo] IN ([bar], 'baz', 1 + 2)",
+		},
+		QueryTest{
+
+			Name:     "Inverted group",
+			Input:    "!(foo > 1 && bar < 2) || !baz",
+			Expected: "NOT ( [foo] > 1 AND [bar] < 2 ) OR NOT [baz]",
+		},
+		QueryTest{
+
+			Name:     "Inverted membership",
+			Input:    "!(foo in (1, 2))",
+			Expected: "NOT ( [foo] IN (1, 2) )",
+		},
 		QueryTest{
 
 			Name:     "Regex equals",
@@ -245,3 +277,363 @@ func runQueryTests(testCases []QueryTest, test *testing.T) {
 		}
 	}
 }
+
+/*
+	Represents a test of creating a SQL query in a specific dialect.
+*/
+type DialectQueryTest struct {
+	Name     string
+	Dialect  string
+	Input    string
+	Expected string
+}
+
+func TestSQLDialects(test *testing.T) {
+
+	RegisterSQLDialect("test", SQLDialect{
+		IdentifierOpen:   "{",
+		IdentifierClose:  "}",
+		RegexOperator:    "MATCHES",
+		NotRegexOperator: "NOT MATCHES",
+		True:             "YES",
+		False:            "NO",
+		DateFormat:       "2006-01-02",
+		PowerFormat:      "EXP(%s, %s)",
+		ModulusFormat:    "REM(%s, %s)",
+	})
+
+	testCases := []DialectQueryTest{
+
+		DialectQueryTest{
+
+			Name:     "Default",
+			Dialect:  "default",
+			Input:    "foo == true && bar =~ 'a' && baz ** 2 > qux % 3",
+			Expected: "[foo] = 1 AND [bar] RLIKE 'a' AND POW([baz], 2) > MOD([qux], 3)",
+		},
+		DialectQueryTest{
+
+			Name:     "Postgres",
+			Dialect:  "postgres",
+			Input:    "foo == true && bar =~ 'a' && bar !~ 'b' && baz ** 2 > qux % 3",
+			Expected: "\"foo\" = TRUE AND \"bar\" ~ 'a' AND \"bar\" !~ 'b' AND POWER(\"baz\", 2) > MOD(\"qux\", 3)",
+		},
+		DialectQueryTest{
+
+			Name:     "MySQL",
+			Dialect:  "mysql",
+			Input:    "foo == false && bar =~ 'a' && baz ** 2 > qux % 3",
+			Expected: "`foo` = FALSE AND `bar` REGEXP 'a' AND POW(`baz`, 2) > MOD(`qux`, 3)",
+		},
+		DialectQueryTest{
+
+			Name:     "MySQL time",
+			Dialect:  "mysql",
+			Input:    "foo > '2014-07-04T10:20:30Z'",
+			Expected: "`foo` > '2014-07-04 10:20:30'",
+		},
+		DialectQueryTest{
+
+			Name:     "SQLite",
+			Dialect:  "sqlite",
+			Input:    "foo == true && bar !~ 'a' && baz ** 2 > qux % 3",
+			Expected: "\"foo\" = 1 AND \"bar\" NOT REGEXP 'a' AND POWER(\"baz\", 2) > \"qux\" % 3",
+		},
+		DialectQueryTest{
+
+			Name:     "Escaped identifier",
+			Dialect:  "postgres",
+			Input:    "[foo\"bar] > 1",
+			Expected: "\"foo\"\"bar\" > 1",
+		},
+		DialectQueryTest{
+
+			Name:     "Registered",
+			Dialect:  "test",
+			Input:    "foo == true && bar =~ 'a' && baz ** 2 > qux % 3 && quux > '2014-07-04T10:20:30Z'",
+			Expected: "{foo} = YES AND {bar} MATCHES 'a' AND EXP({baz}, 2) > REM({qux}, 3) AND {quux} > '2014-07-04'",
+		},
+	}
+
+	test.Logf("Running %d SQL dialect test cases", len(testCases))
+
+	for _, testCase := range testCases {
+
+		expression, err := NewEvaluableExpression(testCase.Input)
+		if err != nil {
+
+			test.Logf("Test '%s' failed to parse: %s", testCase.Name, err)
+			test.Fail()
+			continue
+		}
+
+		actualQuery, err := expression.ToSQLQueryWithDialect(testCase.Dialect)
+		if err != nil {
+
+			test.Logf("Test '%s' failed to create query: %s", testCase.Name, err)
+			test.Fail()
+			continue
+		}
+
+		if actualQuery != testCase.Expected {
+
+			test.Logf("Test '%s' did not create expected query.", testCase.Name)
+			test.Logf("Actual: '%s', expected '%s'", actualQuery, testCase.Expected)
+			test.Fail()
+		}
+	}
+
+	expression, _ := NewEvaluableExpression("foo > 1")
+	_, err := expression.ToSQLQueryWithDialect("unknown")
+
+	if err == nil {
+		test.Logf("Expected an error for an unknown dialect")
+		test.Fail()
+	}
+}
+
+/*
+	Represents a test of creating a parameterized SQL query in a specific dialect.
+*/
+type ArgumentQueryTest struct {
+	Name              string
+	Dialect           string
+	Input             string
+	Expected          string
+	ExpectedArguments []interface{}
+}
+
+func TestSQLQueryWithArguments(test *testing.T) {
+
+	RegisterSQLDialect("named", SQLDialect{
+		IdentifierOpen:   "[",
+		IdentifierClose:  "]",
+		RegexOperator:    "LIKE",
+		NotRegexOperator: "NOT LIKE",
+		True:             "1",
+		False:            "0",
+		PowerFormat:      "POWER(%s, %s)",
+		ModulusFormat:    "%s %% %s",
+		Placeholders:     AT_PLACEHOLDERS,
+	})
+
+	date := time.Date(2014, 7, 4, 0, 0, 0, 0, time.UTC)
+
+	testCases := []ArgumentQueryTest{
+
+		ArgumentQueryTest{
+
+			Name:              "Question placeholders",
+			Dialect:           "mysql",
+			Input:             "foo == 'bar' && baz != 'qux' && count > 5",
+			Expected:          "`foo` = ? AND `baz` <> ? AND `count` > 5",
+			ExpectedArguments: []interface{}{"bar", "qux"},
+		},
+		ArgumentQueryTest{
+
+			Name:              "Dollar placeholders",
+			Dialect:           "postgres",
+			Input:             "foo == 'bar' || foo =~ '^b' || created > '2014-07-04T00:00:00Z'",
+			Expected:          "\"foo\" = $1 OR \"foo\" ~ $2 OR \"created\" > $3",
+			ExpectedArguments: []interface{}{"bar", "^b", date},
+		},
+		ArgumentQueryTest{
+
+			Name:              "At placeholders",
+			Dialect:           "named",
+			Input:             "foo in ('a', 'b') && bar == true",
+			Expected:          "[foo] IN (@p1, @p2) AND [bar] = 1",
+			ExpectedArguments: []interface{}{"a", "b"},
+		},
+		ArgumentQueryTest{
+
+			Name:              "Arguments in order around functions",
+			Dialect:           "sqlite",
+			Input:             "(foo ?? 'a') == 'b'",
+			Expected:          "( COALESCE(\"foo\", ?) ) = ?",
+			ExpectedArguments: []interface{}{"a", "b"},
+		},
+		ArgumentQueryTest{
+
+			Name:              "Injection",
+			Dialect:           "sqlite",
+			Input:             "foo == 'x\\' OR 1=1 --'",
+			Expected:          "\"foo\" = ?",
+			ExpectedArguments: []interface{}{"x' OR 1=1 --"},
+		},
+		ArgumentQueryTest{
+
+			Name:     "No literals",
+			Dialect:  "sqlite",
+			Input:    "foo > bar",
+			Expected: "\"foo\" > \"bar\"",
+		},
+	}
+
+	test.Logf("Running %d parameterized SQL test cases", len(testCases))
+
+	for _, testCase := range testCases {
+
+		expression, err := NewEvaluableExpression(testCase.Input)
+		if err != nil {
+
+			test.Logf("Test '%s' failed to parse: %s", testCase.Name, err)
+			test.Fail()
+			continue
+		}
+
+		actualQuery, actualArguments, err := expression.ToSQLQueryWithArguments(testCase.Dialect)
+		if err != nil {
+
+			test.Logf("Test '%s' failed to create query: %s", testCase.Name, err)
+			test.Fail()
+			continue
+		}
+
+		if actualQuery != testCase.Expected || !reflect.DeepEqual(actualArguments, testCase.ExpectedArguments) {
+
+			test.Logf("Test '%s' did not create expected query.", testCase.Name)
+			test.Logf("Actual: '%s' %v, expected '%s' %v", actualQuery, actualArguments, testCase.Expected, testCase.ExpectedArguments)
+			test.Fail()
+		}
+	}
+}
+
+func TestSQLQueryEscaping(test *testing.T) {
+
+	expression, _ := NewEvaluableExpression("foo == 'it\\'s'")
+	actualQuery, _ := expression.ToSQLQuery()
+
+	if actualQuery != "[foo] = 'it''s'" {
+		test.Logf("Unexpected query: '%s'", actualQuery)
+		test.Fail()
+	}
+}
+
+func TestSQLFunctionsAndColumns(test *testing.T) {
+
+	functions := map[string]ExpressionFunction{
+		"strlen": func(arguments ...interface{}) (interface{}, error) {
+			return float64(len(arguments[0].(string))), nil
+		},
+		"now": func(arguments ...interface{}) (interface{}, error) {
+			return time.Now(), nil
+		},
+		"unmapped": func(arguments ...interface{}) (interface{}, error) {
+			return nil, nil
+		},
+	}
+
+	dialect, err := FindSQLDialect("postgres")
+	if err != nil {
+		test.Logf("Failed to find dialect: %s", err)
+		test.Fail()
+		return
+	}
+
+	dialect.Functions = map[string]SQLFunction{
+		"strlen": SQLFunctionCall("LENGTH"),
+		"now":    SQLFunctionCall("NOW"),
+	}
+	dialect.Columns = func(path []string) (string, error) {
+
+		if path[0] == "user" {
+			return "users." + strings.ToLower(strings.Join(path[1:], "_")), nil
+		}
+		if path[0] == "secret" {
+			return "", errors.New("Column 'secret' is not queryable")
+		}
+		return "", nil
+	}
+	RegisterSQLDialect("mapped", dialect)
+
+	testCases := []DialectQueryTest{
+
+		DialectQueryTest{
+
+			Name:     "Function",
+			Input:    "strlen(name) > 3",
+			Expected: "LENGTH(\"name\") > 3",
+		},
+		DialectQueryTest{
+
+			Name:     "Function without arguments",
+			Input:    "created < now()",
+			Expected: "\"created\" < NOW()",
+		},
+		DialectQueryTest{
+
+			Name:     "Nested functions",
+			Input:    "strlen(name) + strlen('foo' + name) > 3",
+			Expected: "LENGTH(\"name\") + LENGTH('foo' + \"name\") > 3",
+		},
+		DialectQueryTest{
+
+			Name:     "Mapped accessor",
+			Input:    "user.Age >= 18 && user.HomeCountry == country",
+			Expected: "users.age >= 18 AND users.homecountry = \"country\"",
+		},
+	}
+
+	test.Logf("Running %d SQL mapping test cases", len(testCases))
+
+	for _, testCase := range testCases {
+
+		expression, err := NewEvaluableExpressionWithFunctions(testCase.Input, functions)
+		if err != nil {
+
+			test.Logf("Test '%s' failed to parse: %s", testCase.Name, err)
+			test.Fail()
+			continue
+		}
+
+		actualQuery, err := expression.ToSQLQueryWithDialect("mapped")
+		if err != nil {
+
+			test.Logf("Test '%s' failed to create query: %s", testCase.Name, err)
+			test.Fail()
+			continue
+		}
+
+		if actualQuery != testCase.Expected {
+
+			test.Logf("Test '%s' did not create expected query.", testCase.Name)
+			test.Logf("Actual: '%s', expected '%s'", actualQuery, testCase.Expected)
+			test.Fail()
+		}
+	}
+
+	failures := map[string]string{
+		"unmapped(name)":         "Unable to write function 'unmapped' as SQL",
+		"secret.Key == 1":        "Column 'secret' is not queryable",
+		"other.Key == 1":         "Unable to write accessor 'other.Key' as SQL",
+		"user.Name.Get() == 'a'": "Unable to write method call 'user.Name.Get' as SQL",
+	}
+
+	for input, expected := range failures {
+
+		expression, err := NewEvaluableExpressionWithFunctions(input, functions)
+		if err != nil {
+
+			test.Logf("'%s' failed to parse: %s", input, err)
+			test.Fail()
+			continue
+		}
+
+		_, err = expression.ToSQLQueryWithDialect("mapped")
+		if err == nil || err.Error() != expected {
+
+			test.Logf("'%s' gave error '%v', expected '%s'", input, err, expected)
+			test.Fail()
+		}
+	}
+
+	// without a mapping, the default dialect can't write functions or accessors.
+	expression, _ := NewEvaluableExpressionWithFunctions("strlen(user.Name) > 1", functions)
+
+	_, err = expression.ToSQLQuery()
+	if err == nil {
+		test.Logf("Expected an error writing a function without a mapping")
+		test.Fail()
+	}
+}
@@ -1,8 +1,6 @@
 package govaluate
 
 import (
-	"errors"
-	"fmt"
 	"time"
 )
 
@@ -178,9 +176,32 @@ func makePrecedentFromPlanner(planner *precedencePlanner) precedent {
 	which is used to completely evaluate a set of tokens at evaluation-time.
 	The three stages of evaluation can be thought of as parsing strings to tokens, then tokens to a stage list, then evaluation with parameters.
 */
-func planStages(tokens []ExpressionToken) (*evaluationStage, error) {
+func planStages(tokens []ExpressionToken, options *compileOptions) (*evaluationStage, error) {
+
+	stage, err := planStructure(tokens, options.limits.MaxStageDepth)
+	if err != nil {
+		return nil, err
+	}
+
+	// operators are planned for float64 values. Other numeric modes need their own.
+	applyNumericMode(stage, options.numericMode, options.decimalRounding)
+	applyCustomOperators(stage, options.operators)
+
+	stage = elideLiterals(stage)
+	return stage, nil
+}
+
+/*
+	Plans the given [tokens] into the tree of stages they represent, without computing anything in advance.
+	Translators (such as `ToSQLQuery`) use this, since they need every literal and operator exactly as it was written.
+
+	If [maxDepth] is positive, planning stops with an error as soon as stages are nested deeper than that,
+	so that a pathological expression can't exhaust the stack while it's being planned.
+*/
+func planStructure(tokens []ExpressionToken, maxDepth int) (*evaluationStage, error) {
 
 	stream := newTokenStream(tokens)
+	stream.maxDepth = maxDepth
 
 	stage, err := planTokens(stream)
 	if err != nil {
@@ -190,8 +211,6 @@ func planStages(tokens []ExpressionToken) (*evaluationStage, error) {
 	// while we're now fully-planned, we now need to re-order same-precedence operators.
 	// this could probably be avoided with a different planning method
 	reorderStages(stage)
-
-	stage = elideLiterals(stage)
 	return stage, nil
 }
 
@@ -204,6 +223,26 @@ func planTokens(stream *tokenStream) (*evaluationStage, error) {
 	return planSeparator(stream)
 }
 
+/*
+	Plans a stage which will be nested within the stage of the given [token], using the given [planner].
+	Returns an error instead if that nests stages more deeply than the stream allows.
+*/
+func planNested(stream *tokenStream, token ExpressionToken, planner precedent) (*evaluationStage, error) {
+
+	var ret *evaluationStage
+	var err error
+
+	if stream.maxDepth > 0 && stream.depth >= stream.maxDepth {
+		return nil, newTokenParseError(token, nil, "Expression is nested more than %d stages deep", stream.maxDepth)
+	}
+
+	stream.depth++
+	ret, err = planner(stream)
+	stream.depth--
+
+	return ret, err
+}
+
 /*
 	The most usual method of parsing an evaluation stage for a given precedence.
 	Most stages use the same logic
@@ -263,7 +302,7 @@ func planPrecedenceLevel(
 		}
 
 		if rightPrecedent != nil {
-			rightStage, err = rightPrecedent(stream)
+			rightStage, err = planNested(stream, token, rightPrecedent)
 			if err != nil {
 				return nil, err
 			}
@@ -296,6 +335,7 @@ func planFunction(stream *tokenStream) (*evaluationStage, error) {
 
 	var token ExpressionToken
 	var rightStage *evaluationStage
+	var operator evaluationOperator
 	var err error
 
 	token = stream.next()
@@ -305,17 +345,26 @@ func planFunction(stream *tokenStream) (*evaluationStage, error) {
 		return planAccessor(stream)
 	}
 
-	rightStage, err = planAccessor(stream)
+	rightStage, err = planNested(stream, token, planAccessor)
 	if err != nil {
 		return nil, err
 	}
 
+	switch token.Value.(type) {
+	case ContextExpressionFunction:
+		operator = makeContextFunctionStage(token.Value.(ContextExpressionFunction))
+	default:
+		operator = makeFunctionStage(token.Value.(ExpressionFunction))
+	}
+
 	return &evaluationStage{
 
 		symbol:          FUNCTIONAL,
 		rightStage:      rightStage,
-		operator:        makeFunctionStage(token.Value.(ExpressionFunction)),
+		operator:        operator,
 		typeErrorFormat: "Unable to run function '%v': %v",
+		name:            token.Text,
+		value:           token.Value,
 	}, nil
 }
 
@@ -346,7 +395,7 @@ func planAccessor(stream *tokenStream) (*evaluationStage, error) {
 
 			stream.rewind()
 
-			rightStage, err = planTokens(stream)
+			rightStage, err = planNested(stream, token, planTokens)
 			if err != nil {
 				return nil, err
 			}
@@ -361,6 +410,7 @@ func planAccessor(stream *tokenStream) (*evaluationStage, error) {
 		rightStage:      rightStage,
 		operator:        makeAccessorStage(token.Value.([]string)),
 		typeErrorFormat: "Unable to access parameter field or method '%v': %v",
+		path:            token.Value.([]string),
 	}, nil
 }
 
@@ -374,6 +424,8 @@ func planValue(stream *tokenStream) (*evaluationStage, error) {
 	var symbol OperatorSymbol
 	var ret *evaluationStage
 	var operator evaluationOperator
+	var name string
+	var value interface{}
 	var err error
 
 	if !stream.hasNext() {
@@ -386,7 +438,7 @@ func planValue(stream *tokenStream) (*evaluationStage, error) {
 
 	case CLAUSE:
 
-		ret, err = planTokens(stream)
+		ret, err = planNested(stream, token, planTokens)
 		if err != nil {
 			return nil, err
 		}
@@ -413,7 +465,8 @@ func planValue(stream *tokenStream) (*evaluationStage, error) {
 		return nil, nil
 
 	case VARIABLE:
-		operator = makeParameterStage(token.Value.(string))
+		name = token.Value.(string)
+		operator = makeParameterStage(name)
 
 	case NUMERIC:
 		fallthrough
@@ -423,9 +476,11 @@ func planValue(stream *tokenStream) (*evaluationStage, error) {
 		fallthrough
 	case BOOLEAN:
 		symbol = LITERAL
+		value = token.Value
 		operator = makeLiteralStage(token.Value)
 	case TIME:
 		symbol = LITERAL
+		value = token.Value
 		operator = makeLiteralStage(float64(token.Value.(time.Time).Unix()))
 
 	case PREFIX:
@@ -434,13 +489,14 @@ func planValue(stream *tokenStream) (*evaluationStage, error) {
 	}
 
 	if operator == nil {
-		errorMsg := fmt.Sprintf("Unable to plan token kind: '%s', value: '%v'", token.Kind.String(), token.Value)
-		return nil, errors.New(errorMsg)
+		return nil, newTokenParseError(token, nil, "Unable to plan token kind: '%s', value: '%v'", token.Kind.String(), token.Value)
 	}
 
 	return &evaluationStage{
 		symbol:   symbol,
 		operator: operator,
+		name:     name,
+		value:    value,
 	}, nil
 }
 
@@ -666,13 +722,17 @@ func elideLiterals(root *evaluationStage) *evaluationStage {
 func elideStage(root *evaluationStage) *evaluationStage {
 
 	var leftValue, rightValue, result interface{}
+	var isNegation bool
 	var err error
 
-	// right side must be a non-nil value. Left side must be nil or a value.
+	// negative numbers are parsed as the negation of a literal, but they're literals once planned (and formatted as such).
+	// no other prefix is elided.
+	isNegation = root.symbol == NEGATE && root.leftStage == nil
+
+	// right side must be a non-nil value. Left side must be a value, unless this is a negation.
 	if root.rightStage == nil ||
 		root.rightStage.symbol != LITERAL ||
-		root.leftStage == nil ||
-		root.leftStage.symbol != LITERAL {
+		(!isNegation && (root.leftStage == nil || root.leftStage.symbol != LITERAL)) {
 		return root
 	}
 
@@ -686,9 +746,12 @@ func elideStage(root *evaluationStage) *evaluationStage {
 
 	// both sides are values, get their actual values.
 	// errors should be near-impossible here. If we encounter them, just abort this optimization.
-	leftValue, err = root.leftStage.operator(nil, nil, nil)
-	if err != nil {
-		return root
+	if root.leftStage != nil {
+
+		leftValue, err = root.leftStage.operator(nil, nil, nil)
+		if err != nil {
+			return root
+		}
 	}
 
 	rightValue, err = root.rightStage.operator(nil, nil, nil)
@@ -720,5 +783,6 @@ func elideStage(root *evaluationStage) *evaluationStage {
 	return &evaluationStage{
 		symbol:   LITERAL,
 		operator: makeLiteralStage(result),
+		value:    result,
 	}
 }
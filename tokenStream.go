@@ -4,6 +4,10 @@ type tokenStream struct {
 	tokens      []ExpressionToken
 	index       int
 	tokenLength int
+
+	// how many stages are currently being planned, each of which contains the next, and how many may be (if positive).
+	depth    int
+	maxDepth int
 }
 
 func newTokenStream(tokens []ExpressionToken) *tokenStream {